@@ -2,92 +2,831 @@ package main
 
 import (
 	"FinQuotesService/internal/api"
+	"FinQuotesService/internal/auth"
+	"FinQuotesService/internal/cache"
+	"FinQuotesService/internal/config"
 	"FinQuotesService/internal/db"
+	"FinQuotesService/internal/grpcapi"
+	"FinQuotesService/internal/grpcapi/quotesv1"
+	"FinQuotesService/internal/health"
+	"FinQuotesService/internal/importer"
+	"FinQuotesService/internal/logging"
+	"FinQuotesService/internal/metrics"
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/provider"
+	"FinQuotesService/internal/ratelimit"
+	"FinQuotesService/internal/repository"
+	"FinQuotesService/internal/repository/memory"
+	"FinQuotesService/internal/repository/postgres"
+	"FinQuotesService/internal/repository/sqlite"
 	"FinQuotesService/internal/service"
 	"FinQuotesService/internal/tools"
+	"FinQuotesService/internal/tracing"
 	"FinQuotesService/internal/worker"
+	"FinQuotesService/internal/ws"
 	"context"
+	"database/sql"
 	"errors"
-	"log"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
-const workersCount = 10
-const jobBufferSize = 32
+func setupRoutes(h *api.Handler, admin *api.AdminHandler, live *ws.Handler, authn *auth.Authenticator, limiter *ratelimit.Limiter, checker *health.Checker) *http.ServeMux {
+	mux := http.NewServeMux()
+	// API routes are served under /v1. The unversioned paths are kept as
+	// deprecated aliases and share the rate limit buckets of their /v1 route.
+	route := func(method, path string, scope model.Scope, handler http.HandlerFunc) {
+		protected := authn.Require(scope, limiter.Wrap(path, handler))
+		mux.HandleFunc(method+" "+api.V1+path, protected)
+		mux.HandleFunc(method+" "+path, api.Deprecated(protected))
+	}
+	route("POST", "/quotes/update", model.ScopeQuotesUpdate, h.PostStartAsyncUpdateQuote)
+	route("GET", "/quotes/update/{request_id}", model.ScopeQuotesRead, h.GetQuoteByRequestId)
+	route("GET", "/quotes/last/{base}/{quote}", model.ScopeQuotesRead, h.GetLastQuote)
+	route("GET", "/quotes/candles/{base}/{quote}", model.ScopeQuotesRead, h.GetCandles)
+	route("GET", "/quotes/export", model.ScopeQuotesRead, h.ExportQuotes)
+	route("GET", "/quotes/consistency", model.ScopeQuotesRead, h.GetConsistency)
+	route("GET", "/admin/keys", model.ScopeAdmin, admin.ListAPIKeys)
+	route("POST", "/admin/keys", model.ScopeAdmin, admin.CreateAPIKey)
+	route("DELETE", "/admin/keys/{id}", model.ScopeAdmin, admin.RevokeAPIKey)
+	route("POST", "/admin/quotes/import", model.ScopeAdmin, admin.ImportQuotes)
+	route("GET", "/admin/backfills", model.ScopeAdmin, admin.ListBackfills)
+	route("POST", "/admin/backfills", model.ScopeAdmin, admin.CreateBackfill)
+	route("GET", "/admin/backfills/{id}", model.ScopeAdmin, admin.GetBackfill)
+	route("POST", "/admin/backfills/{id}/resume", model.ScopeAdmin, admin.ResumeBackfill)
+	route("GET", "/admin/quotes/suspect", model.ScopeAdmin, admin.ListSuspectQuotes)
+	route("POST", "/admin/quotes/{id}/approve", model.ScopeAdmin, admin.ApproveQuote)
+	route("POST", "/admin/quotes/{id}/reject", model.ScopeAdmin, admin.RejectQuote)
+	// The WebSocket API is served at /ws as well as /v1/ws; it is newer than
+	// the versioned API so /ws is not deprecated. Refresh commands are checked
+	// against the quotes:update scope per message and the /quotes/update rate
+	// limit per pair.
+	liveHandler := authn.Require(model.ScopeQuotesRead, limiter.Wrap("/ws", live.Serve))
+	mux.HandleFunc("GET "+api.V1+"/ws", liveHandler)
+	mux.HandleFunc("GET /ws", liveHandler)
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.HandleFunc("GET /healthz", checker.Liveness)
+	mux.HandleFunc("GET /readyz", checker.Readiness)
+	mux.HandleFunc("GET /openapi.json", api.OpenAPI)
+	return mux
+}
+
+// serveGRPC starts the gRPC API, with the standard health and reflection
+// services, on addr. The returned function stops it gracefully, or forcibly
+// once ctx is done.
+func serveGRPC(addr string, quotes *grpcapi.Server, authn *auth.Authenticator, limiter *ratelimit.Limiter) (func(ctx context.Context), error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("grpc listen: %w", err)
+	}
+	gs := grpc.NewServer(
+		grpc.UnaryInterceptor(grpcapi.UnaryInterceptor(authn, limiter)),
+		grpc.StreamInterceptor(grpcapi.StreamInterceptor(authn, limiter)),
+	)
+	quotesv1.RegisterQuoteServiceServer(gs, quotes)
+	healthServer := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(gs, healthServer)
+	reflection.Register(gs)
+
+	go func() {
+		slog.Info("grpc server listening", "addr", addr)
+		if err := gs.Serve(lis); err != nil {
+			slog.Error("grpc Serve error", "error", err)
+		}
+	}()
+	return func(ctx context.Context) {
+		healthServer.Shutdown()
+		quotes.Close()
+		stopped := make(chan struct{})
+		go func() {
+			gs.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			gs.Stop()
+		}
+	}, nil
+}
+
+// repositories groups the storage backends selected by the DSN.
+type repositories struct {
+	Quotes  repository.QuoteRepository
+	APIKeys repository.APIKeyRepository
+	Quota   repository.ProviderQuotaRepository
+	// Idempotency keeps responses to requests sent with an Idempotency-Key.
+	Idempotency repository.IdempotencyRepository
+	Backfills   repository.BackfillRepository
+	// DB is nil for the in-memory backend.
+	DB *sql.DB
+}
+
+// newRepositories picks the storage backend from the DSN: "memory://" keeps
+// data in process memory, "sqlite://" uses a SQLite file and anything else
+// is treated as a Postgres DSN.
+func newRepositories(cfg config.Database) (*repositories, error) {
+	dsn := cfg.DSN
+	if strings.HasPrefix(dsn, "memory://") {
+		slog.Warn("using in-memory storage, quotes will be lost on restart")
+		return &repositories{
+			Quotes:      memory.NewQuoteRepository(),
+			APIKeys:     memory.NewAPIKeyRepository(),
+			Quota:       memory.NewProviderQuotaRepository(),
+			Idempotency: memory.NewIdempotencyRepository(),
+			Backfills:   memory.NewBackfillRepository(),
+		}, nil
+	}
+	database, err := db.InitializeDb(cfg)
+	if err != nil {
+		return nil, err
+	}
+	repos := &repositories{DB: database}
+	if driver, _ := db.ParseDSN(dsn); driver == db.DriverSQLite {
+		repos.Quotes, err = sqlite.NewQuoteRepository(database)
+		if err == nil {
+			repos.APIKeys, err = sqlite.NewAPIKeyRepository(database)
+		}
+		if err == nil {
+			repos.Quota, err = sqlite.NewProviderQuotaRepository(database)
+		}
+		if err == nil {
+			repos.Idempotency, err = sqlite.NewIdempotencyRepository(database)
+		}
+		if err == nil {
+			repos.Backfills, err = sqlite.NewBackfillRepository(database)
+		}
+	} else {
+		repos.Quotes, err = postgres.NewQuoteRepository(database)
+		if err == nil {
+			repos.APIKeys, err = postgres.NewAPIKeyRepository(database)
+		}
+		if err == nil {
+			repos.Quota, err = postgres.NewProviderQuotaRepository(database)
+		}
+		if err == nil {
+			repos.Idempotency, err = postgres.NewIdempotencyRepository(database)
+		}
+		if err == nil {
+			repos.Backfills, err = postgres.NewBackfillRepository(database)
+		}
+	}
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+	return repos, nil
+}
+
+// connectRepositories opens the storage backend, retrying until it succeeds
+// or ctx is done. Until then the db and migrations readiness checks fail
+// with the last error.
+func connectRepositories(ctx context.Context, cfg config.Database, checker *health.Checker) (*repositories, bool) {
+	var lastErr atomic.Pointer[error]
+	notConnected := errors.New("not connected yet")
+	lastErr.Store(&notConnected)
+	checker.Add("db", func(context.Context) error {
+		return fmt.Errorf("database unavailable: %w", *lastErr.Load())
+	})
+	checker.Add("migrations", func(context.Context) error {
+		return errors.New("migrations not applied yet")
+	})
+
+	result := make(chan *repositories, 1)
+	go func() {
+		for {
+			repos, err := newRepositories(cfg)
+			if err == nil {
+				result <- repos
+				return
+			}
+			lastErr.Store(&err)
+			slog.Error("database unavailable, retrying", "component", "db", "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(cfg.ConnectRetryDelay.Duration):
+			}
+		}
+	}()
+	select {
+	case repos := <-result:
+		return repos, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
 
-func setupRoutes(h *api.Handler) *http.ServeMux {
+// startupRoutes serves the health and metrics endpoints while the database
+// is being connected. Every other request is answered with 503.
+func startupRoutes(checker *health.Checker) *http.ServeMux {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/quotes/update", h.PostStartAsyncUpdateQuote)
-	mux.HandleFunc("/quotes/update/", h.GetQuoteByRequestId)
-	mux.HandleFunc("/quotes/last/", h.GetLastQuote)
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.HandleFunc("GET /healthz", checker.Liveness)
+	mux.HandleFunc("GET /readyz", checker.Readiness)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "service starting", http.StatusServiceUnavailable)
+	})
 	return mux
 }
 
-func runServer() error {
-	database := db.InitializeDb()
-	defer database.Close()
+// swappableHandler lets the HTTP server start with the startup routes and
+// switch to the full API once it is ready.
+type swappableHandler struct {
+	h atomic.Pointer[http.Handler]
+}
+
+func (s *swappableHandler) Set(h http.Handler) {
+	s.h.Store(&h)
+}
 
-	supportedCurrency, err := tools.LoadSupportedCurrencies("./supported_currency.json")
+func (s *swappableHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*s.h.Load()).ServeHTTP(w, r)
+}
+
+// addHealthChecks registers the readiness checks of the running service,
+// replacing the startup ones.
+func addHealthChecks(checker *health.Checker, cfg *config.Config, database *sql.DB, jobChan chan worker.QuoteJob, breaker *provider.CircuitBreaker) {
+	if database == nil {
+		checker.Remove("db")
+		checker.Remove("migrations")
+	} else {
+		checker.Add("db", database.PingContext)
+		driver, _ := db.ParseDSN(cfg.Database.DSN)
+		checker.Add("migrations", func(ctx context.Context) error {
+			migrator, err := db.NewMigrator(database, driver)
+			if err != nil {
+				return err
+			}
+			pending, err := migrator.Pending(ctx)
+			if err != nil {
+				return err
+			}
+			if pending > 0 {
+				return fmt.Errorf("%d migrations pending", pending)
+			}
+			return nil
+		})
+	}
+	checker.Add("workers", func(ctx context.Context) error {
+		if running := worker.Running(); running < cfg.Worker.Count {
+			return fmt.Errorf("%d of %d workers running", running, cfg.Worker.Count)
+		}
+		return nil
+	})
+	checker.Add("queue", func(ctx context.Context) error {
+		if float64(len(jobChan)) >= cfg.Worker.QueueSaturation*float64(cap(jobChan)) {
+			return fmt.Errorf("queue saturated: %d of %d jobs", len(jobChan), cap(jobChan))
+		}
+		return nil
+	})
+	checker.Add("provider", func(ctx context.Context) error {
+		if breaker.State() == provider.CircuitOpen {
+			return fmt.Errorf("%s circuit is open", breaker.Name())
+		}
+		return nil
+	})
+}
+
+// newLimiter builds the per-client rate limiter. Buckets are kept in memory
+// unless the shared Postgres store is configured.
+func newLimiter(ctx context.Context, cfg config.RateLimit, database *sql.DB) *ratelimit.Limiter {
+	routes := make(map[string]ratelimit.Limit)
+	var window time.Duration
+	if cfg.Enabled {
+		for route, l := range cfg.Routes {
+			limit := ratelimit.Per(l.Requests, l.Per.Duration, l.Burst)
+			routes[route] = limit
+			window = max(window, limit.Window())
+		}
+	}
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.Store == "postgres" {
+		pgStore := postgres.NewRateLimitStore(database, window)
+		go pgStore.Cleanup(ctx, time.Minute)
+		store = pgStore
+	}
+	limiter := ratelimit.NewLimiter(store, routes)
+	limiter.TrustForwardedFor = cfg.TrustForwardedFor
+	return limiter
+}
+
+// expireIdempotencyRecords deletes records past the idempotency window until
+// ctx is done. Expired records are ignored anyway, this only bounds storage.
+func expireIdempotencyRecords(ctx context.Context, repo repository.IdempotencyRepository, window time.Duration) {
+	ticker := time.NewTicker(min(window, time.Hour))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := repo.DeleteIdempotencyRecords(time.Now().Add(-window))
+			if err != nil {
+				slog.Warn("idempotency cleanup failed", "component", "api", "error", err)
+				continue
+			}
+			if n > 0 {
+				slog.Debug("idempotency records deleted", "component", "api", "count", n)
+			}
+		}
+	}
+}
+
+// checkInverses compares the pairs supported both ways every interval until
+// ctx is done, and exports the deviations as metrics.
+func checkInverses(ctx context.Context, checker *service.InverseChecker, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		checks, err := checker.Check(ctx)
+		if err != nil {
+			slog.Warn("inverse check failed", "component", "consistency", "error", err)
+		}
+		for _, c := range checks {
+			inconsistent := 0.0
+			if !c.Consistent {
+				inconsistent = 1
+				slog.Warn("pair and inverse disagree", "component", "consistency", "currency", c.Pair, "inverse", c.Inverse,
+					"price", c.Price, "inverse_price", c.InversePrice, "deviation", c.Deviation)
+			}
+			metrics.InverseDeviation.WithLabelValues(c.Pair, c.Inverse).Set(c.Deviation)
+			metrics.InverseInconsistent.WithLabelValues(c.Pair, c.Inverse).Set(inconsistent)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// loadLastQuotes reads the latest done quote of every supported pair, which
+// fills the cache and reports their age to the quote age metric.
+func loadLastQuotes(ctx context.Context, srv *service.QuoteService, supported map[string]bool) {
+	for currency := range supported {
+		if _, err := srv.GetLastQuote(ctx, currency, model.StatusDone); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			slog.Warn("failed to load last quote", "currency", currency, "error", err)
+		}
+	}
+}
+
+// requeuePending queues the pending quotes no running instance looks after,
+// e.g. jobs a stopped replica still had queued or deferred. Without it their
+// currency could never be updated again, since only one pending quote is
+// allowed. The quotes queued here stay claimed by instance while it renews
+// its claims, so replicas sharing the database do not queue them twice.
+// Jobs go through the deferrer, which stops sending once shutdown begins.
+func requeuePending(ctx context.Context, repo repository.QuoteRepository, deferrer *worker.Deferrer, instance string, ttl time.Duration) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		claimed, err := repo.ClaimPendingQuotes(instance, now, now.Add(ttl))
+		if err != nil {
+			slog.Error("failed to claim pending quotes", "error", err)
+		}
+		for _, q := range claimed {
+			deferrer.Defer(worker.QuoteJob{Id: q.ID, Currency: q.Currency}, now)
+		}
+		if len(claimed) > 0 {
+			slog.Info("pending quotes requeued", "count", len(claimed))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runServer(cfg *config.Config) error {
+	supportedCurrency, err := tools.LoadSupportedCurrencies(cfg.CurrenciesFile)
 	if err != nil {
 		return err
 	}
 
-	jobChan := make(chan worker.QuoteJob, jobBufferSize)
-	srv := service.NewQuoteService(database)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.TracingExporter)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("tracing shutdown", "error", err)
+		}
+	}()
+
+	// The listener starts before the database is reached, so that a replica
+	// waiting for it reports alive but not ready instead of restarting.
+	checker := health.NewChecker(cfg.HealthCheckTimeout.Duration)
+	handler := &swappableHandler{}
+	handler.Set(logging.Middleware(metrics.Middleware(startupRoutes(checker))))
+	server := &http.Server{Addr: cfg.Server.Addr, Handler: handler}
+	go func() {
+		slog.Info("server listening", "addr", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("ListenAndServe error", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	repos, ok := connectRepositories(ctx, cfg.Database, checker)
+	if !ok {
+		slog.Info("shutdown signal received before the database was ready")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
+	database := repos.DB
+	if database != nil {
+		defer database.Close()
+	}
+
+	authn := auth.NewAuthenticator(repos.APIKeys)
+	authn.Disabled = !cfg.Auth.Enabled
+	if authn.Disabled {
+		slog.Warn("API key authentication is disabled")
+	}
+	if cfg.Auth.BootstrapKey != "" {
+		if err := auth.Bootstrap(repos.APIKeys, cfg.Auth.BootstrapKey); err != nil {
+			return fmt.Errorf("bootstrap api key: %w", err)
+		}
+	}
+
+	srv := service.NewQuoteService(repos.Quotes)
+	srv.Cache = cache.NewQuoteCache(cfg.CacheTTL.Duration)
+	srv.Updates = service.NewBroadcaster()
+	if cfg.Worker.RequeuePending {
+		srv.Instance = uuid.New().String()
+		srv.ClaimTTL = cfg.Worker.ClaimTTL.Duration
+		slog.Info("claiming pending quotes", "instance", srv.Instance, "claim_ttl", srv.ClaimTTL)
+	}
+	if driver, _ := db.ParseDSN(cfg.Database.DSN); database != nil && driver == db.DriverPostgres {
+		notifier := postgres.NewQuoteUpdateNotifier(database)
+		srv.Publisher = notifier
+		go func() {
+			err := notifier.Listen(ctx, cfg.Database.DSN, func(currency string) {
+				if currency == "" {
+					// Notifications may have been missed while reconnecting.
+					srv.Cache.InvalidateAll()
+					loadLastQuotes(ctx, srv, supportedCurrency)
+					return
+				}
+				srv.Cache.Invalidate(currency)
+				// Let streaming clients of this instance see quotes completed elsewhere.
+				if q, err := srv.GetLastQuote(ctx, currency, model.StatusDone); err == nil {
+					srv.Updates.Publish(q)
+				}
+			})
+			if err != nil {
+				slog.Error("quote update listener stopped", "error", err)
+			}
+		}()
+	}
+
+	jobChan := make(chan worker.QuoteJob, cfg.Worker.QueueSize)
 	h := &api.Handler{
 		SupportedCurrency: supportedCurrency,
 		Srv:               srv,
 		JobChan:           jobChan,
 	}
+	h.Refresh = api.RefreshPolicy{
+		Interval: cfg.Refresh.Interval.Duration,
+		Pairs:    make(map[string]time.Duration, len(cfg.Refresh.Pairs)),
+		Public:   cfg.Refresh.PublicCache,
+	}
+	for pair, d := range cfg.Refresh.Pairs {
+		if !supportedCurrency[pair] {
+			slog.Warn("refresh interval set for an unsupported pair", "currency", pair)
+		}
+		h.Refresh.Pairs[pair] = d.Duration
+	}
+	h.Inverse = service.NewInverseChecker(srv, supportedCurrency, cfg.Inverse.Tolerance)
+	go checkInverses(ctx, h.Inverse, cfg.Inverse.CheckInterval.Duration)
+	if cfg.IdempotencyWindow.Duration > 0 {
+		h.Idempotency = repos.Idempotency
+		h.IdempotencyWindow = cfg.IdempotencyWindow.Duration
+		go expireIdempotencyRecords(ctx, repos.Idempotency, cfg.IdempotencyWindow.Duration)
+	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
+	metrics.RegisterQueue(func() int { return len(jobChan) }, func() int { return cap(jobChan) })
+	loadLastQuotes(ctx, srv, supportedCurrency)
+
+	vatcomply := provider.NewVatcomply(cfg.Provider.BaseURL, cfg.Provider.Timeout.Duration)
+	breaker := provider.NewCircuitBreaker(tracing.InstrumentProvider(metrics.InstrumentProvider(vatcomply)), cfg.Provider.BreakerThreshold, cfg.Provider.BreakerCooldown.Duration)
+	throttle := provider.NewThrottle(breaker, provider.ThrottleOptions{
+		RequestsPerSecond: cfg.Provider.RequestsPerSecond,
+		Burst:             cfg.Provider.Burst,
+		DailyQuota:        cfg.Provider.DailyQuota,
+		Quota:             repos.Quota,
+	})
+	metrics.RegisterProviderQuota(throttle.Name(), cfg.Provider.DailyQuota, throttle.QuotaUsed)
 
+	deferrer := worker.NewDeferrer(jobChan)
+	metrics.RegisterDeferredJobs(deferrer.Len)
+	w := &worker.Worker{
+		Jobs:            jobChan,
+		Srv:             srv,
+		Provider:        throttle,
+		ProcessingDelay: cfg.Worker.ProcessingDelay.Duration,
+		Deferrer:        deferrer,
+		DeriveInverse:   make(map[string]bool, len(cfg.Inverse.Derive)),
+		DeriveMaxAge:    cfg.Inverse.MaxAge.Duration,
+	}
+	for _, pair := range cfg.Inverse.Derive {
+		if inverse, _ := model.InversePair(pair); !supportedCurrency[pair] || !supportedCurrency[inverse] {
+			slog.Warn("derived pair or its inverse is not supported", "currency", pair)
+		}
+		w.DeriveInverse[pair] = true
+	}
+	w.Anomaly = service.NewAnomalyDetector(srv, service.AnomalyRule{MaxChange: cfg.Anomaly.MaxChange, MaxSigma: cfg.Anomaly.MaxSigma}, cfg.Anomaly.Window.Duration)
+	w.Anomaly.Pairs = make(map[string]service.AnomalyRule, len(cfg.Anomaly.Pairs))
+	for pair, r := range cfg.Anomaly.Pairs {
+		if !supportedCurrency[pair] {
+			slog.Warn("anomaly rule for an unsupported pair", "currency", pair)
+		}
+		w.Anomaly.Pairs[pair] = service.AnomalyRule{MaxChange: r.MaxChange, MaxSigma: r.MaxSigma}
+	}
 	var wg sync.WaitGroup
-	for i := 0; i < workersCount; i++ {
+	for i := 0; i < cfg.Worker.Count; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			worker.StartWorker(jobChan, srv)
+			w.Run()
+		}()
+	}
+	if cfg.Worker.RequeuePending {
+		go requeuePending(ctx, repos.Quotes, deferrer, srv.Instance, srv.ClaimTTL)
+	}
+	var backfiller *worker.Backfiller
+	if cfg.Backfill.Enabled {
+		backfiller = worker.NewBackfiller(repos.Backfills, srv, throttle)
+		backfiller.Interval = cfg.Backfill.Interval.Duration
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			backfiller.Run(ctx)
 		}()
 	}
 
-	mux := setupRoutes(h)
-	server := &http.Server{
-		Addr:    ":8080",
-		Handler: mux,
+	addHealthChecks(checker, cfg, database, jobChan, breaker)
+	limiter := newLimiter(ctx, cfg.RateLimit, database)
+	live := ws.NewHandler(supportedCurrency, srv, jobChan, srv.Updates, authn)
+	live.Limiter = limiter
+	admin := &api.AdminHandler{
+		Keys:              repos.APIKeys,
+		Importer:          importer.New(supportedCurrency, srv),
+		Backfills:         repos.Backfills,
+		Backfiller:        backfiller,
+		SupportedCurrency: supportedCurrency,
+		Srv:               srv,
 	}
+	mux := setupRoutes(h, admin, live, authn, limiter, checker)
 
-	go func() {
-		log.Printf("Server listening on %s...", server.Addr)
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("ListenAndServe error: %v", err)
+	stopGRPC := func(context.Context) {}
+	if cfg.Server.GRPCAddr != "" {
+		grpcServer := grpcapi.NewServer(supportedCurrency, srv, jobChan, srv.Updates)
+		stopGRPC, err = serveGRPC(cfg.Server.GRPCAddr, grpcServer, authn, limiter)
+		if err != nil {
+			return err
 		}
-	}()
+	}
+
+	// The authenticator sits outside of tracing and metrics: both read the
+	// route pattern that ServeMux sets on the request it receives, so no
+	// middleware below them may replace the request.
+	handler.Set(logging.Middleware(authn.Middleware(tracing.Middleware(metrics.Middleware(mux)))))
+	slog.Info("server ready")
 
 	<-ctx.Done()
-	log.Println("Shutdown signal received, stopping server...")
+	slog.Info("shutdown signal received, stopping server")
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration)
 	defer cancel()
 	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Printf("HTTP server Shutdown: %v", err)
+		slog.Error("HTTP server shutdown", "error", err)
 	}
+	stopGRPC(shutdownCtx)
+	// Shutdown does not wait for hijacked connections, so WebSocket clients
+	// are closed separately before the job queue goes away.
+	live.Close()
 
+	deferrer.Stop()
 	close(jobChan)
 	wg.Wait()
 
-	log.Println("All workers done. Server stopped.")
+	slog.Info("all workers done, server stopped")
 	return nil
 }
 
+func runMigrate(cfg *config.Config, args []string) error {
+	database, err := db.Connect(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	driver, _ := db.ParseDSN(cfg.Database.DSN)
+	migrator, err := db.NewMigrator(database, driver)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("bad number of steps: %s", args[1])
+			}
+		}
+		return migrator.Down(ctx, steps)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = "applied at " + st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", st.Version, st.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q (expected up, down [N] or status)", command)
+	}
+}
+
+func runAPIKey(cfg *config.Config, args []string) error {
+	if strings.HasPrefix(cfg.Database.DSN, "memory://") {
+		return errors.New("api keys of the in-memory backend cannot be managed from the command line, use -auth-bootstrap-key")
+	}
+	repos, err := newRepositories(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer repos.DB.Close()
+
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	switch args[0] {
+	case "create":
+		if len(args) != 3 {
+			return errors.New("usage: apikey create NAME SCOPE[,SCOPE...]")
+		}
+		scopes, err := model.ParseScopes(args[2])
+		if err != nil {
+			return err
+		}
+		secret, key, err := auth.CreateKey(repos.APIKeys, args[1], scopes)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("id:  %s\nkey: %s\n", key.ID, secret)
+		return nil
+	case "list":
+		keys, err := repos.APIKeys.ListAPIKeys()
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			state := "active"
+			if k.Revoked() {
+				state = "revoked at " + k.RevokedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%s\t%s\t%s\t%s\n", k.ID, k.Name, model.FormatScopes(k.Scopes), state)
+		}
+		return nil
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New("usage: apikey revoke ID")
+		}
+		return repos.APIKeys.RevokeAPIKey(args[1])
+	default:
+		return fmt.Errorf("unknown apikey command %q (expected create, list or revoke)", args[0])
+	}
+}
+
+func runImport(cfg *config.Config, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: import FILE|- [csv|ndjson]")
+	}
+	if strings.HasPrefix(cfg.Database.DSN, "memory://") {
+		return errors.New("quotes cannot be imported into the in-memory backend, it is gone when the command exits")
+	}
+	format := importer.FormatCSV
+	if ext := filepath.Ext(args[0]); ext == ".ndjson" || ext == ".jsonl" {
+		format = importer.FormatNDJSON
+	}
+	if len(args) == 2 {
+		format = args[1]
+	}
+	in := os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	supportedCurrency, err := tools.LoadSupportedCurrencies(cfg.CurrenciesFile)
+	if err != nil {
+		return err
+	}
+	repos, err := newRepositories(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer repos.DB.Close()
+
+	imp := importer.New(supportedCurrency, service.NewQuoteService(repos.Quotes))
+	imp.MaxErrors = math.MaxInt
+	rep, err := imp.Import(context.Background(), in, format)
+	for _, e := range rep.Errors {
+		fmt.Printf("line %d: %s\n", e.Line, e.Message)
+	}
+	fmt.Printf("imported: %d\nduplicates: %d\nfailed: %d\n", rep.Imported, rep.Duplicates, rep.Failed)
+	return err
+}
+
 func main() {
-	if err := runServer(); err != nil {
-		log.Fatalf("Startup error: %v", err)
+	logging.Setup(os.Stdout, slog.LevelInfo)
+	cfg, args, printConfig, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		slog.Error("config error", "error", err)
+		os.Exit(1)
+	}
+	if printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			slog.Error("print config", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	level, _ := cfg.SlogLevel()
+	logging.Setup(os.Stdout, level)
+
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrate(cfg, args[1:]); err != nil {
+			slog.Error("migrate error", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "apikey" {
+		if err := runAPIKey(cfg, args[1:]); err != nil {
+			slog.Error("apikey error", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "import" {
+		if err := runImport(cfg, args[1:]); err != nil {
+			slog.Error("import error", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := runServer(cfg); err != nil {
+		slog.Error("startup error", "error", err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"FinQuotesService/internal/auth"
+	"FinQuotesService/internal/importer"
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository"
+	"FinQuotesService/internal/service"
+	"FinQuotesService/internal/worker"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AdminHandler serves the endpoints that require the admin scope.
+type AdminHandler struct {
+	Keys     repository.APIKeyRepository
+	Importer *importer.Importer
+
+	// Backfills stores the backfill jobs, which Backfiller runs. Backfiller
+	// is nil on instances that leave backfills to another one.
+	Backfills         repository.BackfillRepository
+	Backfiller        *worker.Backfiller
+	SupportedCurrency map[string]bool
+
+	// Srv settles the quotes held for review by the anomaly detector.
+	Srv service.QuoteServiceInterface
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string        `json:"name"`
+	Scopes []model.Scope `json:"scopes"`
+}
+
+type APIKeyResponse struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Scopes    []model.Scope `json:"scopes"`
+	CreatedAt time.Time     `json:"created_at"`
+	RevokedAt *time.Time    `json:"revoked_at,omitempty"`
+	// Key is only returned once, when the key is created.
+	Key string `json:"key,omitempty"`
+}
+
+func (h *AdminHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.Keys.ListAPIKeys()
+	if err != nil {
+		serverInternalError(w)
+		return
+	}
+	resp := make([]APIKeyResponse, 0, len(keys))
+	for _, k := range keys {
+		resp = append(resp, mapToAPIKeyResponse(k))
+	}
+	successResponse(w, resp)
+}
+
+func (h *AdminHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" || len(req.Scopes) == 0 {
+		invalidRequestParams(w)
+		return
+	}
+	for _, s := range req.Scopes {
+		if !s.Valid() {
+			invalidRequestParams(w)
+			return
+		}
+	}
+	secret, key, err := auth.CreateKey(h.Keys, req.Name, req.Scopes)
+	if err != nil {
+		serverInternalError(w)
+		return
+	}
+	slog.InfoContext(r.Context(), "api key created", "component", "admin", "api_key_id", key.ID, "name", key.Name, "created_by", auth.KeyID(r.Context()))
+	resp := mapToAPIKeyResponse(key)
+	resp.Key = secret
+	successResponse(w, resp)
+}
+
+func (h *AdminHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := h.Keys.RevokeAPIKey(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			errorResponse(w, http.StatusNotFound, APIKeyNotFound)
+		} else {
+			serverInternalError(w)
+		}
+		return
+	}
+	slog.InfoContext(r.Context(), "api key revoked", "component", "admin", "api_key_id", id, "revoked_by", auth.KeyID(r.Context()))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type ImportResponse struct {
+	Imported   int `json:"imported"`
+	Duplicates int `json:"duplicates"`
+	Failed     int `json:"failed"`
+	// Errors lists the first failed lines, Failed counts all of them.
+	Errors []ImportLineError `json:"errors"`
+}
+
+type ImportLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportQuotes stores the historical quotes in the request body, given as
+// CSV or NDJSON by the format parameter or the Content-Type.
+func (h *AdminHandler) ImportQuotes(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = importer.FormatCSV
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/x-ndjson" {
+			format = importer.FormatNDJSON
+		}
+	}
+	if format != importer.FormatCSV && format != importer.FormatNDJSON {
+		invalidRequestParams(w)
+		return
+	}
+	rep, err := h.Importer.Import(r.Context(), r.Body, format)
+	var storeErr *importer.StoreError
+	if errors.As(err, &storeErr) {
+		slog.ErrorContext(r.Context(), "quote import failed", "component", "admin", "imported", rep.Imported, "error", err)
+		serverInternalError(w)
+		return
+	}
+	if err != nil {
+		invalidRequestParams(w)
+		return
+	}
+	slog.InfoContext(r.Context(), "quotes imported", "component", "admin", "imported", rep.Imported, "duplicates", rep.Duplicates, "failed", rep.Failed, "imported_by", auth.KeyID(r.Context()))
+	resp := ImportResponse{
+		Imported:   rep.Imported,
+		Duplicates: rep.Duplicates,
+		Failed:     rep.Failed,
+		Errors:     make([]ImportLineError, 0, len(rep.Errors)),
+	}
+	for _, e := range rep.Errors {
+		resp.Errors = append(resp.Errors, ImportLineError{Line: e.Line, Error: e.Message})
+	}
+	successResponse(w, resp)
+}
+
+func mapToAPIKeyResponse(k model.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:        k.ID,
+		Name:      k.Name,
+		Scopes:    k.Scopes,
+		CreatedAt: k.CreatedAt,
+		RevokedAt: k.RevokedAt,
+	}
+}
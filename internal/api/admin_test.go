@@ -0,0 +1,149 @@
+package api
+
+import (
+	"FinQuotesService/internal/auth"
+	"FinQuotesService/internal/importer"
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository/memory"
+	"FinQuotesService/internal/worker"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminHandler_CreateListRevoke(t *testing.T) {
+	repo := memory.NewAPIKeyRepository()
+	h := &AdminHandler{Keys: repo}
+
+	body := []byte(`{"name":"ci","scopes":["quotes:read","quotes:update"]}`)
+	w := httptest.NewRecorder()
+	h.CreateAPIKey(w, httptest.NewRequest(http.MethodPost, "/v1/admin/keys", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var created APIKeyResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if created.Key == "" || created.ID == "" || len(created.Scopes) != 2 {
+		t.Fatalf("unexpected response: %+v", created)
+	}
+	stored, err := repo.GetAPIKeyByHash(auth.HashKey(created.Key))
+	if err != nil || stored.ID != created.ID {
+		t.Fatalf("created key is not usable: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	h.ListAPIKeys(w, httptest.NewRequest(http.MethodGet, "/v1/admin/keys", nil))
+	var listed []APIKeyResponse
+	if err := json.NewDecoder(w.Body).Decode(&listed); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(listed) != 1 || listed[0].Key != "" {
+		t.Fatalf("list must not expose secrets: %+v", listed)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/admin/keys/"+created.ID, nil)
+	req.SetPathValue("id", created.ID)
+	w = httptest.NewRecorder()
+	h.RevokeAPIKey(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	h.RevokeAPIKey(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestAdminHandler_CreateInvalid(t *testing.T) {
+	h := &AdminHandler{Keys: memory.NewAPIKeyRepository()}
+	for _, body := range []string{`{`, `{"name":"","scopes":["admin"]}`, `{"name":"x","scopes":[]}`, `{"name":"x","scopes":["root"]}`} {
+		w := httptest.NewRecorder()
+		h.CreateAPIKey(w, httptest.NewRequest(http.MethodPost, "/v1/admin/keys", bytes.NewReader([]byte(body))))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestPostStartAsyncUpdateQuote_RecordsAPIKey(t *testing.T) {
+	var gotRequestedBy string
+	mock := &MockQuoteService{
+		GetLastQuoteFunc: func(ctx context.Context, currency string, status model.Status) (model.Quote, error) {
+			return model.Quote{}, sql.ErrNoRows
+		},
+		InsertPendingQuoteFunc: func(ctx context.Context, currency, requestedBy string) (string, error) {
+			gotRequestedBy = requestedBy
+			return "uuid-123", nil
+		},
+	}
+	h := &Handler{SupportedCurrency: map[string]bool{"USD/EUR": true}, Srv: mock, JobChan: make(chan worker.QuoteJob, 1)}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/quotes/update", bytes.NewReader([]byte(`{"currency":"USD/EUR"}`)))
+	req = req.WithContext(auth.WithKey(req.Context(), model.APIKey{ID: "key-1"}))
+	w := httptest.NewRecorder()
+	h.PostStartAsyncUpdateQuote(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if gotRequestedBy != "key-1" {
+		t.Errorf("expected requestedBy key-1, got %q", gotRequestedBy)
+	}
+}
+
+func TestAdminHandler_ImportQuotes(t *testing.T) {
+	var stored []string
+	mock := &MockQuoteService{
+		InsertHistoricalQuoteFunc: func(ctx context.Context, currency string, price float64, updatedAt time.Time, source string) (string, error) {
+			if source != model.SourceImport {
+				t.Errorf("unexpected source %q", source)
+			}
+			stored = append(stored, currency+" "+updatedAt.Format(time.DateOnly))
+			return "q-1", nil
+		},
+	}
+	h := &AdminHandler{Importer: importer.New(map[string]bool{"USD/EUR": true}, mock)}
+
+	body := `{"pair":"USD/EUR","timestamp":"2024-01-01","price":0.9}` + "\n" + `{"pair":"GBP/USD","timestamp":"2024-01-01","price":1.2}` + "\n"
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/quotes/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	h.ImportQuotes(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp ImportResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if resp.Imported != 1 || resp.Failed != 1 || len(resp.Errors) != 1 || resp.Errors[0].Line != 2 {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if len(stored) != 1 || stored[0] != "USD/EUR 2024-01-01" {
+		t.Errorf("unexpected stored quotes %v", stored)
+	}
+}
+
+func TestAdminHandler_ImportQuotesInvalid(t *testing.T) {
+	h := &AdminHandler{Importer: importer.New(map[string]bool{"USD/EUR": true}, &MockQuoteService{})}
+	for _, tt := range []struct{ url, body string }{
+		{"/v1/admin/quotes/import?format=xlsx", "pair,timestamp,price\n"},
+		{"/v1/admin/quotes/import", "pair,price\nUSD/EUR,0.9\n"},
+	} {
+		w := httptest.NewRecorder()
+		h.ImportQuotes(w, httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", tt.url, w.Code)
+		}
+	}
+}
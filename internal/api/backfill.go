@@ -0,0 +1,157 @@
+package api
+
+import (
+	"FinQuotesService/internal/auth"
+	"FinQuotesService/internal/model"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type CreateBackfillRequest struct {
+	Currency string `json:"currency"`
+	// From and To are UTC days given as YYYY-MM-DD, both included.
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type BackfillResponse struct {
+	ID       string               `json:"id"`
+	Currency string               `json:"currency"`
+	From     string               `json:"from"`
+	To       string               `json:"to"`
+	Status   model.BackfillStatus `json:"status"`
+	// DaysDone counts the days fetched so far out of DaysTotal.
+	DaysTotal  int       `json:"days_total"`
+	DaysDone   int       `json:"days_done"`
+	Inserted   int       `json:"inserted"`
+	Duplicates int       `json:"duplicates"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CreateBackfill schedules the fetch of the historical rates of a pair, one
+// quote per day of the range.
+func (h *AdminHandler) CreateBackfill(w http.ResponseWriter, r *http.Request) {
+	var req CreateBackfillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		invalidRequestParams(w)
+		return
+	}
+	if !h.SupportedCurrency[req.Currency] {
+		unsupportedCurrencyPair(w)
+		return
+	}
+	from, errFrom := time.Parse(time.DateOnly, req.From)
+	to, errTo := time.Parse(time.DateOnly, req.To)
+	if errFrom != nil || errTo != nil || to.Before(from) || to.After(time.Now().UTC()) {
+		invalidRequestParams(w)
+		return
+	}
+	now := time.Now().UTC()
+	bf, err := h.Backfills.CreateBackfill(model.Backfill{
+		Currency:  req.Currency,
+		From:      from,
+		To:        to,
+		Next:      from,
+		Status:    model.BackfillPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		serverInternalError(w)
+		return
+	}
+	slog.InfoContext(r.Context(), "backfill created", "component", "admin", "backfill_id", bf.ID, "currency", bf.Currency, "from", req.From, "to", req.To, "created_by", auth.KeyID(r.Context()))
+	h.notifyBackfiller()
+	successResponse(w, mapToBackfillResponse(bf))
+}
+
+func (h *AdminHandler) ListBackfills(w http.ResponseWriter, r *http.Request) {
+	backfills, err := h.Backfills.ListBackfills()
+	if err != nil {
+		serverInternalError(w)
+		return
+	}
+	resp := make([]BackfillResponse, 0, len(backfills))
+	for _, bf := range backfills {
+		resp = append(resp, mapToBackfillResponse(bf))
+	}
+	successResponse(w, resp)
+}
+
+func (h *AdminHandler) GetBackfill(w http.ResponseWriter, r *http.Request) {
+	bf, ok := h.backfill(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	successResponse(w, mapToBackfillResponse(bf))
+}
+
+// ResumeBackfill retries a failed backfill from the day it failed on.
+func (h *AdminHandler) ResumeBackfill(w http.ResponseWriter, r *http.Request) {
+	bf, ok := h.backfill(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	if bf.Status != model.BackfillFailed {
+		errorResponse(w, http.StatusConflict, BackfillNotFailed)
+		return
+	}
+	bf.Status = model.BackfillPending
+	bf.Error = ""
+	bf.UpdatedAt = time.Now().UTC()
+	if err := h.Backfills.UpdateBackfill(bf); err != nil {
+		serverInternalError(w)
+		return
+	}
+	slog.InfoContext(r.Context(), "backfill resumed", "component", "admin", "backfill_id", bf.ID, "resumed_by", auth.KeyID(r.Context()))
+	h.notifyBackfiller()
+	successResponse(w, mapToBackfillResponse(bf))
+}
+
+// backfill loads a backfill, answering the request itself when it cannot.
+func (h *AdminHandler) backfill(w http.ResponseWriter, id string) (model.Backfill, bool) {
+	bf, err := h.Backfills.GetBackfill(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		errorResponse(w, http.StatusNotFound, BackfillNotFound)
+		return model.Backfill{}, false
+	}
+	if err != nil {
+		serverInternalError(w)
+		return model.Backfill{}, false
+	}
+	return bf, true
+}
+
+func (h *AdminHandler) notifyBackfiller() {
+	if h.Backfiller != nil {
+		h.Backfiller.Notify()
+	}
+}
+
+func mapToBackfillResponse(bf model.Backfill) BackfillResponse {
+	return BackfillResponse{
+		ID:         bf.ID,
+		Currency:   bf.Currency,
+		From:       bf.From.Format(time.DateOnly),
+		To:         bf.To.Format(time.DateOnly),
+		Status:     bf.Status,
+		DaysTotal:  days(bf.From, bf.To) + 1,
+		DaysDone:   days(bf.From, bf.Next),
+		Inserted:   bf.Inserted,
+		Duplicates: bf.Duplicates,
+		Error:      bf.Error,
+		CreatedAt:  bf.CreatedAt,
+		UpdatedAt:  bf.UpdatedAt,
+	}
+}
+
+// days counts the whole days from a to b.
+func days(a, b time.Time) int {
+	return int(b.Sub(a).Round(time.Hour) / (24 * time.Hour))
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository/memory"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newBackfillHandler() *AdminHandler {
+	return &AdminHandler{Backfills: memory.NewBackfillRepository(), SupportedCurrency: map[string]bool{"EUR/USD": true}}
+}
+
+func TestAdminHandler_Backfills(t *testing.T) {
+	h := newBackfillHandler()
+
+	w := httptest.NewRecorder()
+	h.CreateBackfill(w, httptest.NewRequest(http.MethodPost, "/v1/admin/backfills", strings.NewReader(`{"currency":"EUR/USD","from":"2024-01-01","to":"2024-01-31"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	var created BackfillResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if created.ID == "" || created.Status != model.BackfillPending || created.From != "2024-01-01" || created.DaysTotal != 31 || created.DaysDone != 0 {
+		t.Fatalf("unexpected response %+v", created)
+	}
+
+	// Simulate the backfiller failing on the 11th day.
+	bf, err := h.Backfills.GetBackfill(created.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bf.Next = bf.From.AddDate(0, 0, 10)
+	bf.Status = model.BackfillFailed
+	bf.Error = "boom"
+	if err := h.Backfills.UpdateBackfill(bf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/backfills/"+created.ID, nil)
+	req.SetPathValue("id", created.ID)
+	w = httptest.NewRecorder()
+	h.GetBackfill(w, req)
+	var got BackfillResponse
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if got.Status != model.BackfillFailed || got.DaysDone != 10 || got.Error != "boom" {
+		t.Errorf("unexpected backfill %+v", got)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/admin/backfills/"+created.ID+"/resume", nil)
+	req.SetPathValue("id", created.ID)
+	w = httptest.NewRecorder()
+	h.ResumeBackfill(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if bf, _ := h.Backfills.GetBackfill(created.ID); bf.Status != model.BackfillPending || bf.Error != "" || !bf.Next.Equal(bf.From.AddDate(0, 0, 10)) {
+		t.Errorf("expected the backfill to be pending again from day 11, got %+v", bf)
+	}
+	w = httptest.NewRecorder()
+	h.ResumeBackfill(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 for a pending backfill, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ListBackfills(w, httptest.NewRequest(http.MethodGet, "/v1/admin/backfills", nil))
+	var listed []BackfillResponse
+	if err := json.NewDecoder(w.Body).Decode(&listed); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != created.ID {
+		t.Errorf("unexpected list %+v", listed)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/admin/backfills/unknown", nil)
+	req.SetPathValue("id", "unknown")
+	w = httptest.NewRecorder()
+	h.GetBackfill(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestAdminHandler_CreateBackfillInvalid(t *testing.T) {
+	h := newBackfillHandler()
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	tests := []struct {
+		name string
+		body string
+		want ServiceError
+	}{
+		{"bad json", `{`, InvalidRequestParams},
+		{"unsupported pair", `{"currency":"GBP/USD","from":"2024-01-01","to":"2024-01-02"}`, UnsupportedCurrencyPair},
+		{"bad date", `{"currency":"EUR/USD","from":"2024-01-01T00:00:00Z","to":"2024-01-02"}`, InvalidRequestParams},
+		{"reversed range", `{"currency":"EUR/USD","from":"2024-01-02","to":"2024-01-01"}`, InvalidRequestParams},
+		{"future day", `{"currency":"EUR/USD","from":"2024-01-01","to":"` + tomorrow + `"}`, InvalidRequestParams},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.CreateBackfill(w, httptest.NewRequest(http.MethodPost, "/v1/admin/backfills", strings.NewReader(tt.body)))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", w.Code)
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.Message != tt.want {
+				t.Errorf("expected %q, got %+v, %v", tt.want, resp, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"FinQuotesService/internal/model"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// finishedQuoteMaxAge is how long a finished update may be cached when read
+// by its request id; its result does not change anymore.
+const finishedQuoteMaxAge = 24 * time.Hour
+
+// RefreshPolicy is how often the quote of each pair is expected to change.
+// Responses with the latest quote may be cached until the next expected
+// refresh.
+type RefreshPolicy struct {
+	// Interval applies to pairs not listed in Pairs.
+	Interval time.Duration
+	Pairs    map[string]time.Duration
+	// Public lets shared caches such as CDNs store quote responses, which
+	// are then served without checking the API key.
+	Public bool
+}
+
+func (p RefreshPolicy) IntervalFor(currency string) time.Duration {
+	if d, ok := p.Pairs[currency]; ok {
+		return d
+	}
+	return p.Interval
+}
+
+func (p RefreshPolicy) cacheControl(maxAge time.Duration) string {
+	visibility := "private"
+	if p.Public {
+		visibility = "public"
+	}
+	return fmt.Sprintf("%s, max-age=%d", visibility, int64(max(maxAge, 0)/time.Second))
+}
+
+// quoteETag identifies a version of a quote by its id and update time.
+func quoteETag(q model.Quote) string {
+	var updated int64
+	if q.UpdatedAt != nil {
+		updated = q.UpdatedAt.UnixNano()
+	}
+	return `"` + q.ID + "-" + strconv.FormatInt(updated, 36) + `"`
+}
+
+// notModified sets the validators of q and cacheControl on the response and
+// answers with 304 when the request's conditions show the client already has
+// this version. If-None-Match takes precedence over If-Modified-Since.
+func notModified(w http.ResponseWriter, r *http.Request, q model.Quote, cacheControl string) bool {
+	etag := quoteETag(q)
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", cacheControl)
+	if q.UpdatedAt != nil {
+		h.Set("Last-Modified", q.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+
+	match := false
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		match = etagMatches(inm, etag)
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && q.UpdatedAt != nil {
+		if t, err := http.ParseTime(ims); err == nil {
+			match = !q.UpdatedAt.Truncate(time.Second).After(t)
+		}
+	}
+	if match {
+		w.WriteHeader(http.StatusNotModified)
+	}
+	return match
+}
+
+// etagMatches compares with the weak comparison If-None-Match calls for.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
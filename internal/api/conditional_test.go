@@ -0,0 +1,127 @@
+package api
+
+import (
+	"FinQuotesService/internal/model"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetLastQuote_Conditional(t *testing.T) {
+	price := 1.08
+	updated := time.Date(2025, 1, 2, 10, 0, 0, 500, time.UTC)
+	q := model.Quote{ID: "q-1", Currency: "USD/EUR", Price: &price, UpdatedAt: &updated, Status: model.StatusDone}
+	h := &Handler{
+		SupportedCurrency: map[string]bool{"USD/EUR": true},
+		Srv: &MockQuoteService{
+			GetLastQuoteFunc: func(ctx context.Context, currency string, status model.Status) (model.Quote, error) { return q, nil },
+		},
+	}
+	etag := quoteETag(q)
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		status int
+	}{
+		{"unconditional", "", "", http.StatusOK},
+		{"etag matches", "If-None-Match", etag, http.StatusNotModified},
+		{"weak etag in a list", "If-None-Match", `"other", W/` + etag, http.StatusNotModified},
+		{"any etag", "If-None-Match", "*", http.StatusNotModified},
+		{"etag differs", "If-None-Match", `"q-0-1"`, http.StatusOK},
+		{"not modified since", "If-Modified-Since", updated.Format(http.TimeFormat), http.StatusNotModified},
+		{"modified since", "If-Modified-Since", updated.Add(-time.Second).Format(http.TimeFormat), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/quotes/last/USD/EUR", nil)
+			req.SetPathValue("base", "USD")
+			req.SetPathValue("quote", "EUR")
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			h.GetLastQuote(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Errorf("expected ETag %s, got %s", etag, got)
+			}
+			if got := w.Header().Get("Last-Modified"); got != "Thu, 02 Jan 2025 10:00:00 GMT" {
+				t.Errorf("unexpected Last-Modified %q", got)
+			}
+			if tt.status == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("expected an empty body, got %s", w.Body)
+			}
+		})
+	}
+
+	// ETags change with every update, even within the same second.
+	later := updated.Add(time.Millisecond)
+	if quoteETag(model.Quote{ID: "q-1", UpdatedAt: &later}) == etag {
+		t.Errorf("expected a new ETag for a new update time")
+	}
+}
+
+func TestQuoteCacheControl(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-20*time.Second + 500*time.Millisecond)
+	stale := now.Add(-time.Hour)
+	policy := RefreshPolicy{Interval: time.Minute, Pairs: map[string]time.Duration{"USD/MXN": 5 * time.Minute}}
+
+	tests := []struct {
+		name     string
+		currency string
+		updated  *time.Time
+		policy   RefreshPolicy
+		want     string
+	}{
+		{"until next refresh", "USD/EUR", &recent, policy, "private, max-age=40"},
+		{"per pair interval", "USD/MXN", &recent, policy, "private, max-age=280"},
+		{"overdue", "USD/EUR", &stale, policy, "private, max-age=0"},
+		{"public", "USD/EUR", &stale, RefreshPolicy{Interval: time.Minute, Public: true}, "public, max-age=0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := model.Quote{ID: "q-1", Currency: tt.currency, UpdatedAt: tt.updated, Status: model.StatusDone}
+			h := &Handler{
+				SupportedCurrency: map[string]bool{tt.currency: true},
+				Srv: &MockQuoteService{
+					GetLastQuoteFunc: func(ctx context.Context, currency string, status model.Status) (model.Quote, error) { return q, nil },
+				},
+				Refresh: tt.policy,
+			}
+			req := httptest.NewRequest(http.MethodGet, "/v1/quotes/last/"+tt.currency, nil)
+			req.SetPathValue("base", tt.currency[:3])
+			req.SetPathValue("quote", tt.currency[4:])
+			w := httptest.NewRecorder()
+			h.GetLastQuote(w, req)
+			if got := w.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("expected Cache-Control %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetQuoteByRequestId_Conditional(t *testing.T) {
+	updated := time.Now().UTC()
+	q := model.Quote{ID: "q-1", Currency: "USD/EUR", UpdatedAt: &updated, Status: model.StatusError}
+	h := &Handler{Srv: &MockQuoteService{
+		GetQuoteByIdFunc: func(ctx context.Context, id string) (model.Quote, error) { return q, nil },
+	}}
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/update/q-1", nil)
+	req.SetPathValue("request_id", "q-1")
+	req.Header.Set("If-None-Match", quoteETag(q))
+	w := httptest.NewRecorder()
+	h.GetQuoteByRequestId(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", w.Code)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "private, max-age=86400, immutable" {
+		t.Errorf("unexpected Cache-Control %q", got)
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type ConsistencyResponse struct {
+	Tolerance float64                `json:"tolerance"`
+	Pairs     []InverseCheckResponse `json:"pairs"`
+}
+
+type InverseCheckResponse struct {
+	Pair             string    `json:"pair"`
+	Inverse          string    `json:"inverse"`
+	Price            float64   `json:"price"`
+	InversePrice     float64   `json:"inverse_price"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	InverseUpdatedAt time.Time `json:"inverse_updated_at"`
+	// Deviation is how far Price × InversePrice is from 1.
+	Deviation  float64 `json:"deviation"`
+	Consistent bool    `json:"consistent"`
+}
+
+// GetConsistency compares the latest done quotes of every pair supported
+// both ways with those of its inverse.
+func (h *Handler) GetConsistency(w http.ResponseWriter, r *http.Request) {
+	checks, err := h.Inverse.Check(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "inverse check failed", "component", "api", "error", err)
+		serverInternalError(w)
+		return
+	}
+	resp := ConsistencyResponse{Tolerance: h.Inverse.Tolerance, Pairs: make([]InverseCheckResponse, 0, len(checks))}
+	for _, c := range checks {
+		resp.Pairs = append(resp.Pairs, InverseCheckResponse{
+			Pair:             c.Pair,
+			Inverse:          c.Inverse,
+			Price:            c.Price,
+			InversePrice:     c.InversePrice,
+			UpdatedAt:        c.UpdatedAt,
+			InverseUpdatedAt: c.InverseUpdatedAt,
+			Deviation:        c.Deviation,
+			Consistent:       c.Consistent,
+		})
+	}
+	successResponse(w, resp)
+}
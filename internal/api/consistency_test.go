@@ -0,0 +1,62 @@
+package api
+
+import (
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository/memory"
+	"FinQuotesService/internal/service"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetConsistency(t *testing.T) {
+	srv := service.NewQuoteService(memory.NewQuoteRepository())
+	now := time.Now()
+	for _, q := range []struct {
+		currency string
+		price    float64
+	}{{"EUR/USD", 1.25}, {"USD/EUR", 0.8}} {
+		if _, err := srv.InsertHistoricalQuote(context.Background(), q.currency, q.price, now, model.SourceImport); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	supported := map[string]bool{"EUR/USD": true, "USD/EUR": true}
+	h := &Handler{SupportedCurrency: supported, Srv: srv, Inverse: service.NewInverseChecker(srv, supported, 0.001)}
+
+	rec := httptest.NewRecorder()
+	h.GetConsistency(rec, httptest.NewRequest(http.MethodGet, "/v1/quotes/consistency", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var resp ConsistencyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if resp.Tolerance != 0.001 || len(resp.Pairs) != 1 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if p := resp.Pairs[0]; p.Pair != "EUR/USD" || p.Inverse != "USD/EUR" || p.Price != 1.25 || p.InversePrice != 0.8 || !p.Consistent {
+		t.Errorf("unexpected check %+v", p)
+	}
+}
+
+func TestGetConsistency_Failing(t *testing.T) {
+	mock := &MockQuoteService{
+		GetLastQuoteFunc: func(ctx context.Context, currency string, status model.Status) (model.Quote, error) {
+			return model.Quote{}, errors.New("db down")
+		},
+	}
+	supported := map[string]bool{"EUR/USD": true, "USD/EUR": true}
+	h := &Handler{SupportedCurrency: supported, Srv: mock, Inverse: service.NewInverseChecker(mock, supported, 0.001)}
+
+	rec := httptest.NewRecorder()
+	h.GetConsistency(rec, httptest.NewRequest(http.MethodGet, "/v1/quotes/consistency", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", rec.Code)
+	}
+}
@@ -11,4 +11,11 @@ const (
 	ServerInternalError     ServiceError = "Server internal error"
 	QuoteOnPending          ServiceError = "Quote on pending"
 	UnsupportedCurrencyPair ServiceError = "Unsupported currency pair"
+	InvalidRequestParams    ServiceError = "Invalid request parameters"
+	APIKeyNotFound          ServiceError = "API key not found"
+	IdempotencyKeyReused    ServiceError = "Idempotency key reused with a different request"
+	BackfillNotFound        ServiceError = "Backfill not found"
+	BackfillNotFailed       ServiceError = "Only failed backfills can be resumed"
+	SuspectQuoteNotFound    ServiceError = "Suspect quote not found"
+	TooManyRequests         ServiceError = "Too many requests"
 )
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"FinQuotesService/internal/model"
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportFilenameLayout formats the range bounds in export file names.
+const exportFilenameLayout = "20060102T150405Z"
+
+// exportColumns is the header row of CSV exports.
+var exportColumns = []string{"currency", "price", "updated_at"}
+
+// exportEncoder writes quotes in one of the export formats.
+type exportEncoder interface {
+	begin() error
+	write(q QuoteResponse) error
+	end() error
+}
+
+// ExportQuotes streams the done quotes of the requested pairs, pair by pair
+// and oldest first. Rows are written as the database returns them, so memory
+// use does not depend on the range.
+func (h *Handler) ExportQuotes(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	pairs, ok := h.exportPairs(query.Get("pairs"))
+	if !ok {
+		unsupportedCurrencyPair(w)
+		return
+	}
+	from, to, ok := exportRange(query)
+	if !ok {
+		invalidRequestParams(w)
+		return
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	var enc exportEncoder
+	var contentType string
+	switch format {
+	case "csv":
+		enc, contentType = &csvExport{w: csv.NewWriter(w)}, "text/csv; charset=utf-8"
+	case "ndjson":
+		enc, contentType = &ndjsonExport{enc: json.NewEncoder(w)}, "application/x-ndjson"
+	default:
+		invalidRequestParams(w)
+		return
+	}
+
+	// The status is only sent with the first row, so a query that fails
+	// right away still gets a proper error response.
+	started := false
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		filename := "quotes_" + from.UTC().Format(exportFilenameLayout) + "_" + to.UTC().Format(exportFilenameLayout) + "." + format
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+		w.WriteHeader(http.StatusOK)
+		return enc.begin()
+	}
+	for _, pair := range pairs {
+		err := h.Srv.ExportQuotes(r.Context(), pair, from, to, func(q model.Quote) error {
+			if err := start(); err != nil {
+				return err
+			}
+			return enc.write(mapToQuoteResponse(q))
+		})
+		if err == nil {
+			continue
+		}
+		if !started {
+			serverInternalError(w)
+			return
+		}
+		if r.Context().Err() != nil {
+			// The client went away, nobody is left to tell.
+			return
+		}
+		// Headers are gone already. Abort the response so the client sees
+		// a broken transfer rather than a file that looks complete.
+		slog.ErrorContext(r.Context(), "quote export failed", "component", "api", "currency", pair, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+	if err := start(); err != nil {
+		return
+	}
+	enc.end()
+}
+
+// exportPairs parses the comma-separated pairs parameter. An empty list
+// selects every supported pair.
+func (h *Handler) exportPairs(param string) ([]string, bool) {
+	seen := make(map[string]bool)
+	var pairs []string
+	if param == "" {
+		for pair := range h.SupportedCurrency {
+			pairs = append(pairs, pair)
+		}
+		sort.Strings(pairs)
+		return pairs, true
+	}
+	for _, pair := range strings.Split(param, ",") {
+		pair = strings.TrimSpace(pair)
+		if !h.SupportedCurrency[pair] {
+			return nil, false
+		}
+		if !seen[pair] {
+			seen[pair] = true
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs, true
+}
+
+// exportRange parses from and to, which default to the day before now.
+func exportRange(query url.Values) (time.Time, time.Time, bool) {
+	to := time.Now().UTC()
+	if v := query.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		to = t
+	}
+	from := to.Add(-defaultCandlesRange)
+	if v := query.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		from = t
+	}
+	return from, to, from.Before(to)
+}
+
+type csvExport struct {
+	w *csv.Writer
+}
+
+func (e *csvExport) begin() error {
+	return e.w.Write(exportColumns)
+}
+
+func (e *csvExport) write(q QuoteResponse) error {
+	var price, updated string
+	if q.Price != nil {
+		price = strconv.FormatFloat(*q.Price, 'f', -1, 64)
+	}
+	if q.UpdatedAt != nil {
+		updated = q.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return e.w.Write([]string{q.Currency, price, updated})
+}
+
+func (e *csvExport) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonExport struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonExport) begin() error {
+	return nil
+}
+
+func (e *ndjsonExport) write(q QuoteResponse) error {
+	return e.enc.Encode(q)
+}
+
+func (e *ndjsonExport) end() error {
+	return nil
+}
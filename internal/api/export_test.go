@@ -0,0 +1,171 @@
+package api
+
+import (
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository/memory"
+	"FinQuotesService/internal/service"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newExportHandler(t *testing.T) *Handler {
+	t.Helper()
+	srv := service.NewQuoteService(memory.NewQuoteRepository())
+	ctx := context.Background()
+	for _, q := range []struct {
+		currency string
+		price    float64
+	}{{"USD/EUR", 0.91}, {"EUR/USD", 1.09}, {"USD/EUR", 0.92}} {
+		id, err := srv.InsertPendingQuote(ctx, q.currency, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := srv.UpdateQuote(ctx, id, q.price, model.StatusDone); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return &Handler{SupportedCurrency: map[string]bool{"USD/EUR": true, "EUR/USD": true}, Srv: srv}
+}
+
+func TestExportQuotes_CSV(t *testing.T) {
+	h := newExportHandler(t)
+	from := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	to := from.Add(2 * time.Hour)
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/export?pairs=USD/EUR,EUR/USD&from="+from.Format(time.RFC3339)+"&to="+to.Format(time.RFC3339), nil)
+	rec := httptest.NewRecorder()
+
+	h.ExportQuotes(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+	wantDisposition := "attachment; filename=quotes_" + from.Format(exportFilenameLayout) + "_" + to.Format(exportFilenameLayout) + ".csv"
+	if cd := rec.Header().Get("Content-Disposition"); cd != wantDisposition {
+		t.Errorf("expected Content-Disposition %q, got %q", wantDisposition, cd)
+	}
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 4 || strings.Join(records[0], ",") != "currency,price,updated_at" {
+		t.Fatalf("unexpected records %v", records)
+	}
+	var got []string
+	for _, r := range records[1:] {
+		if _, err := time.Parse(time.RFC3339Nano, r[2]); err != nil {
+			t.Errorf("unexpected updated_at %q", r[2])
+		}
+		got = append(got, r[0]+" "+r[1])
+	}
+	if want := "USD/EUR 0.91,USD/EUR 0.92,EUR/USD 1.09"; strings.Join(got, ",") != want {
+		t.Errorf("expected rows %s, got %s", want, strings.Join(got, ","))
+	}
+}
+
+func TestExportQuotes_NDJSON(t *testing.T) {
+	h := newExportHandler(t)
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/export?format=ndjson", nil)
+	rec := httptest.NewRecorder()
+
+	h.ExportQuotes(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.HasSuffix(cd, ".ndjson") {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", rec.Body)
+	}
+	var first QuoteResponse
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Every supported pair is exported, in name order.
+	if first.Currency != "EUR/USD" || first.Price == nil || *first.Price != 1.09 {
+		t.Errorf("unexpected first line %s", lines[0])
+	}
+}
+
+func TestExportQuotes_Empty(t *testing.T) {
+	h := newExportHandler(t)
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/export?to=2020-01-02T00:00:00Z", nil)
+	rec := httptest.NewRecorder()
+
+	h.ExportQuotes(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd != "attachment; filename=quotes_20200101T000000Z_20200102T000000Z.csv" {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	if body := rec.Body.String(); body != "currency,price,updated_at\n" {
+		t.Errorf("expected only the header row, got %q", body)
+	}
+}
+
+func TestExportQuotes_InvalidParams(t *testing.T) {
+	h := newExportHandler(t)
+	tests := []struct {
+		name  string
+		query string
+		want  ServiceError
+	}{
+		{"unsupported pair", "pairs=USD/EUR,GBP/USD", UnsupportedCurrencyPair},
+		{"unknown format", "format=xlsx", InvalidRequestParams},
+		{"bad from", "from=yesterday", InvalidRequestParams},
+		{"empty range", "from=2025-01-02T00:00:00Z&to=2025-01-01T00:00:00Z", InvalidRequestParams},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ExportQuotes(rec, httptest.NewRequest(http.MethodGet, "/v1/quotes/export?"+tt.query, nil))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", rec.Code)
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Message != tt.want {
+				t.Errorf("expected %q, got %+v, %v", tt.want, resp, err)
+			}
+		})
+	}
+}
+
+func TestExportQuotes_FailureAfterFirstRow(t *testing.T) {
+	price := 0.91
+	now := time.Now()
+	h := &Handler{
+		SupportedCurrency: map[string]bool{"USD/EUR": true},
+		Srv: &MockQuoteService{
+			ExportQuotesFunc: func(ctx context.Context, currency string, from, to time.Time, fn func(model.Quote) error) error {
+				if err := fn(model.Quote{Currency: currency, Price: &price, UpdatedAt: &now}); err != nil {
+					return err
+				}
+				return errors.New("connection reset")
+			},
+		},
+	}
+	rec := httptest.NewRecorder()
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("expected the response to be aborted, got %v", p)
+		}
+	}()
+	h.ExportQuotes(rec, httptest.NewRequest(http.MethodGet, "/v1/quotes/export", nil))
+}
@@ -1,33 +1,61 @@
 package api
 
 import (
+	"FinQuotesService/internal/auth"
 	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository"
 	"FinQuotesService/internal/service"
 	"FinQuotesService/internal/worker"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"io"
-	"log"
 	"net/http"
-	"strings"
 	"time"
 )
 
+const defaultCandlesRange = 24 * time.Hour
+const maxCandlesCount = 5000
+
+var candleIntervals = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"4h":  4 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
 type SupportedCurrency map[string]bool
 
 type Handler struct {
 	SupportedCurrency map[string]bool
 	Srv               service.QuoteServiceInterface
 	JobChan           chan worker.QuoteJob
+	// Idempotency, when set, keeps the outcome of update requests sent with
+	// an Idempotency-Key for IdempotencyWindow.
+	Idempotency       repository.IdempotencyRepository
+	IdempotencyWindow time.Duration
+	// Refresh sets the caching headers of quote responses.
+	Refresh RefreshPolicy
+	// Inverse compares the pairs supported both ways.
+	Inverse *service.InverseChecker
 }
 
 type UpdateRequest struct {
 	Currency string `json:"currency"`
+	// MaxAge, in seconds, accepts the latest done quote instead of starting
+	// an update when it is at most that old. Force always starts one.
+	MaxAge int  `json:"max_age,omitempty"`
+	Force  bool `json:"force,omitempty"`
 }
 
 type UpdateResponse struct {
 	RequestId string `json:"request_id"`
+	// Fresh is set when RequestId is an existing done quote that satisfied
+	// max_age, so no update was started.
+	Fresh bool `json:"fresh,omitempty"`
 }
 
 type QuoteResponse struct {
@@ -36,48 +64,67 @@ type QuoteResponse struct {
 	UpdatedAt *time.Time `json:"updated_at,omitempty"`
 }
 
+type CandleResponse struct {
+	Time  time.Time `json:"time"`
+	Open  float64   `json:"open"`
+	High  float64   `json:"high"`
+	Low   float64   `json:"low"`
+	Close float64   `json:"close"`
+	Count int64     `json:"count"`
+}
+
+type CandlesResponse struct {
+	Currency string           `json:"currency"`
+	Interval string           `json:"interval"`
+	Candles  []CandleResponse `json:"candles"`
+}
+
 func (h *Handler) PostStartAsyncUpdateQuote(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		httpMethodNotAllowed(w, "POST")
-		return
-	}
 	var req UpdateRequest
 	body, _ := io.ReadAll(r.Body)
+	idem, ok := h.checkIdempotency(w, r, body)
+	if !ok {
+		return
+	}
 	if err := json.Unmarshal(body, &req); err != nil || !h.SupportedCurrency[req.Currency] {
 		unsupportedCurrencyPair(w)
 		return
 	}
-	var quoteId string
-	quote, err := h.Srv.GetLastQuote(req.Currency, model.StatusPending)
+	if req.MaxAge < 0 {
+		invalidRequestParams(w)
+		return
+	}
+	resp, err := h.startUpdate(r, req)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			quoteId, err = h.Srv.InsertPendingQuote(req.Currency)
-			if err != nil {
-				serverInternalError(w)
-				return
-			}
-			h.JobChan <- worker.QuoteJob{Id: quoteId, Currency: req.Currency}
-			log.Println("[Handler] Job pushed to queue, job_id = " + quoteId)
-		} else {
-			serverInternalError(w)
-			return
-		}
-	} else {
-		quoteId = quote.ID
-		log.Println("[Handler] Existing pending job found, job_id = " + quoteId)
+		serverInternalError(w)
+		return
+	}
+	if idem != nil {
+		h.saveIdempotency(w, r, idem, resp)
+		return
 	}
-
-	resp := UpdateResponse{RequestId: quoteId}
 	successResponse(w, resp)
 }
 
-func (h *Handler) GetQuoteByRequestId(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		httpMethodNotAllowed(w, "GET")
-		return
+// startUpdate answers with the latest done quote when it satisfies max_age,
+// and otherwise starts an update or reuses the pending one.
+func (h *Handler) startUpdate(r *http.Request, req UpdateRequest) (UpdateResponse, error) {
+	if req.MaxAge > 0 && !req.Force {
+		q, fresh, err := worker.FreshQuote(r.Context(), h.Srv, req.Currency, time.Duration(req.MaxAge)*time.Second)
+		if err != nil {
+			return UpdateResponse{}, err
+		}
+		if fresh {
+			return UpdateResponse{RequestId: q.ID, Fresh: true}, nil
+		}
 	}
-	requestId := strings.TrimPrefix(r.URL.Path, "/quotes/update/")
-	q, err := h.Srv.GetQuoteById(requestId)
+	quoteId, err := worker.StartUpdate(r.Context(), h.Srv, h.JobChan, req.Currency, auth.KeyID(r.Context()))
+	return UpdateResponse{RequestId: quoteId}, err
+}
+
+func (h *Handler) GetQuoteByRequestId(w http.ResponseWriter, r *http.Request) {
+	requestId := r.PathValue("request_id")
+	q, err := h.Srv.GetQuoteById(r.Context(), requestId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			quoteNotFoundError(w)
@@ -86,25 +133,29 @@ func (h *Handler) GetQuoteByRequestId(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	if q.Status == model.StatusPending {
+	// A suspect quote is still waiting for review, so it is reported like a
+	// pending one, and the rate of a rejected quote is never served.
+	if q.Status == model.StatusPending || q.Status == model.StatusSuspect {
 		quoteOnPendingError(w)
 		return
 	}
+	if q.Status == model.StatusRejected {
+		q.Price = nil
+	}
+	if notModified(w, r, q, h.Refresh.cacheControl(finishedQuoteMaxAge)+", immutable") {
+		return
+	}
 	resp := mapToQuoteResponse(q)
 	successResponse(w, resp)
 }
 
 func (h *Handler) GetLastQuote(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		httpMethodNotAllowed(w, "GET")
-		return
-	}
-	currency := strings.TrimPrefix(r.URL.Path, "/quotes/last/")
+	currency := pathCurrency(r)
 	if !h.SupportedCurrency[currency] {
 		unsupportedCurrencyPair(w)
 		return
 	}
-	q, err := h.Srv.GetLastQuote(currency, model.StatusDone)
+	q, err := h.Srv.GetLastQuote(r.Context(), currency, model.StatusDone)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			quoteNotFoundError(w)
@@ -113,23 +164,85 @@ func (h *Handler) GetLastQuote(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	// The quote may be reused until the pair is next expected to refresh.
+	maxAge := h.Refresh.IntervalFor(currency)
+	if q.UpdatedAt != nil {
+		maxAge -= time.Since(*q.UpdatedAt)
+	}
+	if notModified(w, r, q, h.Refresh.cacheControl(maxAge)) {
+		return
+	}
 	resp := mapToQuoteResponse(q)
 	successResponse(w, resp)
 }
 
-func httpMethodNotAllowed(w http.ResponseWriter, targetMethod string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusMethodNotAllowed)
-	err := json.NewEncoder(w).Encode(targetMethod + " only")
+func (h *Handler) GetCandles(w http.ResponseWriter, r *http.Request) {
+	currency := pathCurrency(r)
+	if !h.SupportedCurrency[currency] {
+		unsupportedCurrencyPair(w)
+		return
+	}
+	query := r.URL.Query()
+	intervalName := query.Get("interval")
+	if intervalName == "" {
+		intervalName = "1h"
+	}
+	interval, ok := candleIntervals[intervalName]
+	if !ok {
+		invalidRequestParams(w)
+		return
+	}
+	to := time.Now().UTC()
+	if v := query.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			invalidRequestParams(w)
+			return
+		}
+		to = t
+	}
+	from := to.Add(-defaultCandlesRange)
+	if v := query.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			invalidRequestParams(w)
+			return
+		}
+		from = t
+	}
+	if !from.Before(to) || to.Sub(from)/interval > maxCandlesCount {
+		invalidRequestParams(w)
+		return
+	}
+	candles, err := h.Srv.GetCandles(r.Context(), currency, interval, from, to)
 	if err != nil {
+		serverInternalError(w)
 		return
 	}
+	resp := CandlesResponse{
+		Currency: currency,
+		Interval: intervalName,
+		Candles:  make([]CandleResponse, 0, len(candles)),
+	}
+	for _, c := range candles {
+		resp.Candles = append(resp.Candles, mapToCandleResponse(c))
+	}
+	successResponse(w, resp)
+}
+
+// pathCurrency joins the {base} and {quote} path segments into a pair.
+func pathCurrency(r *http.Request) string {
+	return r.PathValue("base") + "/" + r.PathValue("quote")
 }
 
 func unsupportedCurrencyPair(w http.ResponseWriter) {
 	errorResponse(w, http.StatusBadRequest, UnsupportedCurrencyPair)
 }
 
+func invalidRequestParams(w http.ResponseWriter) {
+	errorResponse(w, http.StatusBadRequest, InvalidRequestParams)
+}
+
 func quoteNotFoundError(w http.ResponseWriter) {
 	errorResponse(w, http.StatusNotFound, QuoteNotFound)
 }
@@ -169,3 +282,14 @@ func mapToQuoteResponse(q model.Quote) QuoteResponse {
 		UpdatedAt: q.UpdatedAt,
 	}
 }
+
+func mapToCandleResponse(c model.Candle) CandleResponse {
+	return CandleResponse{
+		Time:  c.Bucket,
+		Open:  c.Open,
+		High:  c.High,
+		Low:   c.Low,
+		Close: c.Close,
+		Count: c.Count,
+	}
+}
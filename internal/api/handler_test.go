@@ -1,9 +1,11 @@
 package api
 
 import (
+	"FinQuotesService/internal/logging"
 	"FinQuotesService/internal/model"
 	"FinQuotesService/internal/worker"
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -14,33 +16,53 @@ import (
 )
 
 type MockQuoteService struct {
-	InsertPendingQuoteFunc func(currency string) (string, error)
-	UpdateQuoteFunc        func(id string, price float64, status model.Status) error
-	GetQuoteByIdFunc       func(id string) (model.Quote, error)
-	GetLastQuoteFunc       func(currency string, status model.Status) (model.Quote, error)
+	InsertPendingQuoteFunc    func(ctx context.Context, currency, requestedBy string) (string, error)
+	UpdateQuoteFunc           func(ctx context.Context, id string, price float64, status model.Status) error
+	GetQuoteByIdFunc          func(ctx context.Context, id string) (model.Quote, error)
+	GetLastQuoteFunc          func(ctx context.Context, currency string, status model.Status) (model.Quote, error)
+	GetCandlesFunc            func(ctx context.Context, currency string, interval time.Duration, from, to time.Time) ([]model.Candle, error)
+	ExportQuotesFunc          func(ctx context.Context, currency string, from, to time.Time, fn func(model.Quote) error) error
+	InsertHistoricalQuoteFunc func(ctx context.Context, currency string, price float64, updatedAt time.Time, source string) (string, error)
+	ListSuspectQuotesFunc     func(ctx context.Context) ([]model.Quote, error)
+	ReviewQuoteFunc           func(ctx context.Context, id string, approve bool) error
 }
 
-func (m *MockQuoteService) InsertPendingQuote(currency string) (string, error) {
-	return m.InsertPendingQuoteFunc(currency)
+func (m *MockQuoteService) InsertPendingQuote(ctx context.Context, currency, requestedBy string) (string, error) {
+	return m.InsertPendingQuoteFunc(ctx, currency, requestedBy)
 }
-func (m *MockQuoteService) UpdateQuote(id string, price float64, status model.Status) error {
-	return m.UpdateQuoteFunc(id, price, status)
+func (m *MockQuoteService) UpdateQuote(ctx context.Context, id string, price float64, status model.Status) error {
+	return m.UpdateQuoteFunc(ctx, id, price, status)
 }
-func (m *MockQuoteService) GetQuoteById(id string) (model.Quote, error) {
-	return m.GetQuoteByIdFunc(id)
+func (m *MockQuoteService) GetQuoteById(ctx context.Context, id string) (model.Quote, error) {
+	return m.GetQuoteByIdFunc(ctx, id)
 }
-func (m *MockQuoteService) GetLastQuote(currency string, status model.Status) (model.Quote, error) {
-	return m.GetLastQuoteFunc(currency, status)
+func (m *MockQuoteService) GetLastQuote(ctx context.Context, currency string, status model.Status) (model.Quote, error) {
+	return m.GetLastQuoteFunc(ctx, currency, status)
+}
+func (m *MockQuoteService) GetCandles(ctx context.Context, currency string, interval time.Duration, from, to time.Time) ([]model.Candle, error) {
+	return m.GetCandlesFunc(ctx, currency, interval, from, to)
+}
+func (m *MockQuoteService) ExportQuotes(ctx context.Context, currency string, from, to time.Time, fn func(model.Quote) error) error {
+	return m.ExportQuotesFunc(ctx, currency, from, to, fn)
+}
+func (m *MockQuoteService) InsertHistoricalQuote(ctx context.Context, currency string, price float64, updatedAt time.Time, source string) (string, error) {
+	return m.InsertHistoricalQuoteFunc(ctx, currency, price, updatedAt, source)
+}
+func (m *MockQuoteService) ListSuspectQuotes(ctx context.Context) ([]model.Quote, error) {
+	return m.ListSuspectQuotesFunc(ctx)
+}
+func (m *MockQuoteService) ReviewQuote(ctx context.Context, id string, approve bool) error {
+	return m.ReviewQuoteFunc(ctx, id, approve)
 }
 
 func TestPostStartAsyncUpdateQuote_NewPending(t *testing.T) {
 	supported := map[string]bool{"USD/EUR": true}
 	jobChan := make(chan worker.QuoteJob, 1)
 	mock := &MockQuoteService{
-		GetLastQuoteFunc: func(currency string, status model.Status) (model.Quote, error) {
+		GetLastQuoteFunc: func(ctx context.Context, currency string, status model.Status) (model.Quote, error) {
 			return model.Quote{}, sql.ErrNoRows
 		},
-		InsertPendingQuoteFunc: func(currency string) (string, error) {
+		InsertPendingQuoteFunc: func(ctx context.Context, currency, requestedBy string) (string, error) {
 			if currency != "USD/EUR" {
 				t.Errorf("expected USD/EUR, got %s", currency)
 			}
@@ -50,7 +72,7 @@ func TestPostStartAsyncUpdateQuote_NewPending(t *testing.T) {
 	h := &Handler{SupportedCurrency: supported, Srv: mock, JobChan: jobChan}
 
 	body := []byte(`{"currency":"USD/EUR"}`)
-	req := httptest.NewRequest(http.MethodPost, "/quotes/update", bytes.NewReader(body))
+	req := httptest.NewRequest(http.MethodPost, "/v1/quotes/update", bytes.NewReader(body))
 	w := httptest.NewRecorder()
 
 	h.PostStartAsyncUpdateQuote(w, req)
@@ -86,13 +108,13 @@ func TestPostStartAsyncUpdateQuote_ExistingPending(t *testing.T) {
 	supported := map[string]bool{"USD/EUR": true}
 	jobChan := make(chan worker.QuoteJob, 1)
 	mock := &MockQuoteService{
-		GetLastQuoteFunc: func(currency string, status model.Status) (model.Quote, error) {
+		GetLastQuoteFunc: func(ctx context.Context, currency string, status model.Status) (model.Quote, error) {
 			return model.Quote{ID: "uuid-999"}, nil
 		},
 	}
 	h := &Handler{SupportedCurrency: supported, Srv: mock, JobChan: jobChan}
 	body := []byte(`{"currency":"USD/EUR"}`)
-	req := httptest.NewRequest(http.MethodPost, "/quotes/update", bytes.NewReader(body))
+	req := httptest.NewRequest(http.MethodPost, "/v1/quotes/update", bytes.NewReader(body))
 	w := httptest.NewRecorder()
 
 	h.PostStartAsyncUpdateQuote(w, req)
@@ -124,7 +146,7 @@ func TestPostStartAsyncUpdateQuote_UnsupportedCurrency(t *testing.T) {
 	h := &Handler{SupportedCurrency: supported, Srv: mock, JobChan: jobChan}
 
 	body := []byte(`{"currency":"GBP/USD"}`)
-	req := httptest.NewRequest(http.MethodPost, "/quotes/update", bytes.NewReader(body))
+	req := httptest.NewRequest(http.MethodPost, "/v1/quotes/update", bytes.NewReader(body))
 	w := httptest.NewRecorder()
 
 	h.PostStartAsyncUpdateQuote(w, req)
@@ -139,16 +161,16 @@ func TestPostStartAsyncUpdateQuote_ServerErrorOnInsert(t *testing.T) {
 	supported := map[string]bool{"USD/EUR": true}
 	jobChan := make(chan worker.QuoteJob, 1)
 	mock := &MockQuoteService{
-		GetLastQuoteFunc: func(currency string, status model.Status) (model.Quote, error) {
+		GetLastQuoteFunc: func(ctx context.Context, currency string, status model.Status) (model.Quote, error) {
 			return model.Quote{}, sql.ErrNoRows
 		},
-		InsertPendingQuoteFunc: func(currency string) (string, error) {
+		InsertPendingQuoteFunc: func(ctx context.Context, currency, requestedBy string) (string, error) {
 			return "", errors.New("db error")
 		},
 	}
 	h := &Handler{SupportedCurrency: supported, Srv: mock, JobChan: jobChan}
 	body := []byte(`{"currency":"USD/EUR"}`)
-	req := httptest.NewRequest(http.MethodPost, "/quotes/update", bytes.NewReader(body))
+	req := httptest.NewRequest(http.MethodPost, "/v1/quotes/update", bytes.NewReader(body))
 	w := httptest.NewRecorder()
 
 	h.PostStartAsyncUpdateQuote(w, req)
@@ -159,9 +181,65 @@ func TestPostStartAsyncUpdateQuote_ServerErrorOnInsert(t *testing.T) {
 	}
 }
 
+func TestPostStartAsyncUpdateQuote_MaxAge(t *testing.T) {
+	price := 0.92
+	updated := time.Now().Add(-2 * time.Minute)
+	tests := []struct {
+		name      string
+		body      string
+		status    int
+		requestId string
+		fresh     bool
+	}{
+		{"fresh enough", `{"currency":"USD/EUR","max_age":300}`, http.StatusOK, "uuid-done", true},
+		{"too old", `{"currency":"USD/EUR","max_age":60}`, http.StatusOK, "uuid-new", false},
+		{"forced", `{"currency":"USD/EUR","max_age":300,"force":true}`, http.StatusOK, "uuid-new", false},
+		{"no max age", `{"currency":"USD/EUR"}`, http.StatusOK, "uuid-new", false},
+		{"negative", `{"currency":"USD/EUR","max_age":-1}`, http.StatusBadRequest, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockQuoteService{
+				GetLastQuoteFunc: func(ctx context.Context, currency string, status model.Status) (model.Quote, error) {
+					if status == model.StatusDone {
+						return model.Quote{ID: "uuid-done", Currency: currency, Price: &price, UpdatedAt: &updated, Status: model.StatusDone}, nil
+					}
+					return model.Quote{}, sql.ErrNoRows
+				},
+				InsertPendingQuoteFunc: func(ctx context.Context, currency, requestedBy string) (string, error) {
+					return "uuid-new", nil
+				},
+			}
+			jobChan := make(chan worker.QuoteJob, 1)
+			h := &Handler{SupportedCurrency: map[string]bool{"USD/EUR": true}, Srv: mock, JobChan: jobChan}
+			req := httptest.NewRequest(http.MethodPost, "/v1/quotes/update", bytes.NewReader([]byte(tt.body)))
+			w := httptest.NewRecorder()
+
+			h.PostStartAsyncUpdateQuote(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var out UpdateResponse
+			if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if out.RequestId != tt.requestId || out.Fresh != tt.fresh {
+				t.Errorf("expected %s fresh=%v, got %+v", tt.requestId, tt.fresh, out)
+			}
+			if queued := len(jobChan) == 1; queued == tt.fresh {
+				t.Errorf("expected a job to be queued only when the quote is not fresh, queued=%v", queued)
+			}
+		})
+	}
+}
+
 func TestGetQuoteByRequestId_Success(t *testing.T) {
 	mock := &MockQuoteService{
-		GetQuoteByIdFunc: func(id string) (model.Quote, error) {
+		GetQuoteByIdFunc: func(ctx context.Context, id string) (model.Quote, error) {
 			price := 10.0
 			now := time.Now()
 			return model.Quote{
@@ -174,7 +252,8 @@ func TestGetQuoteByRequestId_Success(t *testing.T) {
 		},
 	}
 	h := &Handler{Srv: mock}
-	req := httptest.NewRequest(http.MethodGet, "/quotes/update/uuid-1", nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/update/uuid-1", nil)
+	req.SetPathValue("request_id", "uuid-1")
 	w := httptest.NewRecorder()
 
 	h.GetQuoteByRequestId(w, req)
@@ -194,12 +273,13 @@ func TestGetQuoteByRequestId_Success(t *testing.T) {
 
 func TestGetQuoteByRequestId_NotFound(t *testing.T) {
 	mock := &MockQuoteService{
-		GetQuoteByIdFunc: func(id string) (model.Quote, error) {
+		GetQuoteByIdFunc: func(ctx context.Context, id string) (model.Quote, error) {
 			return model.Quote{}, sql.ErrNoRows
 		},
 	}
 	h := &Handler{Srv: mock}
-	req := httptest.NewRequest(http.MethodGet, "/quotes/update/not-exist", nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/update/not-exist", nil)
+	req.SetPathValue("request_id", "not-exist")
 	w := httptest.NewRecorder()
 
 	h.GetQuoteByRequestId(w, req)
@@ -212,12 +292,13 @@ func TestGetQuoteByRequestId_NotFound(t *testing.T) {
 
 func TestGetQuoteByRequestId_Pending(t *testing.T) {
 	mock := &MockQuoteService{
-		GetQuoteByIdFunc: func(id string) (model.Quote, error) {
+		GetQuoteByIdFunc: func(ctx context.Context, id string) (model.Quote, error) {
 			return model.Quote{Status: model.StatusPending}, nil
 		},
 	}
 	h := &Handler{Srv: mock}
-	req := httptest.NewRequest(http.MethodGet, "/quotes/update/pending", nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/update/pending", nil)
+	req.SetPathValue("request_id", "pending")
 	w := httptest.NewRecorder()
 
 	h.GetQuoteByRequestId(w, req)
@@ -230,12 +311,13 @@ func TestGetQuoteByRequestId_Pending(t *testing.T) {
 
 func TestGetQuoteByRequestId_ServerError(t *testing.T) {
 	mock := &MockQuoteService{
-		GetQuoteByIdFunc: func(id string) (model.Quote, error) {
+		GetQuoteByIdFunc: func(ctx context.Context, id string) (model.Quote, error) {
 			return model.Quote{}, errors.New("db error")
 		},
 	}
 	h := &Handler{Srv: mock}
-	req := httptest.NewRequest(http.MethodGet, "/quotes/update/some", nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/update/some", nil)
+	req.SetPathValue("request_id", "some")
 	w := httptest.NewRecorder()
 
 	h.GetQuoteByRequestId(w, req)
@@ -248,7 +330,7 @@ func TestGetQuoteByRequestId_ServerError(t *testing.T) {
 
 func TestGetLastQuote_Success(t *testing.T) {
 	mock := &MockQuoteService{
-		GetLastQuoteFunc: func(currency string, status model.Status) (model.Quote, error) {
+		GetLastQuoteFunc: func(ctx context.Context, currency string, status model.Status) (model.Quote, error) {
 			price := 1.1
 			now := time.Now()
 			return model.Quote{
@@ -262,7 +344,9 @@ func TestGetLastQuote_Success(t *testing.T) {
 	}
 	supported := map[string]bool{"EUR/USD": true}
 	h := &Handler{SupportedCurrency: supported, Srv: mock}
-	req := httptest.NewRequest(http.MethodGet, "/quotes/last/EUR/USD", nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/last/EUR/USD", nil)
+	req.SetPathValue("base", "EUR")
+	req.SetPathValue("quote", "USD")
 	w := httptest.NewRecorder()
 
 	h.GetLastQuote(w, req)
@@ -284,7 +368,9 @@ func TestGetLastQuote_NotSupported(t *testing.T) {
 	mock := &MockQuoteService{}
 	supported := map[string]bool{"EUR/USD": true}
 	h := &Handler{SupportedCurrency: supported, Srv: mock}
-	req := httptest.NewRequest(http.MethodGet, "/quotes/last/GBP/USD", nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/last/GBP/USD", nil)
+	req.SetPathValue("base", "GBP")
+	req.SetPathValue("quote", "USD")
 	w := httptest.NewRecorder()
 
 	h.GetLastQuote(w, req)
@@ -297,13 +383,15 @@ func TestGetLastQuote_NotSupported(t *testing.T) {
 
 func TestGetLastQuote_NotFound(t *testing.T) {
 	mock := &MockQuoteService{
-		GetLastQuoteFunc: func(currency string, status model.Status) (model.Quote, error) {
+		GetLastQuoteFunc: func(ctx context.Context, currency string, status model.Status) (model.Quote, error) {
 			return model.Quote{}, sql.ErrNoRows
 		},
 	}
 	supported := map[string]bool{"EUR/USD": true}
 	h := &Handler{SupportedCurrency: supported, Srv: mock}
-	req := httptest.NewRequest(http.MethodGet, "/quotes/last/EUR/USD", nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/last/EUR/USD", nil)
+	req.SetPathValue("base", "EUR")
+	req.SetPathValue("quote", "USD")
 	w := httptest.NewRecorder()
 
 	h.GetLastQuote(w, req)
@@ -316,13 +404,15 @@ func TestGetLastQuote_NotFound(t *testing.T) {
 
 func TestGetLastQuote_ServerError(t *testing.T) {
 	mock := &MockQuoteService{
-		GetLastQuoteFunc: func(currency string, status model.Status) (model.Quote, error) {
+		GetLastQuoteFunc: func(ctx context.Context, currency string, status model.Status) (model.Quote, error) {
 			return model.Quote{}, errors.New("db error")
 		},
 	}
 	supported := map[string]bool{"EUR/USD": true}
 	h := &Handler{SupportedCurrency: supported, Srv: mock}
-	req := httptest.NewRequest(http.MethodGet, "/quotes/last/EUR/USD", nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/last/EUR/USD", nil)
+	req.SetPathValue("base", "EUR")
+	req.SetPathValue("quote", "USD")
 	w := httptest.NewRecorder()
 
 	h.GetLastQuote(w, req)
@@ -332,3 +422,131 @@ func TestGetLastQuote_ServerError(t *testing.T) {
 		t.Fatalf("expected 500, got %d", resp.StatusCode)
 	}
 }
+
+func TestGetCandles_Success(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(2 * time.Hour)
+	mock := &MockQuoteService{
+		GetCandlesFunc: func(ctx context.Context, currency string, interval time.Duration, gotFrom, gotTo time.Time) ([]model.Candle, error) {
+			if currency != "EUR/USD" {
+				t.Errorf("expected EUR/USD, got %s", currency)
+			}
+			if interval != 15*time.Minute {
+				t.Errorf("expected 15m interval, got %v", interval)
+			}
+			if !gotFrom.Equal(from) || !gotTo.Equal(to) {
+				t.Errorf("unexpected range: %v - %v", gotFrom, gotTo)
+			}
+			return []model.Candle{
+				{Bucket: from, Open: 1.1, High: 1.2, Low: 1.0, Close: 1.15, Count: 3},
+			}, nil
+		},
+	}
+	supported := map[string]bool{"EUR/USD": true}
+	h := &Handler{SupportedCurrency: supported, Srv: mock}
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/candles/EUR/USD?interval=15m&from=2025-01-01T00:00:00Z&to=2025-01-01T02:00:00Z", nil)
+	req.SetPathValue("base", "EUR")
+	req.SetPathValue("quote", "USD")
+	w := httptest.NewRecorder()
+
+	h.GetCandles(w, req)
+	resp := w.Result()
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var cr CandlesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&cr); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if cr.Interval != "15m" {
+		t.Errorf("wrong interval: %s", cr.Interval)
+	}
+	if len(cr.Candles) != 1 || cr.Candles[0].Count != 3 || cr.Candles[0].Close != 1.15 {
+		t.Errorf("unexpected candles: %+v", cr.Candles)
+	}
+}
+
+func TestGetCandles_InvalidInterval(t *testing.T) {
+	mock := &MockQuoteService{}
+	supported := map[string]bool{"EUR/USD": true}
+	h := &Handler{SupportedCurrency: supported, Srv: mock}
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/candles/EUR/USD?interval=7m", nil)
+	req.SetPathValue("base", "EUR")
+	req.SetPathValue("quote", "USD")
+	w := httptest.NewRecorder()
+
+	h.GetCandles(w, req)
+	resp := w.Result()
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestGetCandles_InvalidRange(t *testing.T) {
+	mock := &MockQuoteService{}
+	supported := map[string]bool{"EUR/USD": true}
+	h := &Handler{SupportedCurrency: supported, Srv: mock}
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/candles/EUR/USD?from=2025-01-02T00:00:00Z&to=2025-01-01T00:00:00Z", nil)
+	req.SetPathValue("base", "EUR")
+	req.SetPathValue("quote", "USD")
+	w := httptest.NewRecorder()
+
+	h.GetCandles(w, req)
+	resp := w.Result()
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestGetCandles_NotSupported(t *testing.T) {
+	mock := &MockQuoteService{}
+	supported := map[string]bool{"EUR/USD": true}
+	h := &Handler{SupportedCurrency: supported, Srv: mock}
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/candles/GBP/USD", nil)
+	req.SetPathValue("base", "GBP")
+	req.SetPathValue("quote", "USD")
+	w := httptest.NewRecorder()
+
+	h.GetCandles(w, req)
+	resp := w.Result()
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestPostStartAsyncUpdateQuote_PropagatesRequestId(t *testing.T) {
+	supported := map[string]bool{"USD/EUR": true}
+	jobChan := make(chan worker.QuoteJob, 1)
+	mock := &MockQuoteService{
+		GetLastQuoteFunc: func(ctx context.Context, currency string, status model.Status) (model.Quote, error) {
+			return model.Quote{}, sql.ErrNoRows
+		},
+		InsertPendingQuoteFunc: func(ctx context.Context, currency, requestedBy string) (string, error) {
+			return "uuid-123", nil
+		},
+	}
+	h := &Handler{SupportedCurrency: supported, Srv: mock, JobChan: jobChan}
+
+	body := []byte(`{"currency":"USD/EUR"}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/quotes/update", bytes.NewReader(body))
+	req.Header.Set(logging.RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+
+	logging.Middleware(http.HandlerFunc(h.PostStartAsyncUpdateQuote)).ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	select {
+	case job := <-jobChan:
+		if job.RequestId != "req-42" {
+			t.Errorf("expected job.RequestId req-42, got %q", job.RequestId)
+		}
+	default:
+		t.Errorf("no job sent to channel")
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"FinQuotesService/internal/auth"
+	"FinQuotesService/internal/model"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// IdempotencyKeyHeader lets clients retry POST /quotes/update without
+// starting a second update.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses replayed from a stored key.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+const maxIdempotencyKeyLength = 255
+
+// idempotentRequest is an update request sent with an Idempotency-Key whose
+// outcome is yet to be stored.
+type idempotentRequest struct {
+	rec       model.IdempotencyRecord
+	notBefore time.Time
+}
+
+// checkIdempotency answers requests reusing an Idempotency-Key: a retry of
+// the same body gets the stored response, any other body is rejected. It
+// returns false when the request has been answered, and a nil request when
+// there is no key to store.
+func (h *Handler) checkIdempotency(w http.ResponseWriter, r *http.Request, body []byte) (*idempotentRequest, bool) {
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if key == "" || h.Idempotency == nil {
+		return nil, true
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		invalidRequestParams(w)
+		return nil, false
+	}
+	sum := sha256.Sum256(body)
+	now := time.Now()
+	req := &idempotentRequest{
+		rec: model.IdempotencyRecord{
+			ClientID:    auth.KeyID(r.Context()),
+			Key:         key,
+			RequestHash: hex.EncodeToString(sum[:]),
+			StatusCode:  http.StatusOK,
+			CreatedAt:   now,
+		},
+		notBefore: now.Add(-h.IdempotencyWindow),
+	}
+	stored, err := h.Idempotency.GetIdempotencyRecord(req.rec.ClientID, key, req.notBefore)
+	if errors.Is(err, sql.ErrNoRows) {
+		return req, true
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "idempotency key lookup failed", "component", "api", "error", err)
+		serverInternalError(w)
+		return nil, false
+	}
+	replay(w, stored, req.rec.RequestHash)
+	return nil, false
+}
+
+// saveIdempotency stores the outcome of req and answers with whichever
+// outcome was kept: a concurrent request with the same key may have been
+// stored first.
+func (h *Handler) saveIdempotency(w http.ResponseWriter, r *http.Request, req *idempotentRequest, resp UpdateResponse) {
+	req.rec.RequestID = resp.RequestId
+	req.rec.Fresh = resp.Fresh
+	stored, err := h.Idempotency.SaveIdempotencyRecord(req.rec, req.notBefore)
+	if err != nil {
+		// The update has started, so answer anyway; a retry starts a new one.
+		slog.ErrorContext(r.Context(), "failed to save idempotency key", "component", "api", "error", err)
+		successResponse(w, resp)
+		return
+	}
+	if stored.RequestID == resp.RequestId && stored.RequestHash == req.rec.RequestHash {
+		successResponse(w, resp)
+		return
+	}
+	replay(w, stored, req.rec.RequestHash)
+}
+
+func replay(w http.ResponseWriter, stored model.IdempotencyRecord, requestHash string) {
+	if stored.RequestHash != requestHash {
+		errorResponse(w, http.StatusUnprocessableEntity, IdempotencyKeyReused)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(stored.StatusCode)
+	json.NewEncoder(w).Encode(UpdateResponse{RequestId: stored.RequestID, Fresh: stored.Fresh})
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"FinQuotesService/internal/auth"
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository/memory"
+	"FinQuotesService/internal/worker"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPostStartAsyncUpdateQuote_IdempotencyKey(t *testing.T) {
+	inserted := 0
+	mock := &MockQuoteService{
+		GetLastQuoteFunc: func(ctx context.Context, currency string, status model.Status) (model.Quote, error) {
+			return model.Quote{}, sql.ErrNoRows
+		},
+		InsertPendingQuoteFunc: func(ctx context.Context, currency, requestedBy string) (string, error) {
+			inserted++
+			return fmt.Sprintf("uuid-%d", inserted), nil
+		},
+	}
+	jobChan := make(chan worker.QuoteJob, 10)
+	h := &Handler{
+		SupportedCurrency: map[string]bool{"USD/EUR": true, "EUR/USD": true},
+		Srv:               mock,
+		JobChan:           jobChan,
+		Idempotency:       memory.NewIdempotencyRepository(),
+		IdempotencyWindow: time.Hour,
+	}
+	post := func(key, keyID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/quotes/update", bytes.NewReader([]byte(body)))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		req = req.WithContext(auth.WithKey(req.Context(), model.APIKey{ID: keyID}))
+		w := httptest.NewRecorder()
+		h.PostStartAsyncUpdateQuote(w, req)
+		return w
+	}
+	requestID := func(w *httptest.ResponseRecorder) string {
+		t.Helper()
+		var out UpdateResponse
+		if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		return out.RequestId
+	}
+
+	first := post("retry-1", "key-1", `{"currency":"USD/EUR"}`)
+	if first.Code != http.StatusOK || first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("expected a fresh 200, got %d %v", first.Code, first.Header())
+	}
+	id := requestID(first)
+
+	replayed := post("retry-1", "key-1", `{"currency":"USD/EUR"}`)
+	if replayed.Code != http.StatusOK || replayed.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Fatalf("expected a replayed 200, got %d %v", replayed.Code, replayed.Header())
+	}
+	if got := requestID(replayed); got != id {
+		t.Errorf("expected request_id %s, got %s", id, got)
+	}
+	if len(jobChan) != 1 {
+		t.Errorf("expected a single queued job, got %d", len(jobChan))
+	}
+
+	reused := post("retry-1", "key-1", `{"currency":"EUR/USD"}`)
+	if reused.Code != http.StatusUnprocessableEntity || !strings.Contains(reused.Body.String(), string(IdempotencyKeyReused)) {
+		t.Errorf("expected 422, got %d: %s", reused.Code, reused.Body)
+	}
+
+	if other := post("retry-1", "key-2", `{"currency":"EUR/USD"}`); other.Code != http.StatusOK || requestID(other) == id {
+		t.Errorf("expected keys to be scoped per client, got %d", other.Code)
+	}
+	if long := post(strings.Repeat("k", maxIdempotencyKeyLength+1), "key-1", `{"currency":"USD/EUR"}`); long.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an oversized key, got %d", long.Code)
+	}
+}
+
+func TestPostStartAsyncUpdateQuote_IdempotencyKeyFresh(t *testing.T) {
+	updatedAt := time.Now().Add(-time.Minute)
+	price := 0.92
+	mock := &MockQuoteService{
+		GetLastQuoteFunc: func(ctx context.Context, currency string, status model.Status) (model.Quote, error) {
+			return model.Quote{ID: "uuid-done", Currency: currency, Price: &price, UpdatedAt: &updatedAt, Status: model.StatusDone}, nil
+		},
+	}
+	h := &Handler{
+		SupportedCurrency: map[string]bool{"USD/EUR": true},
+		Srv:               mock,
+		JobChan:           make(chan worker.QuoteJob, 1),
+		Idempotency:       memory.NewIdempotencyRepository(),
+		IdempotencyWindow: time.Hour,
+	}
+	post := func() UpdateResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/v1/quotes/update", bytes.NewReader([]byte(`{"currency":"USD/EUR","max_age":300}`)))
+		req.Header.Set(IdempotencyKeyHeader, "retry-1")
+		w := httptest.NewRecorder()
+		h.PostStartAsyncUpdateQuote(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+		}
+		var out UpdateResponse
+		if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		return out
+	}
+
+	if first := post(); first.RequestId != "uuid-done" || !first.Fresh {
+		t.Fatalf("expected the done quote to be served, got %+v", first)
+	}
+	if replayed := post(); replayed.RequestId != "uuid-done" || !replayed.Fresh {
+		t.Errorf("expected the replay to keep fresh, got %+v", replayed)
+	}
+}
+
+func TestPostStartAsyncUpdateQuote_IdempotencyKeyExpired(t *testing.T) {
+	mock := &MockQuoteService{
+		GetLastQuoteFunc: func(ctx context.Context, currency string, status model.Status) (model.Quote, error) {
+			return model.Quote{}, sql.ErrNoRows
+		},
+		InsertPendingQuoteFunc: func(ctx context.Context, currency, requestedBy string) (string, error) {
+			return "uuid-new", nil
+		},
+	}
+	repo := memory.NewIdempotencyRepository()
+	repo.SaveIdempotencyRecord(model.IdempotencyRecord{Key: "retry-1", RequestHash: "other", RequestID: "uuid-old", StatusCode: http.StatusOK, CreatedAt: time.Now().Add(-2 * time.Hour)}, time.Time{})
+	h := &Handler{
+		SupportedCurrency: map[string]bool{"USD/EUR": true},
+		Srv:               mock,
+		JobChan:           make(chan worker.QuoteJob, 1),
+		Idempotency:       repo,
+		IdempotencyWindow: time.Hour,
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v1/quotes/update", bytes.NewReader([]byte(`{"currency":"USD/EUR"}`)))
+	req.Header.Set(IdempotencyKeyHeader, "retry-1")
+	w := httptest.NewRecorder()
+	h.PostStartAsyncUpdateQuote(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "uuid-new") {
+		t.Fatalf("expected an expired key to start a new update, got %d: %s", w.Code, w.Body)
+	}
+	if rec, err := repo.GetIdempotencyRecord("", "retry-1", time.Now().Add(-time.Hour)); err != nil || rec.RequestID != "uuid-new" {
+		t.Errorf("expected the key to be stored again, got %+v, %v", rec, err)
+	}
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec documents every route served by cmd/server. It is maintained by
+// hand; TestOpenAPIContract fails when a handler response or response type
+// drifts from it.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPI serves the OpenAPI 3 document of the API.
+func OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openAPISpec)
+}
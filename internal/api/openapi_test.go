@@ -0,0 +1,398 @@
+package api
+
+import (
+	"FinQuotesService/internal/auth"
+	"FinQuotesService/internal/importer"
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/ratelimit"
+	"FinQuotesService/internal/repository/memory"
+	"FinQuotesService/internal/service"
+	"FinQuotesService/internal/worker"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type spec map[string]any
+
+func loadSpec(t *testing.T) spec {
+	t.Helper()
+	var s spec
+	if err := json.Unmarshal(openAPISpec, &s); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	return s
+}
+
+// resolve follows a local "#/components/..." reference.
+func (s spec) resolve(node map[string]any) map[string]any {
+	ref, ok := node["$ref"].(string)
+	if !ok {
+		return node
+	}
+	var cur any = map[string]any(s)
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		cur = cur.(map[string]any)[part]
+	}
+	return s.resolve(cur.(map[string]any))
+}
+
+func (s spec) schema(name string) map[string]any {
+	return s["components"].(map[string]any)["schemas"].(map[string]any)[name].(map[string]any)
+}
+
+// validate checks value against the subset of JSON Schema used by
+// openapi.json. Objects may not carry properties the schema does not declare.
+func (s spec) validate(path string, value any, schema map[string]any) error {
+	schema = s.resolve(schema)
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+	}
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %T", path, value)
+		}
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				return fmt.Errorf("%s: missing required %q", path, name)
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		extra, _ := schema["additionalProperties"].(map[string]any)
+		if props == nil && extra == nil {
+			break
+		}
+		for name, v := range obj {
+			prop, ok := props[name].(map[string]any)
+			if !ok {
+				prop = extra
+			}
+			if prop == nil {
+				return fmt.Errorf("%s: undocumented property %q", path, name)
+			}
+			if err := s.validate(path+"."+name, v, prop); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %T", path, value)
+		}
+		for i, v := range arr {
+			if err := s.validate(path+"["+strconv.Itoa(i)+"]", v, schema["items"].(map[string]any)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string, got %T", path, value)
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return fmt.Errorf("%s: %q is not a date-time", path, str)
+			}
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: expected number, got %T", path, value)
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != math.Trunc(n) {
+			return fmt.Errorf("%s: expected integer, got %v", path, value)
+		}
+	}
+	return nil
+}
+
+// checkResponse asserts that the status of rec is documented for the
+// operation and that its body matches the documented schema.
+func (s spec) checkResponse(method, path string, rec *httptest.ResponseRecorder) error {
+	item, ok := s["paths"].(map[string]any)[path].(map[string]any)
+	if !ok {
+		return fmt.Errorf("path %s is not documented", path)
+	}
+	op, ok := item[strings.ToLower(method)].(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+	resp, ok := op["responses"].(map[string]any)[strconv.Itoa(rec.Code)].(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s %s: status %d is not documented", method, path, rec.Code)
+	}
+	resp = s.resolve(resp)
+	content, ok := resp["content"].(map[string]any)
+	if !ok {
+		if rec.Body.Len() != 0 {
+			return fmt.Errorf("%s %s: status %d is documented without a body", method, path, rec.Code)
+		}
+		return nil
+	}
+	contentType, _, _ := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	media, ok := content[contentType].(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s %s: content type %q is not documented", method, path, contentType)
+	}
+	if contentType != "application/json" {
+		return nil
+	}
+	var body any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		return fmt.Errorf("%s %s: invalid JSON body: %v", method, path, err)
+	}
+	return s.validate("body", body, media["schema"].(map[string]any))
+}
+
+func TestOpenAPIContract(t *testing.T) {
+	s := loadSpec(t)
+	price := 1.08
+	now := time.Now().UTC()
+	done := model.Quote{ID: "q-1", Currency: "USD/EUR", Price: &price, UpdatedAt: &now, Status: model.StatusDone}
+	failing := errors.New("db error")
+
+	keys := memory.NewAPIKeyRepository()
+	readKey, _ := keys.CreateAPIKey("reader", auth.HashKey("reader"), []model.Scope{model.ScopeQuotesRead})
+	revokable, _ := keys.CreateAPIKey("old", auth.HashKey("old"), []model.Scope{model.ScopeQuotesRead})
+	authn := auth.NewAuthenticator(keys)
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[string]ratelimit.Limit{
+		"/quotes/update": ratelimit.Per(1, time.Hour, 1),
+	})
+	limited := limiter.Wrap("/quotes/update", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	limited(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/quotes/update", nil))
+
+	quotes := func(mock *MockQuoteService) *Handler {
+		return &Handler{SupportedCurrency: map[string]bool{"USD/EUR": true}, Srv: mock, JobChan: make(chan worker.QuoteJob, 1)}
+	}
+	lastQuote := func(q model.Quote, err error) *MockQuoteService {
+		return &MockQuoteService{
+			GetLastQuoteFunc: func(ctx context.Context, currency string, status model.Status) (model.Quote, error) { return q, err },
+			GetQuoteByIdFunc: func(ctx context.Context, id string) (model.Quote, error) { return q, err },
+			InsertPendingQuoteFunc: func(ctx context.Context, currency, requestedBy string) (string, error) {
+				return "q-2", nil
+			},
+		}
+	}
+	candles := func(err error) *MockQuoteService {
+		return &MockQuoteService{
+			GetCandlesFunc: func(ctx context.Context, currency string, interval time.Duration, from, to time.Time) ([]model.Candle, error) {
+				return []model.Candle{{Bucket: now.Truncate(time.Hour), Open: 1, High: 2, Low: 0.5, Close: 1.5, Count: 3}}, err
+			},
+		}
+	}
+	consistency := func(mock *MockQuoteService) *Handler {
+		h := quotes(mock)
+		h.Inverse = service.NewInverseChecker(mock, map[string]bool{"USD/EUR": true, "EUR/USD": true}, 0.001)
+		return h
+	}
+	export := func(err error) *MockQuoteService {
+		return &MockQuoteService{
+			ExportQuotesFunc: func(ctx context.Context, currency string, from, to time.Time, fn func(model.Quote) error) error {
+				if err != nil {
+					return err
+				}
+				return fn(done)
+			},
+		}
+	}
+	admin := &AdminHandler{Keys: keys, Importer: importer.New(map[string]bool{"USD/EUR": true}, &MockQuoteService{
+		InsertHistoricalQuoteFunc: func(ctx context.Context, currency string, price float64, updatedAt time.Time, source string) (string, error) {
+			return "q-3", nil
+		},
+	})}
+	admin.SupportedCurrency = map[string]bool{"USD/EUR": true}
+	admin.Backfills = memory.NewBackfillRepository()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	running, err := admin.Backfills.CreateBackfill(model.Backfill{Currency: "USD/EUR", From: day, To: day, Next: day, Status: model.BackfillRunning, CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	failed, err := admin.Backfills.CreateBackfill(model.Backfill{Currency: "USD/EUR", From: day, To: day, Next: day, Status: model.BackfillFailed, Error: "boom", CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reviews := service.NewQuoteService(memory.NewQuoteRepository())
+	admin.Srv = reviews
+	var suspect [2]string
+	for i := range suspect {
+		suspect[i], _ = reviews.InsertPendingQuote(context.Background(), "USD/EUR", "")
+		if err := reviews.UpdateQuote(context.Background(), suspect[i], 1.5, model.StatusSuspect); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	idempotent := quotes(lastQuote(model.Quote{}, sql.ErrNoRows))
+	idempotent.Idempotency = memory.NewIdempotencyRepository()
+	idempotent.IdempotencyWindow = time.Hour
+	withHeader := func(name, value string, next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Set(name, value)
+			next(w, r)
+		}
+	}
+	withHeader(IdempotencyKeyHeader, "retry-1", idempotent.PostStartAsyncUpdateQuote)(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/quotes/update", strings.NewReader(`{"currency":"USD/EUR"}`)))
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string // path template in openapi.json, registered as the ServeMux pattern
+		url     string
+		body    string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{"update queued", "POST", "/v1/quotes/update", "/v1/quotes/update", `{"currency":"USD/EUR"}`, quotes(lastQuote(model.Quote{}, sql.ErrNoRows)).PostStartAsyncUpdateQuote, 200},
+		{"update unsupported", "POST", "/v1/quotes/update", "/v1/quotes/update", `{"currency":"GBP/USD"}`, quotes(nil).PostStartAsyncUpdateQuote, 400},
+		{"update failing", "POST", "/v1/quotes/update", "/v1/quotes/update", `{"currency":"USD/EUR"}`, quotes(lastQuote(model.Quote{}, failing)).PostStartAsyncUpdateQuote, 500},
+		{"update wrong method", "POST", "/v1/quotes/update", "/v1/quotes/update", "", quotes(nil).PostStartAsyncUpdateQuote, 405},
+		{"update replayed", "POST", "/v1/quotes/update", "/v1/quotes/update", `{"currency":"USD/EUR"}`, withHeader(IdempotencyKeyHeader, "retry-1", idempotent.PostStartAsyncUpdateQuote), 200},
+		{"update key reused", "POST", "/v1/quotes/update", "/v1/quotes/update", `{"currency":"EUR/USD"}`, withHeader(IdempotencyKeyHeader, "retry-1", idempotent.PostStartAsyncUpdateQuote), 422},
+		{"update limited", "POST", "/v1/quotes/update", "/v1/quotes/update", "", limited, 429},
+		{"result done", "GET", "/v1/quotes/update/{request_id}", "/v1/quotes/update/q-1", "", quotes(lastQuote(done, nil)).GetQuoteByRequestId, 200},
+		{"result not modified", "GET", "/v1/quotes/update/{request_id}", "/v1/quotes/update/q-1", "", withHeader("If-None-Match", quoteETag(done), quotes(lastQuote(done, nil)).GetQuoteByRequestId), 304},
+		{"result pending", "GET", "/v1/quotes/update/{request_id}", "/v1/quotes/update/q-1", "", quotes(lastQuote(model.Quote{Status: model.StatusPending}, nil)).GetQuoteByRequestId, 425},
+		{"result suspect", "GET", "/v1/quotes/update/{request_id}", "/v1/quotes/update/q-1", "", quotes(lastQuote(model.Quote{Status: model.StatusSuspect}, nil)).GetQuoteByRequestId, 425},
+		{"result rejected", "GET", "/v1/quotes/update/{request_id}", "/v1/quotes/update/q-1", "", quotes(lastQuote(model.Quote{Currency: "USD/EUR", Price: &price, UpdatedAt: &now, Status: model.StatusRejected}, nil)).GetQuoteByRequestId, 200},
+		{"result missing", "GET", "/v1/quotes/update/{request_id}", "/v1/quotes/update/q-1", "", quotes(lastQuote(model.Quote{}, sql.ErrNoRows)).GetQuoteByRequestId, 404},
+		{"result no key", "GET", "/v1/quotes/update/{request_id}", "/v1/quotes/update/q-1", "", authn.Require(model.ScopeQuotesRead, quotes(nil).GetQuoteByRequestId), 401},
+		{"last", "GET", "/v1/quotes/last/{base}/{quote}", "/v1/quotes/last/USD/EUR", "", quotes(lastQuote(done, nil)).GetLastQuote, 200},
+		{"last not modified", "GET", "/v1/quotes/last/{base}/{quote}", "/v1/quotes/last/USD/EUR", "", withHeader("If-Modified-Since", now.Add(time.Second).Format(http.TimeFormat), quotes(lastQuote(done, nil)).GetLastQuote), 304},
+		{"last failed update", "GET", "/v1/quotes/last/{base}/{quote}", "/v1/quotes/last/USD/EUR", "", quotes(lastQuote(model.Quote{Currency: "USD/EUR", Status: model.StatusError}, nil)).GetLastQuote, 200},
+		{"last unsupported", "GET", "/v1/quotes/last/{base}/{quote}", "/v1/quotes/last/GBP/USD", "", quotes(nil).GetLastQuote, 400},
+		{"last missing", "GET", "/v1/quotes/last/{base}/{quote}", "/v1/quotes/last/USD/EUR", "", quotes(lastQuote(model.Quote{}, sql.ErrNoRows)).GetLastQuote, 404},
+		{"candles", "GET", "/v1/quotes/candles/{base}/{quote}", "/v1/quotes/candles/USD/EUR?interval=1h", "", quotes(candles(nil)).GetCandles, 200},
+		{"candles bad interval", "GET", "/v1/quotes/candles/{base}/{quote}", "/v1/quotes/candles/USD/EUR?interval=2h", "", quotes(nil).GetCandles, 400},
+		{"candles failing", "GET", "/v1/quotes/candles/{base}/{quote}", "/v1/quotes/candles/USD/EUR", "", quotes(candles(failing)).GetCandles, 500},
+		{"export csv", "GET", "/v1/quotes/export", "/v1/quotes/export?pairs=USD/EUR", "", quotes(export(nil)).ExportQuotes, 200},
+		{"export ndjson", "GET", "/v1/quotes/export", "/v1/quotes/export?format=ndjson", "", quotes(export(nil)).ExportQuotes, 200},
+		{"export unsupported", "GET", "/v1/quotes/export", "/v1/quotes/export?pairs=GBP/USD", "", quotes(nil).ExportQuotes, 400},
+		{"export bad format", "GET", "/v1/quotes/export", "/v1/quotes/export?format=xml", "", quotes(nil).ExportQuotes, 400},
+		{"export failing", "GET", "/v1/quotes/export", "/v1/quotes/export", "", quotes(export(failing)).ExportQuotes, 500},
+		{"consistency", "GET", "/v1/quotes/consistency", "/v1/quotes/consistency", "", consistency(lastQuote(done, nil)).GetConsistency, 200},
+		{"consistency failing", "GET", "/v1/quotes/consistency", "/v1/quotes/consistency", "", consistency(lastQuote(model.Quote{}, failing)).GetConsistency, 500},
+		{"keys list", "GET", "/v1/admin/keys", "/v1/admin/keys", "", admin.ListAPIKeys, 200},
+		{"keys list forbidden", "GET", "/v1/admin/keys", "/v1/admin/keys", "", authn.Require(model.ScopeAdmin, admin.ListAPIKeys), 403},
+		{"keys create", "POST", "/v1/admin/keys", "/v1/admin/keys", `{"name":"ci","scopes":["quotes:read"]}`, admin.CreateAPIKey, 200},
+		{"keys create invalid", "POST", "/v1/admin/keys", "/v1/admin/keys", `{"name":"ci","scopes":["root"]}`, admin.CreateAPIKey, 400},
+		{"keys revoke", "DELETE", "/v1/admin/keys/{id}", "/v1/admin/keys/" + revokable.ID, "", admin.RevokeAPIKey, 204},
+		{"keys revoke missing", "DELETE", "/v1/admin/keys/{id}", "/v1/admin/keys/unknown", "", admin.RevokeAPIKey, 404},
+		{"import", "POST", "/v1/admin/quotes/import", "/v1/admin/quotes/import", "pair,timestamp,price\nUSD/EUR,2024-01-01,0.9\nGBP/USD,2024-01-01,1.2\n", admin.ImportQuotes, 200},
+		{"import bad header", "POST", "/v1/admin/quotes/import", "/v1/admin/quotes/import", "pair,price\n", admin.ImportQuotes, 400},
+		{"backfill create", "POST", "/v1/admin/backfills", "/v1/admin/backfills", `{"currency":"USD/EUR","from":"2024-01-01","to":"2024-01-31"}`, admin.CreateBackfill, 200},
+		{"backfill create invalid", "POST", "/v1/admin/backfills", "/v1/admin/backfills", `{"currency":"USD/EUR","from":"2024-01-31","to":"2024-01-01"}`, admin.CreateBackfill, 400},
+		{"backfills list", "GET", "/v1/admin/backfills", "/v1/admin/backfills", "", admin.ListBackfills, 200},
+		{"backfill", "GET", "/v1/admin/backfills/{id}", "/v1/admin/backfills/" + running.ID, "", admin.GetBackfill, 200},
+		{"backfill missing", "GET", "/v1/admin/backfills/{id}", "/v1/admin/backfills/unknown", "", admin.GetBackfill, 404},
+		{"backfill resume", "POST", "/v1/admin/backfills/{id}/resume", "/v1/admin/backfills/" + failed.ID + "/resume", "", admin.ResumeBackfill, 200},
+		{"backfill resume running", "POST", "/v1/admin/backfills/{id}/resume", "/v1/admin/backfills/" + running.ID + "/resume", "", admin.ResumeBackfill, 409},
+		{"backfill resume missing", "POST", "/v1/admin/backfills/{id}/resume", "/v1/admin/backfills/unknown/resume", "", admin.ResumeBackfill, 404},
+		{"suspect list", "GET", "/v1/admin/quotes/suspect", "/v1/admin/quotes/suspect", "", admin.ListSuspectQuotes, 200},
+		{"suspect approve", "POST", "/v1/admin/quotes/{id}/approve", "/v1/admin/quotes/" + suspect[0] + "/approve", "", admin.ApproveQuote, 204},
+		{"suspect approve missing", "POST", "/v1/admin/quotes/{id}/approve", "/v1/admin/quotes/" + suspect[0] + "/approve", "", admin.ApproveQuote, 404},
+		{"suspect reject", "POST", "/v1/admin/quotes/{id}/reject", "/v1/admin/quotes/" + suspect[1] + "/reject", "", admin.RejectQuote, 204},
+		{"suspect reject missing", "POST", "/v1/admin/quotes/{id}/reject", "/v1/admin/quotes/unknown/reject", "", admin.RejectQuote, 404},
+		{"openapi", "GET", "/openapi.json", "/openapi.json", "", OpenAPI, 200},
+	}
+	covered := map[string]bool{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if tt.status == http.StatusMethodNotAllowed {
+				method = http.MethodPatch
+			}
+			req := httptest.NewRequest(method, tt.url, bytes.NewReader([]byte(tt.body)))
+			if tt.status == http.StatusForbidden {
+				req = req.WithContext(auth.WithKey(req.Context(), readKey))
+			}
+			mux := http.NewServeMux()
+			mux.HandleFunc(tt.method+" "+tt.path, tt.handler)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body)
+			}
+			if err := s.checkResponse(tt.method, tt.path, rec); err != nil {
+				t.Error(err)
+			}
+		})
+		covered[tt.method+" "+tt.path] = true
+	}
+
+	// Probes and metrics are served by other packages and checked in their own tests.
+	external := map[string]bool{"GET /healthz": true, "GET /readyz": true, "GET /metrics": true}
+	for path, item := range s["paths"].(map[string]any) {
+		for method := range item.(map[string]any) {
+			op := strings.ToUpper(method) + " " + path
+			if !covered[op] && !external[op] {
+				t.Errorf("%s is documented but not exercised by the contract test", op)
+			}
+		}
+	}
+}
+
+// TestOpenAPISchemas keeps the component schemas in line with the Go types
+// the handlers encode and decode.
+func TestOpenAPISchemas(t *testing.T) {
+	s := loadSpec(t)
+	types := map[string]any{
+		"UpdateRequest":         UpdateRequest{},
+		"UpdateResponse":        UpdateResponse{},
+		"QuoteResponse":         QuoteResponse{},
+		"CandleResponse":        CandleResponse{},
+		"CandlesResponse":       CandlesResponse{},
+		"CreateAPIKeyRequest":   CreateAPIKeyRequest{},
+		"APIKeyResponse":        APIKeyResponse{},
+		"ImportResponse":        ImportResponse{},
+		"ImportLineError":       ImportLineError{},
+		"CreateBackfillRequest": CreateBackfillRequest{},
+		"BackfillResponse":      BackfillResponse{},
+		"SuspectQuoteResponse":  SuspectQuoteResponse{},
+		"ErrorResponse":         ErrorResponse{},
+	}
+	for name, v := range types {
+		schema := s.schema(name)
+		props := schema["properties"].(map[string]any)
+		var required []string
+		for _, r := range schema["required"].([]any) {
+			required = append(required, r.(string))
+		}
+		typ := reflect.TypeOf(v)
+		for i := 0; i < typ.NumField(); i++ {
+			tag, opts, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			if _, ok := props[tag]; !ok {
+				t.Errorf("%s: field %q is not documented", name, tag)
+			}
+			isRequired := !strings.Contains(opts, "omitempty")
+			if isRequired != slices.Contains(required, tag) {
+				t.Errorf("%s: field %q required=%v in Go, not in openapi.json", name, tag, isRequired)
+			}
+		}
+		if len(props) != typ.NumField() {
+			t.Errorf("%s: %d documented properties, %d fields", name, len(props), typ.NumField())
+		}
+	}
+
+	messages := s.schema("ServiceError")["enum"].([]any)
+	for _, msg := range []ServiceError{QuoteNotFound, ServerInternalError, QuoteOnPending, UnsupportedCurrencyPair, InvalidRequestParams, APIKeyNotFound, IdempotencyKeyReused, BackfillNotFound, BackfillNotFailed, SuspectQuoteNotFound} {
+		if !slices.Contains(messages, any(string(msg))) {
+			t.Errorf("ServiceError %q is not documented", msg)
+		}
+	}
+}
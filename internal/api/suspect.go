@@ -0,0 +1,80 @@
+package api
+
+import (
+	"FinQuotesService/internal/auth"
+	"FinQuotesService/internal/model"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type SuspectQuoteResponse struct {
+	ID        string    `json:"id"`
+	Currency  string    `json:"currency"`
+	Price     float64   `json:"price"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// PreviousPrice is the latest done quote of the pair, which is served
+	// instead, and Change the relative move from it.
+	PreviousPrice *float64 `json:"previous_price,omitempty"`
+	Change        *float64 `json:"change,omitempty"`
+}
+
+// ListSuspectQuotes returns the quotes held for review, oldest first.
+func (h *AdminHandler) ListSuspectQuotes(w http.ResponseWriter, r *http.Request) {
+	quotes, err := h.Srv.ListSuspectQuotes(r.Context())
+	if err != nil {
+		serverInternalError(w)
+		return
+	}
+	previous := make(map[string]*float64)
+	resp := make([]SuspectQuoteResponse, 0, len(quotes))
+	for _, q := range quotes {
+		if q.Price == nil || q.UpdatedAt == nil {
+			continue
+		}
+		prev, ok := previous[q.Currency]
+		if !ok {
+			last, err := h.Srv.GetLastQuote(r.Context(), q.Currency, model.StatusDone)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				serverInternalError(w)
+				return
+			}
+			prev = last.Price
+			previous[q.Currency] = prev
+		}
+		item := SuspectQuoteResponse{ID: q.ID, Currency: q.Currency, Price: *q.Price, UpdatedAt: *q.UpdatedAt, PreviousPrice: prev}
+		if prev != nil && *prev != 0 {
+			change := *q.Price / *prev - 1
+			item.Change = &change
+		}
+		resp = append(resp, item)
+	}
+	successResponse(w, resp)
+}
+
+// ApproveQuote makes a suspect quote done, so it is served if no newer quote
+// of its pair completed since.
+func (h *AdminHandler) ApproveQuote(w http.ResponseWriter, r *http.Request) {
+	h.reviewQuote(w, r, true)
+}
+
+// RejectQuote discards a suspect quote for good.
+func (h *AdminHandler) RejectQuote(w http.ResponseWriter, r *http.Request) {
+	h.reviewQuote(w, r, false)
+}
+
+func (h *AdminHandler) reviewQuote(w http.ResponseWriter, r *http.Request, approve bool) {
+	id := r.PathValue("id")
+	if err := h.Srv.ReviewQuote(r.Context(), id, approve); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			errorResponse(w, http.StatusNotFound, SuspectQuoteNotFound)
+		} else {
+			serverInternalError(w)
+		}
+		return
+	}
+	slog.InfoContext(r.Context(), "suspect quote reviewed", "component", "admin", "quote_id", id, "approved", approve, "reviewed_by", auth.KeyID(r.Context()))
+	w.WriteHeader(http.StatusNoContent)
+}
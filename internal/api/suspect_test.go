@@ -0,0 +1,102 @@
+package api
+
+import (
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository/memory"
+	"FinQuotesService/internal/service"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newSuspectQuote(t *testing.T, srv *service.QuoteService, currency string, price float64) string {
+	t.Helper()
+	id, err := srv.InsertPendingQuote(context.Background(), currency, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := srv.UpdateQuote(context.Background(), id, price, model.StatusSuspect); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return id
+}
+
+func TestAdminHandler_SuspectQuotes(t *testing.T) {
+	srv := service.NewQuoteService(memory.NewQuoteRepository())
+	done, _ := srv.InsertPendingQuote(context.Background(), "EUR/USD", "")
+	if err := srv.UpdateQuote(context.Background(), done, 1.0, model.StatusDone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	approved := newSuspectQuote(t, srv, "EUR/USD", 1.2)
+	rejected := newSuspectQuote(t, srv, "USD/EUR", 0.5)
+	h := &AdminHandler{Srv: srv}
+
+	w := httptest.NewRecorder()
+	h.ListSuspectQuotes(w, httptest.NewRequest(http.MethodGet, "/v1/admin/quotes/suspect", nil))
+	var listed []SuspectQuoteResponse
+	if err := json.NewDecoder(w.Body).Decode(&listed); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(listed) != 2 || listed[0].ID != approved || listed[0].Price != 1.2 || *listed[0].PreviousPrice != 1.0 || *listed[0].Change < 0.199 || *listed[0].Change > 0.201 {
+		t.Fatalf("unexpected list %+v", listed)
+	}
+	if listed[1].ID != rejected || listed[1].PreviousPrice != nil || listed[1].Change != nil {
+		t.Errorf("expected no previous price for a pair without done quotes, got %+v", listed[1])
+	}
+
+	review := func(handler http.HandlerFunc, id string) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/admin/quotes/"+id+"/approve", nil)
+		req.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w.Code
+	}
+	if code := review(h.ApproveQuote, approved); code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", code)
+	}
+	if last, _ := srv.GetLastQuote(context.Background(), "EUR/USD", model.StatusDone); last.ID != approved {
+		t.Errorf("expected the approved quote to be served, got %+v", last)
+	}
+	if code := review(h.RejectQuote, rejected); code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", code)
+	}
+	if code := review(h.ApproveQuote, rejected); code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a reviewed quote, got %d", code)
+	}
+	if code := review(h.RejectQuote, done); code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a done quote, got %d", code)
+	}
+}
+
+func TestGetQuoteByRequestId_Reviewed(t *testing.T) {
+	srv := service.NewQuoteService(memory.NewQuoteRepository())
+	id := newSuspectQuote(t, srv, "EUR/USD", 1.2)
+	h := &Handler{Srv: srv}
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/quotes/update/"+id, nil)
+		req.SetPathValue("request_id", id)
+		w := httptest.NewRecorder()
+		h.GetQuoteByRequestId(w, req)
+		return w
+	}
+
+	if w := get(); w.Code != http.StatusTooEarly {
+		t.Fatalf("expected 425 for a suspect quote, got %d", w.Code)
+	}
+	if err := srv.ReviewQuote(context.Background(), id, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w := get()
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var qr QuoteResponse
+	if err := json.NewDecoder(w.Body).Decode(&qr); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if qr.Currency != "EUR/USD" || qr.Price != nil {
+		t.Errorf("expected the rejected rate to be left out, got %+v", qr)
+	}
+}
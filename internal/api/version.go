@@ -0,0 +1,23 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// V1 prefixes every route of the current API version.
+const V1 = "/v1"
+
+// legacyDeprecatedAt is when the unversioned paths were superseded by /v1.
+var legacyDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// Deprecated marks a response served on an unversioned legacy path with the
+// Deprecation header (RFC 9745) and links to the /v1 successor.
+func Deprecated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "@"+strconv.FormatInt(legacyDeprecatedAt.Unix(), 10))
+		w.Header().Set("Link", "<"+V1+r.URL.EscapedPath()+`>; rel="successor-version"`)
+		next(w, r)
+	}
+}
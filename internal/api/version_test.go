@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeprecated(t *testing.T) {
+	called := false
+	h := Deprecated(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/quotes/last/USD/EUR", nil))
+
+	if !called {
+		t.Fatal("wrapped handler was not called")
+	}
+	if got := w.Header().Get("Deprecation"); got != "@1792368000" {
+		t.Errorf("unexpected Deprecation header %q", got)
+	}
+	if got, want := w.Header().Get("Link"), `</v1/quotes/last/USD/EUR>; rel="successor-version"`; got != want {
+		t.Errorf("expected Link %q, got %q", want, got)
+	}
+}
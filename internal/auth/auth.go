@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"FinQuotesService/internal/metrics"
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// APIKeyHeader is accepted as an alternative to "Authorization: Bearer".
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyQueryParam carries the key on WebSocket handshakes only.
+const APIKeyQueryParam = "api_key"
+
+const keyPrefix = "fq_"
+
+type contextKey struct{}
+
+// WithKey stores the authenticated key in ctx.
+func WithKey(ctx context.Context, key model.APIKey) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext returns the key that authenticated the request, if any.
+func FromContext(ctx context.Context) (model.APIKey, bool) {
+	key, ok := ctx.Value(contextKey{}).(model.APIKey)
+	return key, ok
+}
+
+// KeyID returns the ID of the authenticated key, or "" for anonymous requests.
+func KeyID(ctx context.Context) string {
+	key, _ := FromContext(ctx)
+	return key.ID
+}
+
+// GenerateKey returns a new random secret. Keys carry 256 bits of entropy, so
+// a plain SHA-256 is enough to store them safely.
+func GenerateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateKey generates and stores a key. The returned secret is not kept
+// anywhere and must be handed to the client right away.
+func CreateKey(repo repository.APIKeyRepository, name string, scopes []model.Scope) (string, model.APIKey, error) {
+	secret, err := GenerateKey()
+	if err != nil {
+		return "", model.APIKey{}, err
+	}
+	key, err := repo.CreateAPIKey(name, HashKey(secret), scopes)
+	if err != nil {
+		return "", model.APIKey{}, err
+	}
+	return secret, key, nil
+}
+
+// Bootstrap makes sure secret is a valid admin key, so that a fresh
+// deployment can create the rest of its keys through the admin API.
+func Bootstrap(repo repository.APIKeyRepository, secret string) error {
+	_, err := repo.GetAPIKeyByHash(HashKey(secret))
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	_, err = repo.CreateAPIKey("bootstrap", HashKey(secret), []model.Scope{model.ScopeAdmin})
+	return err
+}
+
+// Authenticator resolves API keys in front of the router and enforces
+// per-route scopes. With Disabled set every request is let through.
+type Authenticator struct {
+	Keys     repository.APIKeyRepository
+	Disabled bool
+}
+
+func NewAuthenticator(keys repository.APIKeyRepository) *Authenticator {
+	return &Authenticator{Keys: keys}
+}
+
+var (
+	ErrInvalidKey = errors.New("invalid API key")
+	ErrRevokedKey = errors.New("revoked API key")
+)
+
+// Lookup resolves a presented secret to its key. Keys that must be rejected
+// yield ErrInvalidKey or ErrRevokedKey; other errors come from the store.
+func (a *Authenticator) Lookup(secret string) (model.APIKey, error) {
+	key, err := a.Keys.GetAPIKeyByHash(HashKey(secret))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.APIKey{}, ErrInvalidKey
+	}
+	if err != nil {
+		return model.APIKey{}, err
+	}
+	if key.Revoked() {
+		return model.APIKey{}, ErrRevokedKey
+	}
+	return key, nil
+}
+
+// Middleware rejects requests carrying an unknown or revoked key and stores
+// a valid key in the request context. Requests without a key pass through
+// anonymously; routes that need one are wrapped with Require.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	if a.Disabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := keyFromRequest(r)
+		if secret == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key, err := a.Lookup(secret)
+		switch {
+		case errors.Is(err, ErrInvalidKey):
+			unauthorized(w, "invalid_key", "Invalid API key")
+			return
+		case errors.Is(err, ErrRevokedKey):
+			unauthorized(w, "revoked_key", "Invalid API key")
+			return
+		case err != nil:
+			slog.ErrorContext(r.Context(), "api key lookup failed", "component", "auth", "error", err)
+			writeError(w, http.StatusInternalServerError, "Server internal error")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithKey(r.Context(), key)))
+	})
+}
+
+// Require only calls next for requests authenticated with a key that has
+// scope.
+func (a *Authenticator) Require(scope model.Scope, next http.HandlerFunc) http.HandlerFunc {
+	if a.Disabled {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, ok := FromContext(r.Context())
+		if !ok {
+			unauthorized(w, "missing_key", "API key required")
+			return
+		}
+		if !key.HasScope(scope) {
+			metrics.AuthFailures.WithLabelValues("insufficient_scope").Inc()
+			writeError(w, http.StatusForbidden, "API key lacks scope "+string(scope))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Allowed reports whether the request behind ctx may use scope, for checks
+// made inside a handler rather than by Require.
+func (a *Authenticator) Allowed(ctx context.Context, scope model.Scope) bool {
+	if a.Disabled {
+		return true
+	}
+	key, ok := FromContext(ctx)
+	return ok && key.HasScope(scope)
+}
+
+func keyFromRequest(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	// Browsers cannot set headers on a WebSocket handshake, so it may carry
+	// the key in the query string instead.
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return r.URL.Query().Get(APIKeyQueryParam)
+	}
+	return ""
+}
+
+func unauthorized(w http.ResponseWriter, reason, message string) {
+	metrics.AuthFailures.WithLabelValues(reason).Inc()
+	w.Header().Set("WWW-Authenticate", `Bearer realm="FinQuotesService"`)
+	writeError(w, http.StatusUnauthorized, message)
+}
+
+// writeError uses the same body shape as the API handlers.
+func writeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(map[string]string{"error_message": message})
+	if err != nil {
+		return
+	}
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository/memory"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestAuthenticator(t *testing.T) (*Authenticator, map[string]string) {
+	t.Helper()
+	repo := memory.NewAPIKeyRepository()
+	secrets := make(map[string]string)
+	for name, scopes := range map[string][]model.Scope{
+		"reader":  {model.ScopeQuotesRead},
+		"updater": {model.ScopeQuotesRead, model.ScopeQuotesUpdate},
+		"admin":   {model.ScopeAdmin},
+		"revoked": {model.ScopeAdmin},
+	} {
+		secret, key, err := CreateKey(repo, name, scopes)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if name == "revoked" {
+			if err := repo.RevokeAPIKey(key.ID); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		secrets[name] = secret
+	}
+	return NewAuthenticator(repo), secrets
+}
+
+func TestAuthenticator(t *testing.T) {
+	a, secrets := newTestAuthenticator(t)
+	var gotKey model.APIKey
+	handler := a.Middleware(a.Require(model.ScopeQuotesUpdate, func(w http.ResponseWriter, r *http.Request) {
+		gotKey, _ = FromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"no key", "", "", http.StatusUnauthorized},
+		{"unknown key", "Authorization", "Bearer fq_unknown", http.StatusUnauthorized},
+		{"revoked key", "Authorization", "Bearer " + secrets["revoked"], http.StatusUnauthorized},
+		{"missing scope", "Authorization", "Bearer " + secrets["reader"], http.StatusForbidden},
+		{"bearer", "Authorization", "Bearer " + secrets["updater"], http.StatusOK},
+		{"api key header", APIKeyHeader, secrets["updater"], http.StatusOK},
+		{"admin implies all scopes", APIKeyHeader, secrets["admin"], http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotKey = model.APIKey{}
+			req := httptest.NewRequest(http.MethodPost, "/quotes/update", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, w.Code)
+			}
+			if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected WWW-Authenticate header")
+			}
+			if tt.want == http.StatusOK && gotKey.ID == "" {
+				t.Error("expected key in request context")
+			}
+			if tt.want != http.StatusOK && !strings.Contains(w.Body.String(), "error_message") {
+				t.Errorf("unexpected body: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthenticator_Disabled(t *testing.T) {
+	a, _ := newTestAuthenticator(t)
+	a.Disabled = true
+	handler := a.Middleware(a.Require(model.ScopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/admin/keys", nil)
+	req.Header.Set("Authorization", "Bearer fq_unknown")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	repo := memory.NewAPIKeyRepository()
+	for i := 0; i < 2; i++ {
+		if err := Bootstrap(repo, "fq_bootstrap"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	keys, err := repo.ListAPIKeys()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(keys) != 1 || !keys[0].HasScope(model.ScopeAdmin) || keys[0].Hash != HashKey("fq_bootstrap") {
+		t.Errorf("unexpected keys: %+v", keys)
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	a, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := GenerateKey()
+	if a == b || !strings.HasPrefix(a, keyPrefix) || len(a) < 40 {
+		t.Errorf("unexpected keys %q and %q", a, b)
+	}
+	if HashKey(a) == a || len(HashKey(a)) != 64 {
+		t.Errorf("unexpected hash %q", HashKey(a))
+	}
+}
+
+func TestAuthenticator_QueryKeyOnWebSocketOnly(t *testing.T) {
+	a, secrets := newTestAuthenticator(t)
+	handler := a.Middleware(a.Require(model.ScopeQuotesRead, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/ws?api_key="+secrets["reader"], nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected query key to be ignored on plain requests, got %d", w.Code)
+	}
+
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected query key to authenticate the handshake, got %d", w.Code)
+	}
+}
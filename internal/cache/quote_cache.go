@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"FinQuotesService/internal/model"
+	"sync"
+	"time"
+)
+
+// QuoteCache keeps the latest done quote per currency pair for a limited time.
+type QuoteCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[string]entry
+	now     func() time.Time
+}
+
+type entry struct {
+	quote     model.Quote
+	expiresAt time.Time
+}
+
+func NewQuoteCache(ttl time.Duration) *QuoteCache {
+	return &QuoteCache{
+		ttl:     ttl,
+		entries: make(map[string]entry),
+		now:     time.Now,
+	}
+}
+
+func (c *QuoteCache) Get(currency string) (model.Quote, bool) {
+	c.mu.RLock()
+	e, ok := c.entries[currency]
+	c.mu.RUnlock()
+	if !ok || !c.now().Before(e.expiresAt) {
+		return model.Quote{}, false
+	}
+	return e.quote, true
+}
+
+// Set stores q unless a newer quote for the same pair is already cached.
+func (c *QuoteCache) Set(q model.Quote) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if e, ok := c.entries[q.Currency]; ok && now.Before(e.expiresAt) && isNewer(e.quote, q) {
+		return
+	}
+	c.entries[q.Currency] = entry{quote: q, expiresAt: now.Add(c.ttl)}
+}
+
+func (c *QuoteCache) Invalidate(currency string) {
+	c.mu.Lock()
+	delete(c.entries, currency)
+	c.mu.Unlock()
+}
+
+func (c *QuoteCache) InvalidateAll() {
+	c.mu.Lock()
+	c.entries = make(map[string]entry)
+	c.mu.Unlock()
+}
+
+func isNewer(a, b model.Quote) bool {
+	return a.UpdatedAt != nil && b.UpdatedAt != nil && a.UpdatedAt.After(*b.UpdatedAt)
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"FinQuotesService/internal/model"
+	"testing"
+	"time"
+)
+
+func quoteAt(id string, at time.Time) model.Quote {
+	price := 1.0
+	return model.Quote{ID: id, Currency: "USD/EUR", Price: &price, UpdatedAt: &at, Status: model.StatusDone}
+}
+
+func TestQuoteCache_Expiry(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewQuoteCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set(quoteAt("q1", now))
+	if q, ok := c.Get("USD/EUR"); !ok || q.ID != "q1" {
+		t.Fatalf("expected cached q1, got %v %v", q.ID, ok)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("USD/EUR"); ok {
+		t.Fatal("expected entry to expire")
+	}
+}
+
+func TestQuoteCache_KeepsNewest(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewQuoteCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set(quoteAt("newer", now))
+	c.Set(quoteAt("older", now.Add(-time.Second)))
+	if q, _ := c.Get("USD/EUR"); q.ID != "newer" {
+		t.Fatalf("expected newer quote to stay cached, got %s", q.ID)
+	}
+}
+
+func TestQuoteCache_Invalidate(t *testing.T) {
+	c := NewQuoteCache(time.Minute)
+	c.Set(quoteAt("q1", time.Now()))
+
+	c.Invalidate("USD/EUR")
+	if _, ok := c.Get("USD/EUR"); ok {
+		t.Fatal("expected entry to be invalidated")
+	}
+
+	c.Set(quoteAt("q2", time.Now()))
+	c.InvalidateAll()
+	if _, ok := c.Get("USD/EUR"); ok {
+		t.Fatal("expected all entries to be invalidated")
+	}
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, rest, printConfig, err := Load(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Server.Addr != ":8080" || cfg.Worker.Count != 10 || cfg.Worker.ProcessingDelay.Duration != 30*time.Second {
+		t.Errorf("unexpected defaults: %+v", cfg)
+	}
+	if len(rest) != 0 || printConfig {
+		t.Errorf("unexpected rest %v, printConfig %v", rest, printConfig)
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, `{"server":{"addr":":9000"},"worker":{"count":3,"processing_delay":"1s"},"cache_ttl":"1m"}`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("WORKERS_COUNT", "4")
+	t.Setenv("DB_DSN", "memory://")
+
+	cfg, rest, _, err := Load([]string{"-workers", "5", "migrate", "status"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Server.Addr != ":9000" {
+		t.Errorf("expected addr from file, got %q", cfg.Server.Addr)
+	}
+	if cfg.CacheTTL.Duration != time.Minute || cfg.Worker.ProcessingDelay.Duration != time.Second {
+		t.Errorf("expected durations from file, got %v and %v", cfg.CacheTTL, cfg.Worker.ProcessingDelay)
+	}
+	if cfg.Database.DSN != "memory://" {
+		t.Errorf("expected DSN from env, got %q", cfg.Database.DSN)
+	}
+	if cfg.Worker.Count != 5 {
+		t.Errorf("expected flag to win, got %d workers", cfg.Worker.Count)
+	}
+	if strings.Join(rest, " ") != "migrate status" {
+		t.Errorf("unexpected rest args: %v", rest)
+	}
+}
+
+func TestLoad_UnknownFileField(t *testing.T) {
+	path := writeFile(t, `{"workers":3}`)
+	if _, _, _, err := Load([]string{"-config", path}); err == nil {
+		t.Error("expected error for unknown field, got nil")
+	}
+}
+
+func TestLoad_BadEnvValue(t *testing.T) {
+	t.Setenv("PROCESSING_DELAY", "soon")
+	_, _, _, err := Load(nil)
+	if err == nil || !strings.Contains(err.Error(), "PROCESSING_DELAY") {
+		t.Errorf("expected PROCESSING_DELAY error, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Worker.Count = 0
+	cfg.Worker.QueueSaturation = 1.5
+	cfg.Worker.QueueSize = 0
+	cfg.Worker.ClaimTTL = Duration{}
+	cfg.TracingExporter = "jaeger"
+	cfg.LogLevel = "loud"
+	cfg.Inverse.Derive = []string{"USD/EUR", "EUR/USD"}
+	cfg.Anomaly.Pairs = map[string]AnomalyRule{"EUR/USD": {MaxChange: -0.1}}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	for _, field := range []string{"worker.count", "worker.queue_saturation", "worker.queue_size", "worker.claim_ttl", "tracing_exporter", "log_level", "inverse.derive", "anomaly.pairs"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected %s in %q", field, err)
+		}
+	}
+	if err := Default().Validate(); err != nil {
+		t.Errorf("defaults should be valid: %v", err)
+	}
+}
+
+func TestPrint_RedactsPassword(t *testing.T) {
+	cfg := Default()
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "pass@") {
+		t.Errorf("password leaked: %s", out)
+	}
+	if !strings.Contains(out, `"processing_delay": "30s"`) {
+		t.Errorf("expected durations as strings: %s", out)
+	}
+	if cfg.Database.DSN != Default().Database.DSN {
+		t.Error("Print must not modify the config")
+	}
+}
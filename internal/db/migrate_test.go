@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLoadMigrations_Ordered(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_add_source.up.sql":      {Data: []byte("ALTER TABLE quotes ADD COLUMN source TEXT;")},
+		"m/0002_add_source.down.sql":    {Data: []byte("ALTER TABLE quotes DROP COLUMN source;")},
+		"m/0001_create_quotes.up.sql":   {Data: []byte("CREATE TABLE quotes ();")},
+		"m/0001_create_quotes.down.sql": {Data: []byte("DROP TABLE quotes;")},
+		"m/README.md":                   {Data: []byte("ignored")},
+	}
+
+	migrations, err := LoadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "create_quotes" {
+		t.Errorf("unexpected first migration: %+v", migrations[0])
+	}
+	if migrations[1].Version != 2 || migrations[1].Down != "ALTER TABLE quotes DROP COLUMN source;" {
+		t.Errorf("unexpected second migration: %+v", migrations[1])
+	}
+}
+
+func TestLoadMigrations_MissingUp(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0001_create_quotes.down.sql": {Data: []byte("DROP TABLE quotes;")},
+	}
+
+	if _, err := LoadMigrations(fsys, "m"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	versions := make(map[string][]int64)
+	for _, driver := range []string{DriverPostgres, DriverSQLite} {
+		migrations, err := LoadMigrations(migrationsFS, "migrations/"+driver)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", driver, err)
+		}
+		for _, m := range migrations {
+			if m.Down == "" {
+				t.Errorf("%s: migration %d_%s has no down script", driver, m.Version, m.Name)
+			}
+			versions[driver] = append(versions[driver], m.Version)
+		}
+	}
+	if fmt.Sprint(versions[DriverPostgres]) != fmt.Sprint(versions[DriverSQLite]) {
+		t.Errorf("postgres and sqlite migrations diverge: %v vs %v", versions[DriverPostgres], versions[DriverSQLite])
+	}
+}
+
+func TestMigrator_UpAppliesPending(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	m := &Migrator{db: conn, driver: DriverPostgres, migrations: []Migration{
+		{Version: 1, Name: "create_quotes", Up: "CREATE TABLE quotes ()"},
+		{Version: 2, Name: "add_source", Up: "ALTER TABLE quotes ADD COLUMN source TEXT"},
+	}}
+
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT version, applied_at FROM schema_migrations`).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(`ALTER TABLE quotes ADD COLUMN source TEXT`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_migrations \(version, name\) VALUES \(\$1, \$2\)`).
+		WithArgs(int64(2), "add_source").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrator_SQLiteUpDown(t *testing.T) {
+	conn, err := sql.Open(DriverSQLite, "file::memory:")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+	conn.SetMaxOpenConns(1)
+
+	m, err := NewMigrator(conn, DriverSQLite)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, st := range statuses {
+		if st.AppliedAt == nil {
+			t.Errorf("migration %d_%s is not applied", st.Version, st.Name)
+		}
+	}
+	if _, err := conn.Exec(`SELECT count(*) FROM quotes`); err != nil {
+		t.Fatalf("quotes table is missing: %v", err)
+	}
+
+	if err := m.Down(ctx, len(statuses)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := conn.Exec(`SELECT count(*) FROM quotes`); err == nil {
+		t.Fatal("expected quotes table to be dropped")
+	}
+}
+
+func TestMigrator_Pending(t *testing.T) {
+	conn, err := sql.Open(DriverSQLite, "file::memory:")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+	conn.SetMaxOpenConns(1)
+
+	m, err := NewMigrator(conn, DriverSQLite)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := m.Pending(ctx); err == nil {
+		t.Fatal("expected error before schema_migrations exists")
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pending != 0 {
+		t.Errorf("expected no pending migrations, got %d", pending)
+	}
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMP
+);
+
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS requested_by TEXT REFERENCES api_keys(id);
@@ -0,0 +1,3 @@
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS source TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_currency_historical ON quotes(currency, updated_at) WHERE source IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE quotes DROP COLUMN IF EXISTS claimed_until;
+ALTER TABLE quotes DROP COLUMN IF EXISTS claimed_by;
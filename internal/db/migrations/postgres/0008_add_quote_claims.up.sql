@@ -0,0 +1,2 @@
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS claimed_by TEXT;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMP;
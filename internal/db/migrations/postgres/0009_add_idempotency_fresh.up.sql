@@ -0,0 +1 @@
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS fresh BOOLEAN NOT NULL DEFAULT false;
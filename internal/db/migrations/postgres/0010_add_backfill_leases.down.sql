@@ -0,0 +1,2 @@
+ALTER TABLE backfills DROP COLUMN IF EXISTS lease_until;
+ALTER TABLE backfills DROP COLUMN IF EXISTS owner;
@@ -0,0 +1,2 @@
+ALTER TABLE backfills ADD COLUMN IF NOT EXISTS owner TEXT;
+ALTER TABLE backfills ADD COLUMN IF NOT EXISTS lease_until TIMESTAMP;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_quotes_currency_status;
+DROP INDEX IF EXISTS unique_currency_pending;
+DROP TABLE IF EXISTS quotes;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS quotes (
+    id TEXT PRIMARY KEY,
+    currency TEXT NOT NULL,
+    price REAL,
+    updated_at TIMESTAMP,
+    status TEXT NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_currency_pending ON quotes(currency) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_quotes_currency_status ON quotes(currency, status, updated_at DESC);
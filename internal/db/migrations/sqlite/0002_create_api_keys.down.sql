@@ -0,0 +1,2 @@
+ALTER TABLE quotes DROP COLUMN requested_by;
+DROP TABLE IF EXISTS api_keys;
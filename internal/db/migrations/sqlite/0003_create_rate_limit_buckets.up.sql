@@ -0,0 +1,7 @@
+-- SQLite deployments run a single instance and keep buckets in memory; the
+-- table only keeps the schema in line with Postgres.
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens REAL NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
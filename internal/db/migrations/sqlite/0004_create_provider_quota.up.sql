@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS provider_quota (
+    provider TEXT NOT NULL,
+    day TEXT NOT NULL,
+    used INTEGER NOT NULL,
+    PRIMARY KEY (provider, day)
+);
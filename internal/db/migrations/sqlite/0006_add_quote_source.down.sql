@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS unique_currency_historical;
+ALTER TABLE quotes DROP COLUMN source;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS backfills (
+    id TEXT PRIMARY KEY,
+    currency TEXT NOT NULL,
+    from_day TIMESTAMP NOT NULL,
+    to_day TIMESTAMP NOT NULL,
+    next_day TIMESTAMP NOT NULL,
+    status TEXT NOT NULL,
+    inserted INTEGER NOT NULL DEFAULT 0,
+    duplicates INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_backfills_status ON backfills(status, created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE quotes DROP COLUMN claimed_until;
+ALTER TABLE quotes DROP COLUMN claimed_by;
@@ -0,0 +1,2 @@
+ALTER TABLE quotes ADD COLUMN claimed_by TEXT;
+ALTER TABLE quotes ADD COLUMN claimed_until TIMESTAMP;
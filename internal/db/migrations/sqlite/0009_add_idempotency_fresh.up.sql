@@ -0,0 +1 @@
+ALTER TABLE idempotency_keys ADD COLUMN fresh BOOLEAN NOT NULL DEFAULT 0;
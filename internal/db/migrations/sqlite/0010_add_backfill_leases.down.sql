@@ -0,0 +1,2 @@
+ALTER TABLE backfills DROP COLUMN lease_until;
+ALTER TABLE backfills DROP COLUMN owner;
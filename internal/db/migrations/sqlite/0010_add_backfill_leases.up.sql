@@ -0,0 +1,2 @@
+ALTER TABLE backfills ADD COLUMN owner TEXT;
+ALTER TABLE backfills ADD COLUMN lease_until TIMESTAMP;
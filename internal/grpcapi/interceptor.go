@@ -0,0 +1,189 @@
+package grpcapi
+
+import (
+	"FinQuotesService/internal/api"
+	"FinQuotesService/internal/auth"
+	"FinQuotesService/internal/grpcapi/quotesv1"
+	"FinQuotesService/internal/logging"
+	"FinQuotesService/internal/metrics"
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/ratelimit"
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// methodScopes lists the scope each RPC requires. Methods not listed, such
+// as health checks and reflection, are public.
+var methodScopes = map[string]model.Scope{
+	quotesv1.QuoteService_StartUpdate_FullMethodName:      model.ScopeQuotesUpdate,
+	quotesv1.QuoteService_BatchStartUpdate_FullMethodName: model.ScopeQuotesUpdate,
+	quotesv1.QuoteService_GetUpdate_FullMethodName:        model.ScopeQuotesRead,
+	quotesv1.QuoteService_GetLast_FullMethodName:          model.ScopeQuotesRead,
+	quotesv1.QuoteService_StreamQuotes_FullMethodName:     model.ScopeQuotesRead,
+}
+
+// methodRoutes lists the rate limit route each RPC counts against: that of
+// the HTTP route doing the same, so both APIs share a client's buckets.
+var methodRoutes = map[string]string{
+	quotesv1.QuoteService_StartUpdate_FullMethodName:      "/quotes/update",
+	quotesv1.QuoteService_BatchStartUpdate_FullMethodName: "/quotes/update",
+	quotesv1.QuoteService_GetUpdate_FullMethodName:        "/quotes/update/{request_id}",
+	quotesv1.QuoteService_GetLast_FullMethodName:          "/quotes/last/{base}/{quote}",
+	quotesv1.QuoteService_StreamQuotes_FullMethodName:     "/ws",
+}
+
+// UnaryInterceptor tags calls with a request ID, authenticates them with
+// authn, applies the limits of limiter and logs them like the HTTP logging
+// middleware.
+func UnaryInterceptor(authn *auth.Authenticator, limiter *ratelimit.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx = withRequestID(ctx)
+		ctx, err := authorize(ctx, authn, info.FullMethod)
+		if err == nil {
+			err = rateLimit(ctx, limiter, info.FullMethod, req)
+		}
+		var resp any
+		if err == nil {
+			resp, err = handler(ctx, req)
+		}
+		logCall(ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamInterceptor counts a stream as a single call, like a WebSocket
+// handshake.
+func StreamInterceptor(authn *auth.Authenticator, limiter *ratelimit.Limiter) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx := withRequestID(ss.Context())
+		ctx, err := authorize(ctx, authn, info.FullMethod)
+		if err == nil {
+			err = rateLimit(ctx, limiter, info.FullMethod, nil)
+		}
+		if err == nil {
+			err = handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		}
+		logCall(ctx, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	var sent string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(strings.ToLower(logging.RequestIDHeader)); len(v) > 0 {
+			sent = v[0]
+		}
+	}
+	return logging.WithRequestID(ctx, logging.NewRequestID(sent))
+}
+
+// authorize resolves the API key sent in the authorization or x-api-key
+// metadata and checks it has the scope the method requires.
+func authorize(ctx context.Context, authn *auth.Authenticator, method string) (context.Context, error) {
+	scope, ok := methodScopes[method]
+	if !ok || authn.Disabled {
+		return ctx, nil
+	}
+	secret := keyFromMetadata(ctx)
+	if secret == "" {
+		metrics.AuthFailures.WithLabelValues("missing_key").Inc()
+		return ctx, status.Error(codes.Unauthenticated, "API key required")
+	}
+	key, err := authn.Lookup(secret)
+	switch {
+	case errors.Is(err, auth.ErrInvalidKey):
+		metrics.AuthFailures.WithLabelValues("invalid_key").Inc()
+		return ctx, status.Error(codes.Unauthenticated, "Invalid API key")
+	case errors.Is(err, auth.ErrRevokedKey):
+		metrics.AuthFailures.WithLabelValues("revoked_key").Inc()
+		return ctx, status.Error(codes.Unauthenticated, "Invalid API key")
+	case err != nil:
+		slog.ErrorContext(ctx, "api key lookup failed", "component", "auth", "error", err)
+		return ctx, status.Error(codes.Internal, "Server internal error")
+	}
+	if !key.HasScope(scope) {
+		metrics.AuthFailures.WithLabelValues("insufficient_scope").Inc()
+		return ctx, status.Error(codes.PermissionDenied, "API key lacks scope "+string(scope))
+	}
+	return auth.WithKey(ctx, key), nil
+}
+
+// rateLimit counts the call against the route of method. A batch counts as
+// one update per pair, as many StartUpdate calls would, and passes or is
+// rejected as a whole. Rejected calls get ResourceExhausted with
+// ratelimit-remaining and retry-after (in seconds) headers.
+func rateLimit(ctx context.Context, limiter *ratelimit.Limiter, method string, req any) error {
+	route, ok := methodRoutes[method]
+	if !ok {
+		return nil
+	}
+	calls := 1
+	if batch, ok := req.(*quotesv1.BatchStartUpdateRequest); ok && len(batch.GetCurrencies()) <= maxBatchSize {
+		calls = max(1, len(batch.GetCurrencies()))
+	}
+	var addr string
+	if p, ok := peer.FromContext(ctx); ok {
+		addr = p.Addr.String()
+	}
+	res := limiter.Allow(ctx, route, ratelimit.ClientKey(ctx, addr), calls)
+	if res.Allowed {
+		return nil
+	}
+	retryAfter := max(1, int(math.Ceil(res.RetryAfter.Seconds())))
+	header := metadata.Pairs("ratelimit-remaining", strconv.Itoa(res.Remaining), "retry-after", strconv.Itoa(retryAfter))
+	if err := grpc.SetHeader(ctx, header); err != nil {
+		slog.WarnContext(ctx, "failed to set rate limit headers", "component", "grpc", "error", err)
+	}
+	return status.Error(codes.ResourceExhausted, string(api.TooManyRequests))
+}
+
+func keyFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if v := md.Get("authorization"); len(v) > 0 {
+		if token, ok := strings.CutPrefix(v[0], "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	if v := md.Get(strings.ToLower(auth.APIKeyHeader)); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	slog.InfoContext(ctx, "grpc call",
+		"component", "grpc",
+		"method", method,
+		"code", status.Code(err).String(),
+		"api_key_id", auth.KeyID(ctx),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+}
+
+// contextStream overrides the context of a server stream, since handlers
+// read the authenticated key from it.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,600 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: finquotes/v1/quotes.proto
+
+package quotesv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StartUpdateRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Currency string                 `protobuf:"bytes,1,opt,name=currency,proto3" json:"currency,omitempty"`
+	// A done quote at most max_age seconds old is returned instead of starting
+	// an update, unless force is set.
+	MaxAge        int32 `protobuf:"varint,2,opt,name=max_age,json=maxAge,proto3" json:"max_age,omitempty"`
+	Force         bool  `protobuf:"varint,3,opt,name=force,proto3" json:"force,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartUpdateRequest) Reset() {
+	*x = StartUpdateRequest{}
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartUpdateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartUpdateRequest) ProtoMessage() {}
+
+func (x *StartUpdateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartUpdateRequest.ProtoReflect.Descriptor instead.
+func (*StartUpdateRequest) Descriptor() ([]byte, []int) {
+	return file_finquotes_v1_quotes_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StartUpdateRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *StartUpdateRequest) GetMaxAge() int32 {
+	if x != nil {
+		return x.MaxAge
+	}
+	return 0
+}
+
+func (x *StartUpdateRequest) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
+type StartUpdateResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	RequestId string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// Set when request_id is an existing done quote that satisfied max_age.
+	Fresh         bool `protobuf:"varint,2,opt,name=fresh,proto3" json:"fresh,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartUpdateResponse) Reset() {
+	*x = StartUpdateResponse{}
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartUpdateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartUpdateResponse) ProtoMessage() {}
+
+func (x *StartUpdateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartUpdateResponse.ProtoReflect.Descriptor instead.
+func (*StartUpdateResponse) Descriptor() ([]byte, []int) {
+	return file_finquotes_v1_quotes_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *StartUpdateResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *StartUpdateResponse) GetFresh() bool {
+	if x != nil {
+		return x.Fresh
+	}
+	return false
+}
+
+type GetUpdateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUpdateRequest) Reset() {
+	*x = GetUpdateRequest{}
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUpdateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUpdateRequest) ProtoMessage() {}
+
+func (x *GetUpdateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUpdateRequest.ProtoReflect.Descriptor instead.
+func (*GetUpdateRequest) Descriptor() ([]byte, []int) {
+	return file_finquotes_v1_quotes_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetUpdateRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+type GetLastRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Currency      string                 `protobuf:"bytes,1,opt,name=currency,proto3" json:"currency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLastRequest) Reset() {
+	*x = GetLastRequest{}
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLastRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLastRequest) ProtoMessage() {}
+
+func (x *GetLastRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLastRequest.ProtoReflect.Descriptor instead.
+func (*GetLastRequest) Descriptor() ([]byte, []int) {
+	return file_finquotes_v1_quotes_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetLastRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+type Quote struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Currency string                 `protobuf:"bytes,1,opt,name=currency,proto3" json:"currency,omitempty"`
+	// Unset when the update failed.
+	Price         *float64               `protobuf:"fixed64,2,opt,name=price,proto3,oneof" json:"price,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Quote) Reset() {
+	*x = Quote{}
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Quote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Quote) ProtoMessage() {}
+
+func (x *Quote) ProtoReflect() protoreflect.Message {
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Quote.ProtoReflect.Descriptor instead.
+func (*Quote) Descriptor() ([]byte, []int) {
+	return file_finquotes_v1_quotes_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Quote) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Quote) GetPrice() float64 {
+	if x != nil && x.Price != nil {
+		return *x.Price
+	}
+	return 0
+}
+
+func (x *Quote) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type StreamQuotesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Pairs to follow, every supported pair when empty.
+	Currencies    []string `protobuf:"bytes,1,rep,name=currencies,proto3" json:"currencies,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamQuotesRequest) Reset() {
+	*x = StreamQuotesRequest{}
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamQuotesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamQuotesRequest) ProtoMessage() {}
+
+func (x *StreamQuotesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamQuotesRequest.ProtoReflect.Descriptor instead.
+func (*StreamQuotesRequest) Descriptor() ([]byte, []int) {
+	return file_finquotes_v1_quotes_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StreamQuotesRequest) GetCurrencies() []string {
+	if x != nil {
+		return x.Currencies
+	}
+	return nil
+}
+
+type BatchStartUpdateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Currencies    []string               `protobuf:"bytes,1,rep,name=currencies,proto3" json:"currencies,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchStartUpdateRequest) Reset() {
+	*x = BatchStartUpdateRequest{}
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchStartUpdateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchStartUpdateRequest) ProtoMessage() {}
+
+func (x *BatchStartUpdateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchStartUpdateRequest.ProtoReflect.Descriptor instead.
+func (*BatchStartUpdateRequest) Descriptor() ([]byte, []int) {
+	return file_finquotes_v1_quotes_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *BatchStartUpdateRequest) GetCurrencies() []string {
+	if x != nil {
+		return x.Currencies
+	}
+	return nil
+}
+
+type BatchStartUpdateResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One result per requested pair, in request order.
+	Results       []*BatchStartUpdateResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchStartUpdateResponse) Reset() {
+	*x = BatchStartUpdateResponse{}
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchStartUpdateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchStartUpdateResponse) ProtoMessage() {}
+
+func (x *BatchStartUpdateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchStartUpdateResponse.ProtoReflect.Descriptor instead.
+func (*BatchStartUpdateResponse) Descriptor() ([]byte, []int) {
+	return file_finquotes_v1_quotes_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BatchStartUpdateResponse) GetResults() []*BatchStartUpdateResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type BatchStartUpdateResult struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Currency string                 `protobuf:"bytes,1,opt,name=currency,proto3" json:"currency,omitempty"`
+	// Set when the update was started.
+	RequestId string `protobuf:"bytes,2,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// Set instead of request_id when the pair was rejected; the same messages
+	// as the HTTP API error_message.
+	ErrorMessage  string `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchStartUpdateResult) Reset() {
+	*x = BatchStartUpdateResult{}
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchStartUpdateResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchStartUpdateResult) ProtoMessage() {}
+
+func (x *BatchStartUpdateResult) ProtoReflect() protoreflect.Message {
+	mi := &file_finquotes_v1_quotes_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchStartUpdateResult.ProtoReflect.Descriptor instead.
+func (*BatchStartUpdateResult) Descriptor() ([]byte, []int) {
+	return file_finquotes_v1_quotes_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *BatchStartUpdateResult) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *BatchStartUpdateResult) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *BatchStartUpdateResult) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+var File_finquotes_v1_quotes_proto protoreflect.FileDescriptor
+
+const file_finquotes_v1_quotes_proto_rawDesc = "" +
+	"\n" +
+	"\x19finquotes/v1/quotes.proto\x12\ffinquotes.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"_\n" +
+	"\x12StartUpdateRequest\x12\x1a\n" +
+	"\bcurrency\x18\x01 \x01(\tR\bcurrency\x12\x17\n" +
+	"\amax_age\x18\x02 \x01(\x05R\x06maxAge\x12\x14\n" +
+	"\x05force\x18\x03 \x01(\bR\x05force\"J\n" +
+	"\x13StartUpdateResponse\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x14\n" +
+	"\x05fresh\x18\x02 \x01(\bR\x05fresh\"1\n" +
+	"\x10GetUpdateRequest\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\",\n" +
+	"\x0eGetLastRequest\x12\x1a\n" +
+	"\bcurrency\x18\x01 \x01(\tR\bcurrency\"\x83\x01\n" +
+	"\x05Quote\x12\x1a\n" +
+	"\bcurrency\x18\x01 \x01(\tR\bcurrency\x12\x19\n" +
+	"\x05price\x18\x02 \x01(\x01H\x00R\x05price\x88\x01\x01\x129\n" +
+	"\n" +
+	"updated_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAtB\b\n" +
+	"\x06_price\"5\n" +
+	"\x13StreamQuotesRequest\x12\x1e\n" +
+	"\n" +
+	"currencies\x18\x01 \x03(\tR\n" +
+	"currencies\"9\n" +
+	"\x17BatchStartUpdateRequest\x12\x1e\n" +
+	"\n" +
+	"currencies\x18\x01 \x03(\tR\n" +
+	"currencies\"Z\n" +
+	"\x18BatchStartUpdateResponse\x12>\n" +
+	"\aresults\x18\x01 \x03(\v2$.finquotes.v1.BatchStartUpdateResultR\aresults\"x\n" +
+	"\x16BatchStartUpdateResult\x12\x1a\n" +
+	"\bcurrency\x18\x01 \x01(\tR\bcurrency\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x02 \x01(\tR\trequestId\x12#\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage2\x8f\x03\n" +
+	"\fQuoteService\x12R\n" +
+	"\vStartUpdate\x12 .finquotes.v1.StartUpdateRequest\x1a!.finquotes.v1.StartUpdateResponse\x12@\n" +
+	"\tGetUpdate\x12\x1e.finquotes.v1.GetUpdateRequest\x1a\x13.finquotes.v1.Quote\x12<\n" +
+	"\aGetLast\x12\x1c.finquotes.v1.GetLastRequest\x1a\x13.finquotes.v1.Quote\x12H\n" +
+	"\fStreamQuotes\x12!.finquotes.v1.StreamQuotesRequest\x1a\x13.finquotes.v1.Quote0\x01\x12a\n" +
+	"\x10BatchStartUpdate\x12%.finquotes.v1.BatchStartUpdateRequest\x1a&.finquotes.v1.BatchStartUpdateResponseB5Z3FinQuotesService/internal/grpcapi/quotesv1;quotesv1b\x06proto3"
+
+var (
+	file_finquotes_v1_quotes_proto_rawDescOnce sync.Once
+	file_finquotes_v1_quotes_proto_rawDescData []byte
+)
+
+func file_finquotes_v1_quotes_proto_rawDescGZIP() []byte {
+	file_finquotes_v1_quotes_proto_rawDescOnce.Do(func() {
+		file_finquotes_v1_quotes_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_finquotes_v1_quotes_proto_rawDesc), len(file_finquotes_v1_quotes_proto_rawDesc)))
+	})
+	return file_finquotes_v1_quotes_proto_rawDescData
+}
+
+var file_finquotes_v1_quotes_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_finquotes_v1_quotes_proto_goTypes = []any{
+	(*StartUpdateRequest)(nil),       // 0: finquotes.v1.StartUpdateRequest
+	(*StartUpdateResponse)(nil),      // 1: finquotes.v1.StartUpdateResponse
+	(*GetUpdateRequest)(nil),         // 2: finquotes.v1.GetUpdateRequest
+	(*GetLastRequest)(nil),           // 3: finquotes.v1.GetLastRequest
+	(*Quote)(nil),                    // 4: finquotes.v1.Quote
+	(*StreamQuotesRequest)(nil),      // 5: finquotes.v1.StreamQuotesRequest
+	(*BatchStartUpdateRequest)(nil),  // 6: finquotes.v1.BatchStartUpdateRequest
+	(*BatchStartUpdateResponse)(nil), // 7: finquotes.v1.BatchStartUpdateResponse
+	(*BatchStartUpdateResult)(nil),   // 8: finquotes.v1.BatchStartUpdateResult
+	(*timestamppb.Timestamp)(nil),    // 9: google.protobuf.Timestamp
+}
+var file_finquotes_v1_quotes_proto_depIdxs = []int32{
+	9, // 0: finquotes.v1.Quote.updated_at:type_name -> google.protobuf.Timestamp
+	8, // 1: finquotes.v1.BatchStartUpdateResponse.results:type_name -> finquotes.v1.BatchStartUpdateResult
+	0, // 2: finquotes.v1.QuoteService.StartUpdate:input_type -> finquotes.v1.StartUpdateRequest
+	2, // 3: finquotes.v1.QuoteService.GetUpdate:input_type -> finquotes.v1.GetUpdateRequest
+	3, // 4: finquotes.v1.QuoteService.GetLast:input_type -> finquotes.v1.GetLastRequest
+	5, // 5: finquotes.v1.QuoteService.StreamQuotes:input_type -> finquotes.v1.StreamQuotesRequest
+	6, // 6: finquotes.v1.QuoteService.BatchStartUpdate:input_type -> finquotes.v1.BatchStartUpdateRequest
+	1, // 7: finquotes.v1.QuoteService.StartUpdate:output_type -> finquotes.v1.StartUpdateResponse
+	4, // 8: finquotes.v1.QuoteService.GetUpdate:output_type -> finquotes.v1.Quote
+	4, // 9: finquotes.v1.QuoteService.GetLast:output_type -> finquotes.v1.Quote
+	4, // 10: finquotes.v1.QuoteService.StreamQuotes:output_type -> finquotes.v1.Quote
+	7, // 11: finquotes.v1.QuoteService.BatchStartUpdate:output_type -> finquotes.v1.BatchStartUpdateResponse
+	7, // [7:12] is the sub-list for method output_type
+	2, // [2:7] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_finquotes_v1_quotes_proto_init() }
+func file_finquotes_v1_quotes_proto_init() {
+	if File_finquotes_v1_quotes_proto != nil {
+		return
+	}
+	file_finquotes_v1_quotes_proto_msgTypes[4].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_finquotes_v1_quotes_proto_rawDesc), len(file_finquotes_v1_quotes_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_finquotes_v1_quotes_proto_goTypes,
+		DependencyIndexes: file_finquotes_v1_quotes_proto_depIdxs,
+		MessageInfos:      file_finquotes_v1_quotes_proto_msgTypes,
+	}.Build()
+	File_finquotes_v1_quotes_proto = out.File
+	file_finquotes_v1_quotes_proto_goTypes = nil
+	file_finquotes_v1_quotes_proto_depIdxs = nil
+}
@@ -0,0 +1,309 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: finquotes/v1/quotes.proto
+
+package quotesv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	QuoteService_StartUpdate_FullMethodName      = "/finquotes.v1.QuoteService/StartUpdate"
+	QuoteService_GetUpdate_FullMethodName        = "/finquotes.v1.QuoteService/GetUpdate"
+	QuoteService_GetLast_FullMethodName          = "/finquotes.v1.QuoteService/GetLast"
+	QuoteService_StreamQuotes_FullMethodName     = "/finquotes.v1.QuoteService/StreamQuotes"
+	QuoteService_BatchStartUpdate_FullMethodName = "/finquotes.v1.QuoteService/BatchStartUpdate"
+)
+
+// QuoteServiceClient is the client API for QuoteService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// QuoteService is the gRPC counterpart of the /v1 HTTP API. Currency pairs
+// are written as BASE/QUOTE, for example "USD/EUR".
+//
+// Calls carry an API key in the "authorization" ("Bearer <key>") or
+// "x-api-key" metadata. StartUpdate and BatchStartUpdate need the
+// quotes:update scope, the other calls quotes:read.
+type QuoteServiceClient interface {
+	// StartUpdate queues an update of a pair and returns its request id. While
+	// an update of the pair is pending its id is returned instead.
+	StartUpdate(ctx context.Context, in *StartUpdateRequest, opts ...grpc.CallOption) (*StartUpdateResponse, error)
+	// GetUpdate returns the result of an update. It fails with
+	// FAILED_PRECONDITION while the update is still pending.
+	GetUpdate(ctx context.Context, in *GetUpdateRequest, opts ...grpc.CallOption) (*Quote, error)
+	// GetLast returns the latest successful update of a pair.
+	GetLast(ctx context.Context, in *GetLastRequest, opts ...grpc.CallOption) (*Quote, error)
+	// StreamQuotes sends the latest quote of each requested pair, then every
+	// new successful update until the client cancels.
+	StreamQuotes(ctx context.Context, in *StreamQuotesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Quote], error)
+	// BatchStartUpdate starts updates of several pairs. Each pair gets its own
+	// result, so one unsupported pair does not fail the others.
+	BatchStartUpdate(ctx context.Context, in *BatchStartUpdateRequest, opts ...grpc.CallOption) (*BatchStartUpdateResponse, error)
+}
+
+type quoteServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewQuoteServiceClient(cc grpc.ClientConnInterface) QuoteServiceClient {
+	return &quoteServiceClient{cc}
+}
+
+func (c *quoteServiceClient) StartUpdate(ctx context.Context, in *StartUpdateRequest, opts ...grpc.CallOption) (*StartUpdateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StartUpdateResponse)
+	err := c.cc.Invoke(ctx, QuoteService_StartUpdate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *quoteServiceClient) GetUpdate(ctx context.Context, in *GetUpdateRequest, opts ...grpc.CallOption) (*Quote, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Quote)
+	err := c.cc.Invoke(ctx, QuoteService_GetUpdate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *quoteServiceClient) GetLast(ctx context.Context, in *GetLastRequest, opts ...grpc.CallOption) (*Quote, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Quote)
+	err := c.cc.Invoke(ctx, QuoteService_GetLast_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *quoteServiceClient) StreamQuotes(ctx context.Context, in *StreamQuotesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Quote], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &QuoteService_ServiceDesc.Streams[0], QuoteService_StreamQuotes_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamQuotesRequest, Quote]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type QuoteService_StreamQuotesClient = grpc.ServerStreamingClient[Quote]
+
+func (c *quoteServiceClient) BatchStartUpdate(ctx context.Context, in *BatchStartUpdateRequest, opts ...grpc.CallOption) (*BatchStartUpdateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchStartUpdateResponse)
+	err := c.cc.Invoke(ctx, QuoteService_BatchStartUpdate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// QuoteServiceServer is the server API for QuoteService service.
+// All implementations must embed UnimplementedQuoteServiceServer
+// for forward compatibility.
+//
+// QuoteService is the gRPC counterpart of the /v1 HTTP API. Currency pairs
+// are written as BASE/QUOTE, for example "USD/EUR".
+//
+// Calls carry an API key in the "authorization" ("Bearer <key>") or
+// "x-api-key" metadata. StartUpdate and BatchStartUpdate need the
+// quotes:update scope, the other calls quotes:read.
+type QuoteServiceServer interface {
+	// StartUpdate queues an update of a pair and returns its request id. While
+	// an update of the pair is pending its id is returned instead.
+	StartUpdate(context.Context, *StartUpdateRequest) (*StartUpdateResponse, error)
+	// GetUpdate returns the result of an update. It fails with
+	// FAILED_PRECONDITION while the update is still pending.
+	GetUpdate(context.Context, *GetUpdateRequest) (*Quote, error)
+	// GetLast returns the latest successful update of a pair.
+	GetLast(context.Context, *GetLastRequest) (*Quote, error)
+	// StreamQuotes sends the latest quote of each requested pair, then every
+	// new successful update until the client cancels.
+	StreamQuotes(*StreamQuotesRequest, grpc.ServerStreamingServer[Quote]) error
+	// BatchStartUpdate starts updates of several pairs. Each pair gets its own
+	// result, so one unsupported pair does not fail the others.
+	BatchStartUpdate(context.Context, *BatchStartUpdateRequest) (*BatchStartUpdateResponse, error)
+	mustEmbedUnimplementedQuoteServiceServer()
+}
+
+// UnimplementedQuoteServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedQuoteServiceServer struct{}
+
+func (UnimplementedQuoteServiceServer) StartUpdate(context.Context, *StartUpdateRequest) (*StartUpdateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartUpdate not implemented")
+}
+func (UnimplementedQuoteServiceServer) GetUpdate(context.Context, *GetUpdateRequest) (*Quote, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUpdate not implemented")
+}
+func (UnimplementedQuoteServiceServer) GetLast(context.Context, *GetLastRequest) (*Quote, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLast not implemented")
+}
+func (UnimplementedQuoteServiceServer) StreamQuotes(*StreamQuotesRequest, grpc.ServerStreamingServer[Quote]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamQuotes not implemented")
+}
+func (UnimplementedQuoteServiceServer) BatchStartUpdate(context.Context, *BatchStartUpdateRequest) (*BatchStartUpdateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchStartUpdate not implemented")
+}
+func (UnimplementedQuoteServiceServer) mustEmbedUnimplementedQuoteServiceServer() {}
+func (UnimplementedQuoteServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeQuoteServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to QuoteServiceServer will
+// result in compilation errors.
+type UnsafeQuoteServiceServer interface {
+	mustEmbedUnimplementedQuoteServiceServer()
+}
+
+func RegisterQuoteServiceServer(s grpc.ServiceRegistrar, srv QuoteServiceServer) {
+	// If the following call pancis, it indicates UnimplementedQuoteServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&QuoteService_ServiceDesc, srv)
+}
+
+func _QuoteService_StartUpdate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartUpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuoteServiceServer).StartUpdate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QuoteService_StartUpdate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuoteServiceServer).StartUpdate(ctx, req.(*StartUpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QuoteService_GetUpdate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuoteServiceServer).GetUpdate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QuoteService_GetUpdate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuoteServiceServer).GetUpdate(ctx, req.(*GetUpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QuoteService_GetLast_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLastRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuoteServiceServer).GetLast(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QuoteService_GetLast_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuoteServiceServer).GetLast(ctx, req.(*GetLastRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QuoteService_StreamQuotes_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamQuotesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(QuoteServiceServer).StreamQuotes(m, &grpc.GenericServerStream[StreamQuotesRequest, Quote]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type QuoteService_StreamQuotesServer = grpc.ServerStreamingServer[Quote]
+
+func _QuoteService_BatchStartUpdate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchStartUpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuoteServiceServer).BatchStartUpdate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QuoteService_BatchStartUpdate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuoteServiceServer).BatchStartUpdate(ctx, req.(*BatchStartUpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// QuoteService_ServiceDesc is the grpc.ServiceDesc for QuoteService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var QuoteService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "finquotes.v1.QuoteService",
+	HandlerType: (*QuoteServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "StartUpdate",
+			Handler:    _QuoteService_StartUpdate_Handler,
+		},
+		{
+			MethodName: "GetUpdate",
+			Handler:    _QuoteService_GetUpdate_Handler,
+		},
+		{
+			MethodName: "GetLast",
+			Handler:    _QuoteService_GetLast_Handler,
+		},
+		{
+			MethodName: "BatchStartUpdate",
+			Handler:    _QuoteService_BatchStartUpdate_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamQuotes",
+			Handler:       _QuoteService_StreamQuotes_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "finquotes/v1/quotes.proto",
+}
@@ -0,0 +1,211 @@
+// Package grpcapi serves the quote API over gRPC, next to the HTTP handlers
+// and on top of the same quote service and job queue.
+package grpcapi
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=FinQuotesService --go-grpc_out=../.. --go-grpc_opt=module=FinQuotesService finquotes/v1/quotes.proto
+
+import (
+	"FinQuotesService/internal/api"
+	"FinQuotesService/internal/auth"
+	"FinQuotesService/internal/grpcapi/quotesv1"
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/service"
+	"FinQuotesService/internal/worker"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const maxBatchSize = 100
+
+// streamBuffer is how many updates a slow StreamQuotes client may lag behind
+// before it starts missing them.
+const streamBuffer = 64
+
+type Server struct {
+	quotesv1.UnimplementedQuoteServiceServer
+	SupportedCurrency map[string]bool
+	Srv               service.QuoteServiceInterface
+	JobChan           chan worker.QuoteJob
+	Updates           *service.Broadcaster
+
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+func NewServer(supported map[string]bool, srv service.QuoteServiceInterface, jobs chan worker.QuoteJob, updates *service.Broadcaster) *Server {
+	return &Server{
+		SupportedCurrency: supported,
+		Srv:               srv,
+		JobChan:           jobs,
+		Updates:           updates,
+		closing:           make(chan struct{}),
+	}
+}
+
+// Close ends running StreamQuotes calls, which would otherwise keep a
+// graceful stop waiting until their clients go away.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() { close(s.closing) })
+}
+
+func (s *Server) StartUpdate(ctx context.Context, req *quotesv1.StartUpdateRequest) (*quotesv1.StartUpdateResponse, error) {
+	if !s.SupportedCurrency[req.GetCurrency()] {
+		return nil, statusError(api.UnsupportedCurrencyPair)
+	}
+	if req.GetMaxAge() < 0 {
+		return nil, statusError(api.InvalidRequestParams)
+	}
+	if req.GetMaxAge() > 0 && !req.GetForce() {
+		q, fresh, err := worker.FreshQuote(ctx, s.Srv, req.GetCurrency(), time.Duration(req.GetMaxAge())*time.Second)
+		if err != nil {
+			return nil, lookupError(ctx, err)
+		}
+		if fresh {
+			return &quotesv1.StartUpdateResponse{RequestId: q.ID, Fresh: true}, nil
+		}
+	}
+	id, err := worker.StartUpdate(ctx, s.Srv, s.JobChan, req.GetCurrency(), auth.KeyID(ctx))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to start update", "component", "grpc", "currency", req.GetCurrency(), "error", err)
+		return nil, statusError(api.ServerInternalError)
+	}
+	return &quotesv1.StartUpdateResponse{RequestId: id}, nil
+}
+
+func (s *Server) GetUpdate(ctx context.Context, req *quotesv1.GetUpdateRequest) (*quotesv1.Quote, error) {
+	if req.GetRequestId() == "" {
+		return nil, statusError(api.InvalidRequestParams)
+	}
+	q, err := s.Srv.GetQuoteById(ctx, req.GetRequestId())
+	if err != nil {
+		return nil, lookupError(ctx, err)
+	}
+	if q.Status == model.StatusPending || q.Status == model.StatusSuspect {
+		return nil, statusError(api.QuoteOnPending)
+	}
+	if q.Status == model.StatusRejected {
+		q.Price = nil
+	}
+	return mapToQuote(q), nil
+}
+
+func (s *Server) GetLast(ctx context.Context, req *quotesv1.GetLastRequest) (*quotesv1.Quote, error) {
+	if !s.SupportedCurrency[req.GetCurrency()] {
+		return nil, statusError(api.UnsupportedCurrencyPair)
+	}
+	q, err := s.Srv.GetLastQuote(ctx, req.GetCurrency(), model.StatusDone)
+	if err != nil {
+		return nil, lookupError(ctx, err)
+	}
+	return mapToQuote(q), nil
+}
+
+func (s *Server) StreamQuotes(req *quotesv1.StreamQuotesRequest, stream quotesv1.QuoteService_StreamQuotesServer) error {
+	ctx := stream.Context()
+	currencies := req.GetCurrencies()
+	if len(currencies) == 0 {
+		for c := range s.SupportedCurrency {
+			currencies = append(currencies, c)
+		}
+		slices.Sort(currencies)
+	}
+	wanted := make(map[string]bool, len(currencies))
+	for _, c := range currencies {
+		if !s.SupportedCurrency[c] {
+			return statusError(api.UnsupportedCurrencyPair)
+		}
+		wanted[c] = true
+	}
+
+	// Subscribe before reading the latest quotes so that nothing completing
+	// in between is lost; the client may see such a quote twice.
+	updates, unsubscribe := s.Updates.Subscribe(streamBuffer)
+	defer unsubscribe()
+	for _, c := range currencies {
+		q, err := s.Srv.GetLastQuote(ctx, c, model.StatusDone)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return lookupError(ctx, err)
+		}
+		if err := stream.Send(mapToQuote(q)); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.closing:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case q := <-updates:
+			if !wanted[q.Currency] {
+				continue
+			}
+			if err := stream.Send(mapToQuote(q)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Server) BatchStartUpdate(ctx context.Context, req *quotesv1.BatchStartUpdateRequest) (*quotesv1.BatchStartUpdateResponse, error) {
+	if len(req.GetCurrencies()) == 0 || len(req.GetCurrencies()) > maxBatchSize {
+		return nil, statusError(api.InvalidRequestParams)
+	}
+	resp := &quotesv1.BatchStartUpdateResponse{Results: make([]*quotesv1.BatchStartUpdateResult, 0, len(req.GetCurrencies()))}
+	for _, c := range req.GetCurrencies() {
+		result := &quotesv1.BatchStartUpdateResult{Currency: c}
+		if !s.SupportedCurrency[c] {
+			result.ErrorMessage = string(api.UnsupportedCurrencyPair)
+		} else if id, err := worker.StartUpdate(ctx, s.Srv, s.JobChan, c, auth.KeyID(ctx)); err != nil {
+			slog.ErrorContext(ctx, "failed to start update", "component", "grpc", "currency", c, "error", err)
+			result.ErrorMessage = string(api.ServerInternalError)
+		} else {
+			result.RequestId = id
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp, nil
+}
+
+// statusError maps a ServiceError to the gRPC status code matching its HTTP
+// status, keeping the message so clients of both APIs see the same text.
+func statusError(e api.ServiceError) error {
+	code := codes.Internal
+	switch e {
+	case api.UnsupportedCurrencyPair, api.InvalidRequestParams:
+		code = codes.InvalidArgument
+	case api.QuoteNotFound, api.APIKeyNotFound:
+		code = codes.NotFound
+	case api.QuoteOnPending:
+		code = codes.FailedPrecondition
+	}
+	return status.Error(code, string(e))
+}
+
+func lookupError(ctx context.Context, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return statusError(api.QuoteNotFound)
+	}
+	slog.ErrorContext(ctx, "quote lookup failed", "component", "grpc", "error", err)
+	return statusError(api.ServerInternalError)
+}
+
+func mapToQuote(q model.Quote) *quotesv1.Quote {
+	resp := &quotesv1.Quote{Currency: q.Currency, Price: q.Price}
+	if q.UpdatedAt != nil {
+		resp.UpdatedAt = timestamppb.New(*q.UpdatedAt)
+	}
+	return resp
+}
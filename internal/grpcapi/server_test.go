@@ -0,0 +1,251 @@
+package grpcapi
+
+import (
+	"FinQuotesService/internal/auth"
+	"FinQuotesService/internal/grpcapi/quotesv1"
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/ratelimit"
+	"FinQuotesService/internal/repository/memory"
+	"FinQuotesService/internal/service"
+	"FinQuotesService/internal/worker"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testEnv struct {
+	client quotesv1.QuoteServiceClient
+	srv    *service.QuoteService
+	jobs   chan worker.QuoteJob
+	server *Server
+	// limiter has no limits until a test sets its routes.
+	limiter *ratelimit.Limiter
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	keys := memory.NewAPIKeyRepository()
+	keys.CreateAPIKey("reader", auth.HashKey("reader"), []model.Scope{model.ScopeQuotesRead})
+	keys.CreateAPIKey("writer", auth.HashKey("writer"), []model.Scope{model.ScopeQuotesUpdate, model.ScopeQuotesRead})
+	authn := auth.NewAuthenticator(keys)
+
+	srv := service.NewQuoteService(memory.NewQuoteRepository())
+	srv.Updates = service.NewBroadcaster()
+	jobs := make(chan worker.QuoteJob, 10)
+	server := NewServer(map[string]bool{"USD/EUR": true, "EUR/USD": true}, srv, jobs, srv.Updates)
+
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[string]ratelimit.Limit{})
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer(grpc.UnaryInterceptor(UnaryInterceptor(authn, limiter)), grpc.StreamInterceptor(StreamInterceptor(authn, limiter)))
+	quotesv1.RegisterQuoteServiceServer(gs, server)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testEnv{client: quotesv1.NewQuoteServiceClient(conn), srv: srv, jobs: jobs, server: server, limiter: limiter}
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+key)
+}
+
+func expectCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != code {
+		t.Fatalf("expected %s, got %s (%v)", code, got, err)
+	}
+}
+
+func TestServer_UpdateLifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := withKey("writer")
+
+	started, err := env.client.StartUpdate(ctx, &quotesv1.StartUpdateRequest{Currency: "USD/EUR"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	job := <-env.jobs
+	if job.Id != started.RequestId || job.RequestId == "" {
+		t.Errorf("unexpected job %+v", job)
+	}
+	again, err := env.client.StartUpdate(ctx, &quotesv1.StartUpdateRequest{Currency: "USD/EUR"})
+	if err != nil || again.RequestId != started.RequestId {
+		t.Errorf("expected pending update to be reused, got %v, %v", again, err)
+	}
+
+	_, err = env.client.GetUpdate(ctx, &quotesv1.GetUpdateRequest{RequestId: started.RequestId})
+	expectCode(t, err, codes.FailedPrecondition)
+
+	if err := env.srv.UpdateQuote(context.Background(), started.RequestId, 0.92, model.StatusDone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q, err := env.client.GetUpdate(ctx, &quotesv1.GetUpdateRequest{RequestId: started.RequestId})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.GetPrice() != 0.92 || q.GetUpdatedAt() == nil {
+		t.Errorf("unexpected quote %v", q)
+	}
+	last, err := env.client.GetLast(ctx, &quotesv1.GetLastRequest{Currency: "USD/EUR"})
+	if err != nil || last.GetPrice() != 0.92 {
+		t.Errorf("unexpected last quote %v, %v", last, err)
+	}
+}
+
+func TestServer_StartUpdateMaxAge(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := withKey("writer")
+	bg := context.Background()
+	done, _ := env.srv.InsertPendingQuote(bg, "USD/EUR", "")
+	env.srv.UpdateQuote(bg, done, 0.92, model.StatusDone)
+
+	resp, err := env.client.StartUpdate(ctx, &quotesv1.StartUpdateRequest{Currency: "USD/EUR", MaxAge: 300})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.RequestId != done || !resp.Fresh || len(env.jobs) != 0 {
+		t.Errorf("expected the done quote without a new job, got %v", resp)
+	}
+	resp, err = env.client.StartUpdate(ctx, &quotesv1.StartUpdateRequest{Currency: "USD/EUR", MaxAge: 300, Force: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.RequestId == done || resp.Fresh || len(env.jobs) != 1 {
+		t.Errorf("expected force to start an update, got %v", resp)
+	}
+	_, err = env.client.StartUpdate(ctx, &quotesv1.StartUpdateRequest{Currency: "USD/EUR", MaxAge: -1})
+	expectCode(t, err, codes.InvalidArgument)
+}
+
+func TestServer_Errors(t *testing.T) {
+	env := newTestEnv(t)
+
+	_, err := env.client.GetLast(context.Background(), &quotesv1.GetLastRequest{Currency: "USD/EUR"})
+	expectCode(t, err, codes.Unauthenticated)
+	_, err = env.client.GetLast(withKey("unknown"), &quotesv1.GetLastRequest{Currency: "USD/EUR"})
+	expectCode(t, err, codes.Unauthenticated)
+	_, err = env.client.StartUpdate(withKey("reader"), &quotesv1.StartUpdateRequest{Currency: "USD/EUR"})
+	expectCode(t, err, codes.PermissionDenied)
+
+	ctx := withKey("reader")
+	_, err = env.client.GetLast(ctx, &quotesv1.GetLastRequest{Currency: "GBP/USD"})
+	expectCode(t, err, codes.InvalidArgument)
+	_, err = env.client.GetLast(ctx, &quotesv1.GetLastRequest{Currency: "USD/EUR"})
+	expectCode(t, err, codes.NotFound)
+	_, err = env.client.GetUpdate(ctx, &quotesv1.GetUpdateRequest{})
+	expectCode(t, err, codes.InvalidArgument)
+	_, err = env.client.GetUpdate(ctx, &quotesv1.GetUpdateRequest{RequestId: "missing"})
+	expectCode(t, err, codes.NotFound)
+	if s, _ := status.FromError(err); s.Message() != "Quote or request not found" {
+		t.Errorf("expected the HTTP error message, got %q", s.Message())
+	}
+}
+
+func TestServer_BatchStartUpdate(t *testing.T) {
+	env := newTestEnv(t)
+	resp, err := env.client.BatchStartUpdate(withKey("writer"), &quotesv1.BatchStartUpdateRequest{
+		Currencies: []string{"USD/EUR", "GBP/USD", "EUR/USD"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	results := resp.GetResults()
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if results[0].GetRequestId() == "" || results[2].GetRequestId() == "" {
+		t.Errorf("expected supported pairs to start: %v", results)
+	}
+	if results[1].GetRequestId() != "" || results[1].GetErrorMessage() != "Unsupported currency pair" {
+		t.Errorf("expected GBP/USD to be rejected: %v", results[1])
+	}
+	if len(env.jobs) != 2 {
+		t.Errorf("expected 2 queued jobs, got %d", len(env.jobs))
+	}
+
+	_, err = env.client.BatchStartUpdate(withKey("writer"), &quotesv1.BatchStartUpdateRequest{})
+	expectCode(t, err, codes.InvalidArgument)
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	env := newTestEnv(t)
+	env.limiter.Routes["/quotes/update"] = ratelimit.Per(1, time.Hour, 3)
+
+	resp, err := env.client.BatchStartUpdate(withKey("writer"), &quotesv1.BatchStartUpdateRequest{Currencies: []string{"USD/EUR", "EUR/USD"}})
+	if err != nil || len(resp.GetResults()) != 2 {
+		t.Fatalf("expected the batch to pass, got %v, %v", resp, err)
+	}
+	// The rejected batch takes none of the tokens left, so a single update
+	// still passes with the last one.
+	var header metadata.MD
+	_, err = env.client.BatchStartUpdate(withKey("writer"), &quotesv1.BatchStartUpdateRequest{Currencies: []string{"USD/EUR", "EUR/USD"}}, grpc.Header(&header))
+	expectCode(t, err, codes.ResourceExhausted)
+	if got := header.Get("retry-after"); len(got) != 1 || got[0] == "" {
+		t.Errorf("expected a retry-after header, got %v", header)
+	}
+	if got := header.Get("ratelimit-remaining"); len(got) != 1 || got[0] != "1" {
+		t.Errorf("expected the rejected batch to leave 1 token, got %v", header)
+	}
+	// Past the limit, the unsupported pair is rejected by the handler.
+	_, err = env.client.StartUpdate(withKey("writer"), &quotesv1.StartUpdateRequest{Currency: "GBP/USD"})
+	expectCode(t, err, codes.InvalidArgument)
+	header = nil
+	_, err = env.client.StartUpdate(withKey("writer"), &quotesv1.StartUpdateRequest{Currency: "USD/EUR"}, grpc.Header(&header))
+	expectCode(t, err, codes.ResourceExhausted)
+	if got := header.Get("ratelimit-remaining"); len(got) != 1 || got[0] != "0" {
+		t.Errorf("expected no tokens left, got %v", header)
+	}
+	if _, err := env.client.GetLast(withKey("reader"), &quotesv1.GetLastRequest{Currency: "USD/EUR"}); status.Code(err) == codes.ResourceExhausted {
+		t.Errorf("expected a route without a limit to pass, got %v", err)
+	}
+}
+
+func TestServer_StreamQuotes(t *testing.T) {
+	env := newTestEnv(t)
+	bg := context.Background()
+	first, _ := env.srv.InsertPendingQuote(bg, "USD/EUR", "")
+	env.srv.UpdateQuote(bg, first, 0.91, model.StatusDone)
+
+	ctx, cancel := context.WithTimeout(withKey("reader"), 5*time.Second)
+	defer cancel()
+	stream, err := env.client.StreamQuotes(ctx, &quotesv1.StreamQuotesRequest{Currencies: []string{"USD/EUR"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q, err := stream.Recv()
+	if err != nil || q.GetPrice() != 0.91 {
+		t.Fatalf("expected the latest quote first, got %v, %v", q, err)
+	}
+
+	for env.server.Updates.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	other, _ := env.srv.InsertPendingQuote(bg, "EUR/USD", "")
+	env.srv.UpdateQuote(bg, other, 1.09, model.StatusDone)
+	next, _ := env.srv.InsertPendingQuote(bg, "USD/EUR", "")
+	env.srv.UpdateQuote(bg, next, 0.93, model.StatusDone)
+
+	q, err = stream.Recv()
+	if err != nil || q.GetCurrency() != "USD/EUR" || q.GetPrice() != 0.93 {
+		t.Fatalf("expected the USD/EUR update only, got %v, %v", q, err)
+	}
+
+	env.server.Close()
+	_, err = stream.Recv()
+	expectCode(t, err, codes.Unavailable)
+}
@@ -0,0 +1,112 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOk   = "ok"
+	StatusFail = "fail"
+)
+
+type CheckFunc func(ctx context.Context) error
+
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Response struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Checker serves /healthz, which only tells that the process is alive, and
+// /readyz, which runs every registered dependency check.
+type Checker struct {
+	timeout time.Duration
+	mu      sync.RWMutex
+	checks  map[string]CheckFunc
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  make(map[string]CheckFunc),
+	}
+}
+
+func (c *Checker) Add(name string, check CheckFunc) {
+	c.mu.Lock()
+	c.checks[name] = check
+	c.mu.Unlock()
+}
+
+// Remove unregisters the check called name, if any.
+func (c *Checker) Remove(name string) {
+	c.mu.Lock()
+	delete(c.checks, name)
+	c.mu.Unlock()
+}
+
+// Run executes all checks concurrently, each bounded by the checker timeout.
+func (c *Checker) Run(ctx context.Context) Response {
+	c.mu.RLock()
+	checks := make(map[string]CheckFunc, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	resp := Response{Status: StatusOk, Checks: make(map[string]CheckResult, len(checks))}
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := CheckResult{Status: StatusOk}
+			if err := check(ctx); err != nil {
+				result = CheckResult{Status: StatusFail, Error: err.Error()}
+			}
+			mu.Lock()
+			resp.Checks[name] = result
+			if result.Status == StatusFail {
+				resp.Status = StatusFail
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return resp
+}
+
+func (c *Checker) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, Response{Status: StatusOk})
+}
+
+func (c *Checker) Readiness(w http.ResponseWriter, r *http.Request) {
+	resp := c.Run(r.Context())
+	code := http.StatusOK
+	if resp.Status != StatusOk {
+		code = http.StatusServiceUnavailable
+	}
+	writeResponse(w, code, resp)
+}
+
+func writeResponse(w http.ResponseWriter, code int, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		return
+	}
+}
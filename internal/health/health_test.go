@@ -0,0 +1,91 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadiness_AllChecksPass(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.Add("db", func(ctx context.Context) error { return nil })
+	c.Add("workers", func(ctx context.Context) error { return nil })
+
+	w := httptest.NewRecorder()
+	c.Readiness(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp Response
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if resp.Status != StatusOk || len(resp.Checks) != 2 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestReadiness_FailingCheck(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.Add("db", func(ctx context.Context) error { return errors.New("connection refused") })
+	c.Add("workers", func(ctx context.Context) error { return nil })
+
+	w := httptest.NewRecorder()
+	c.Readiness(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+	var resp Response
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if resp.Status != StatusFail {
+		t.Errorf("expected fail status, got %s", resp.Status)
+	}
+	if db := resp.Checks["db"]; db.Status != StatusFail || db.Error != "connection refused" {
+		t.Errorf("unexpected db check: %+v", db)
+	}
+	if resp.Checks["workers"].Status != StatusOk {
+		t.Errorf("unexpected workers check: %+v", resp.Checks["workers"])
+	}
+}
+
+func TestChecker_Remove(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.Add("db", func(ctx context.Context) error { return errors.New("not connected yet") })
+	c.Remove("db")
+
+	if resp := c.Run(context.Background()); resp.Status != StatusOk || len(resp.Checks) != 0 {
+		t.Errorf("expected no checks left, got %+v", resp)
+	}
+}
+
+func TestReadiness_CheckTimeout(t *testing.T) {
+	c := NewChecker(10 * time.Millisecond)
+	c.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	resp := c.Run(context.Background())
+	if resp.Checks["slow"].Status != StatusFail {
+		t.Errorf("expected slow check to fail, got %+v", resp.Checks["slow"])
+	}
+}
+
+func TestLiveness(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.Add("db", func(ctx context.Context) error { return errors.New("down") })
+
+	w := httptest.NewRecorder()
+	c.Liveness(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("liveness must not depend on checks, got %d", w.Code)
+	}
+}
@@ -0,0 +1,41 @@
+// Package httpx holds the helpers shared by the HTTP middlewares.
+package httpx
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// StatusWriter records the status code written through it, for middlewares
+// that report it after the handler returned.
+type StatusWriter struct {
+	http.ResponseWriter
+	Status int
+}
+
+// NewStatusWriter wraps w. The status is 200 until the handler writes another.
+func NewStatusWriter(w http.ResponseWriter) *StatusWriter {
+	return &StatusWriter{ResponseWriter: w, Status: http.StatusOK}
+}
+
+func (w *StatusWriter) WriteHeader(code int) {
+	w.Status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, for
+// flushing and deadlines.
+func (w *StatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack lets WebSocket upgrades through the middleware. The websocket
+// library checks for http.Hijacker directly, so Unwrap is not enough.
+func (w *StatusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.Status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
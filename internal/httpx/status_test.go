@@ -0,0 +1,43 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatusWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sw := NewStatusWriter(rec)
+	if sw.Status != http.StatusOK {
+		t.Errorf("expected 200 by default, got %d", sw.Status)
+	}
+	sw.WriteHeader(http.StatusTeapot)
+	if sw.Status != http.StatusTeapot || rec.Code != http.StatusTeapot {
+		t.Errorf("expected 418 recorded and written, got %d and %d", sw.Status, rec.Code)
+	}
+}
+
+func TestStatusWriter_Hijack(t *testing.T) {
+	var sw *StatusWriter
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw = NewStatusWriter(w)
+		conn, _, err := sw.Hijack()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		conn.Write([]byte("HTTP/1.1 204 No Content\r\n\r\n"))
+		conn.Close()
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if sw.Status != http.StatusSwitchingProtocols {
+		t.Errorf("expected a hijacked connection to report 101, got %d", sw.Status)
+	}
+}
@@ -0,0 +1,266 @@
+// Package importer loads historical quotes from CSV or NDJSON files.
+package importer
+
+import (
+	"FinQuotesService/internal/model"
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// defaultMaxErrors is how many line errors a report lists by default.
+const defaultMaxErrors = 100
+
+// maxLineSize bounds a single NDJSON line.
+const maxLineSize = 64 * 1024
+
+// ErrBadHeader is returned when a CSV file lacks one of the required columns.
+var ErrBadHeader = errors.New("the header must name pair, timestamp and price columns")
+
+// Column names accepted in CSV headers and NDJSON objects. The second names
+// are the ones written by the export endpoint, so an export can be imported
+// elsewhere as is.
+var (
+	pairColumns      = []string{"pair", "currency"}
+	timestampColumns = []string{"timestamp", "updated_at"}
+	priceColumns     = []string{"price"}
+)
+
+// StoreError is returned when the store fails, as opposed to input that
+// cannot be read.
+type StoreError struct {
+	Line int
+	Err  error
+}
+
+func (e *StoreError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *StoreError) Unwrap() error {
+	return e.Err
+}
+
+// Store writes the imported quotes. service.QuoteServiceInterface satisfies it.
+type Store interface {
+	InsertHistoricalQuote(ctx context.Context, currency string, price float64, updatedAt time.Time, source string) (string, error)
+}
+
+// LineError tells why a line was skipped. Lines are numbered from 1 and count
+// the CSV header.
+type LineError struct {
+	Line    int
+	Message string
+}
+
+// Report sums up an import.
+type Report struct {
+	Imported int
+	// Duplicates are valid lines whose pair already had an imported quote
+	// at that timestamp, including earlier lines of the same file.
+	Duplicates int
+	Failed     int
+	// Errors lists the first failed lines.
+	Errors []LineError
+}
+
+type Importer struct {
+	SupportedCurrency map[string]bool
+	Store             Store
+	// MaxErrors caps Report.Errors, later failures are only counted.
+	MaxErrors int
+	now       func() time.Time
+}
+
+func New(supported map[string]bool, store Store) *Importer {
+	return &Importer{SupportedCurrency: supported, Store: store, MaxErrors: defaultMaxErrors, now: time.Now}
+}
+
+// Import reads quotes in format from r and stores them as done quotes marked
+// with model.SourceImport. Invalid lines are skipped and reported. An error
+// is returned only when the input cannot be read any further or the store
+// fails; the report then covers the lines handled so far, which stay stored.
+func (i *Importer) Import(ctx context.Context, r io.Reader, format string) (Report, error) {
+	var rep Report
+	var err error
+	switch format {
+	case FormatCSV:
+		err = i.importCSV(ctx, r, &rep)
+	case FormatNDJSON:
+		err = i.importNDJSON(ctx, r, &rep)
+	default:
+		err = fmt.Errorf("unknown import format %q", format)
+	}
+	return rep, err
+}
+
+func (i *Importer) importCSV(ctx context.Context, r io.Reader, rep *Report) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	pairCol, timestampCol, priceCol := -1, -1, -1
+	for n, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case slices.Contains(pairColumns, name):
+			pairCol = n
+		case slices.Contains(timestampColumns, name):
+			timestampCol = n
+		case slices.Contains(priceColumns, name):
+			priceCol = n
+		}
+	}
+	if pairCol < 0 || timestampCol < 0 || priceCol < 0 {
+		return ErrBadHeader
+	}
+	width := max(pairCol, timestampCol, priceCol) + 1
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			i.fail(rep, parseErr.StartLine, parseErr.Err.Error())
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := cr.FieldPos(0)
+		if len(record) < width {
+			i.fail(rep, line, fmt.Sprintf("expected at least %d fields, got %d", width, len(record)))
+			continue
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(record[priceCol]), 64)
+		if err != nil {
+			i.fail(rep, line, fmt.Sprintf("invalid price %q", record[priceCol]))
+			continue
+		}
+		if err := i.store(ctx, rep, line, strings.TrimSpace(record[pairCol]), strings.TrimSpace(record[timestampCol]), price); err != nil {
+			return err
+		}
+	}
+}
+
+// ndjsonLine accepts the same names as CSV headers.
+type ndjsonLine struct {
+	Pair      string   `json:"pair"`
+	Currency  string   `json:"currency"`
+	Timestamp string   `json:"timestamp"`
+	UpdatedAt string   `json:"updated_at"`
+	Price     *float64 `json:"price"`
+}
+
+func (i *Importer) importNDJSON(ctx context.Context, r io.Reader, rep *Report) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 4096), maxLineSize)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		var l ndjsonLine
+		if err := json.Unmarshal([]byte(text), &l); err != nil {
+			i.fail(rep, line, "invalid JSON: "+err.Error())
+			continue
+		}
+		if l.Price == nil {
+			i.fail(rep, line, "missing price")
+			continue
+		}
+		pair := l.Pair
+		if pair == "" {
+			pair = l.Currency
+		}
+		timestamp := l.Timestamp
+		if timestamp == "" {
+			timestamp = l.UpdatedAt
+		}
+		if err := i.store(ctx, rep, line, pair, timestamp, *l.Price); err != nil {
+			return err
+		}
+	}
+	if errors.Is(sc.Err(), bufio.ErrTooLong) {
+		return fmt.Errorf("line %d is longer than %d bytes", line+1, maxLineSize)
+	}
+	return sc.Err()
+}
+
+// store validates a line and writes it. Only store failures are returned.
+func (i *Importer) store(ctx context.Context, rep *Report, line int, pair, timestamp string, price float64) error {
+	if pair == "" {
+		i.fail(rep, line, "missing pair")
+		return nil
+	}
+	if !i.SupportedCurrency[pair] {
+		i.fail(rep, line, fmt.Sprintf("unsupported currency pair %q", pair))
+		return nil
+	}
+	at, ok := parseTimestamp(timestamp)
+	if !ok {
+		i.fail(rep, line, fmt.Sprintf("invalid timestamp %q, expected RFC 3339 or YYYY-MM-DD", timestamp))
+		return nil
+	}
+	if at.After(i.now()) {
+		i.fail(rep, line, fmt.Sprintf("timestamp %s is in the future", timestamp))
+		return nil
+	}
+	if price <= 0 || math.IsInf(price, 0) || math.IsNaN(price) {
+		i.fail(rep, line, fmt.Sprintf("price must be positive, got %v", price))
+		return nil
+	}
+	_, err := i.Store.InsertHistoricalQuote(ctx, pair, price, at, model.SourceImport)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		rep.Duplicates++
+	case err != nil:
+		return &StoreError{Line: line, Err: err}
+	default:
+		rep.Imported++
+	}
+	return nil
+}
+
+func (i *Importer) fail(rep *Report, line int, msg string) {
+	rep.Failed++
+	if len(rep.Errors) < i.MaxErrors {
+		rep.Errors = append(rep.Errors, LineError{Line: line, Message: msg})
+	}
+}
+
+// parseTimestamp accepts RFC 3339 timestamps and plain dates, which are taken
+// as midnight UTC.
+func parseTimestamp(s string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UTC(), true
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
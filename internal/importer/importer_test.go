@@ -0,0 +1,137 @@
+package importer
+
+import (
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository/memory"
+	"FinQuotesService/internal/service"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newImporter(t *testing.T) (*Importer, *service.QuoteService) {
+	t.Helper()
+	srv := service.NewQuoteService(memory.NewQuoteRepository())
+	i := New(map[string]bool{"USD/EUR": true, "EUR/USD": true}, srv)
+	i.now = func() time.Time { return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC) }
+	return i, srv
+}
+
+func exported(t *testing.T, srv *service.QuoteService, currency string) []model.Quote {
+	t.Helper()
+	var quotes []model.Quote
+	err := srv.ExportQuotes(context.Background(), currency, time.Time{}, time.Now(), func(q model.Quote) error {
+		quotes = append(quotes, q)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return quotes
+}
+
+func TestImport_CSV(t *testing.T) {
+	i, srv := newImporter(t)
+	input := strings.Join([]string{
+		"timestamp,pair,price,comment",
+		"2024-01-01,USD/EUR,0.905,first",
+		"2024-01-02T00:00:00Z,USD/EUR,0.91,",
+		"2024-01-01T00:00:00+00:00,USD/EUR,0.99,same time as line 2",
+		"2024-01-01,GBP/USD,1.27,",
+		"yesterday,EUR/USD,1.1,",
+		"2024-01-03,EUR/USD,abc,",
+		"2024-01-03,EUR/USD,-1,",
+		"2026-01-01,EUR/USD,1.1,",
+		"2024-01-03,EUR/USD",
+		"2024-01-03,EUR/USD,1.09,",
+	}, "\n")
+
+	rep, err := i.Import(context.Background(), strings.NewReader(input), FormatCSV)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rep.Imported != 3 || rep.Duplicates != 1 || rep.Failed != 6 {
+		t.Errorf("unexpected report %+v", rep)
+	}
+	wantLines := []int{5, 6, 7, 8, 9, 10}
+	if len(rep.Errors) != len(wantLines) {
+		t.Fatalf("expected %d line errors, got %+v", len(wantLines), rep.Errors)
+	}
+	for n, e := range rep.Errors {
+		if e.Line != wantLines[n] || e.Message == "" {
+			t.Errorf("expected an error for line %d, got %+v", wantLines[n], e)
+		}
+	}
+
+	quotes := exported(t, srv, "USD/EUR")
+	if len(quotes) != 2 || *quotes[0].Price != 0.905 || !quotes[0].UpdatedAt.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected USD/EUR quotes %+v", quotes)
+	}
+}
+
+func TestImport_NDJSON(t *testing.T) {
+	i, srv := newImporter(t)
+	input := `{"pair":"EUR/USD","timestamp":"2024-03-01T12:00:00Z","price":1.08}
+
+{"currency":"EUR/USD","updated_at":"2024-03-02T12:00:00Z","price":1.09}
+{"pair":"EUR/USD","timestamp":"2024-03-03T12:00:00Z"}
+{"pair":
+`
+	rep, err := i.Import(context.Background(), strings.NewReader(input), FormatNDJSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rep.Imported != 2 || rep.Failed != 2 || rep.Errors[0].Line != 4 || rep.Errors[1].Line != 5 {
+		t.Errorf("unexpected report %+v", rep)
+	}
+	if quotes := exported(t, srv, "EUR/USD"); len(quotes) != 2 {
+		t.Errorf("expected 2 quotes, got %+v", quotes)
+	}
+
+	// Importing the same file again only finds duplicates.
+	rep, err = i.Import(context.Background(), strings.NewReader(input), FormatNDJSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rep.Imported != 0 || rep.Duplicates != 2 {
+		t.Errorf("unexpected report %+v", rep)
+	}
+}
+
+func TestImport_BadHeader(t *testing.T) {
+	i, _ := newImporter(t)
+	_, err := i.Import(context.Background(), strings.NewReader("pair,price\nUSD/EUR,0.9\n"), FormatCSV)
+	if !errors.Is(err, ErrBadHeader) {
+		t.Errorf("expected ErrBadHeader, got %v", err)
+	}
+}
+
+func TestImport_MaxErrors(t *testing.T) {
+	i, _ := newImporter(t)
+	i.MaxErrors = 2
+	input := "pair,timestamp,price\n" + strings.Repeat("GBP/USD,2024-01-01,1.2\n", 5)
+	rep, err := i.Import(context.Background(), strings.NewReader(input), FormatCSV)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rep.Failed != 5 || len(rep.Errors) != 2 {
+		t.Errorf("expected 5 failures with 2 listed, got %+v", rep)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) InsertHistoricalQuote(ctx context.Context, currency string, price float64, updatedAt time.Time, source string) (string, error) {
+	return "", errors.New("disk full")
+}
+
+func TestImport_StoreFailure(t *testing.T) {
+	i, _ := newImporter(t)
+	i.Store = failingStore{}
+	_, err := i.Import(context.Background(), strings.NewReader("pair,timestamp,price\nUSD/EUR,2024-01-01,0.9\n"), FormatCSV)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected the store error with its line, got %v", err)
+	}
+}
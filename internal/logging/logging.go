@@ -0,0 +1,121 @@
+package logging
+
+import (
+	"FinQuotesService/internal/httpx"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	jobIDKey
+)
+
+// Setup installs a JSON slog logger as the process default. Records logged
+// with a context carry its request_id, job_id and trace ids automatically.
+func Setup(w io.Writer, level slog.Level) {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(&contextHandler{Handler: handler}))
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+func WithJobID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, jobIDKey, id)
+}
+
+func JobID(ctx context.Context) string {
+	id, _ := ctx.Value(jobIDKey).(string)
+	return id
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if id := JobID(ctx); id != "" {
+		r.AddAttrs(slog.String("job_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// Middleware assigns every request an ID, taken from X-Request-ID when the
+// client sent a sane one, echoes it back and logs the request once served.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := NewRequestID(r.Header.Get(RequestIDHeader))
+		w.Header().Set(RequestIDHeader, id)
+		ctx := WithRequestID(r.Context(), id)
+
+		sw := httpx.NewStatusWriter(w)
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		slog.InfoContext(ctx, "http request",
+			"component", "http",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", sw.Status,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	})
+}
+
+// NewRequestID returns the request ID sent by a client when it is safe to
+// log, or a fresh one.
+func NewRequestID(sent string) string {
+	if validRequestID(sent) {
+		return sent
+	}
+	return uuid.New().String()
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c > unicode.MaxASCII || !unicode.IsPrint(c) || unicode.IsSpace(c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware_KeepsClientRequestID(t *testing.T) {
+	var seen string
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/quotes/last/USD/EUR", nil)
+	req.Header.Set(RequestIDHeader, "client-id-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if seen != "client-id-1" {
+		t.Errorf("expected client-id-1 in context, got %q", seen)
+	}
+	if got := w.Header().Get(RequestIDHeader); got != "client-id-1" {
+		t.Errorf("expected client-id-1 in response header, got %q", got)
+	}
+}
+
+func TestMiddleware_GeneratesRequestID(t *testing.T) {
+	var seen string
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/quotes/last/USD/EUR", nil)
+	req.Header.Set(RequestIDHeader, "bad id\nwith newline")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if seen == "" || seen == "bad id\nwith newline" {
+		t.Errorf("expected generated request id, got %q", seen)
+	}
+	if got := w.Header().Get(RequestIDHeader); got != seen {
+		t.Errorf("response header %q does not match context id %q", got, seen)
+	}
+}
+
+func TestSetup_AddsContextIDs(t *testing.T) {
+	prev := slog.Default()
+	defer slog.SetDefault(prev)
+
+	var buf bytes.Buffer
+	Setup(&buf, slog.LevelInfo)
+	ctx := WithJobID(WithRequestID(context.Background(), "req-1"), "job-1")
+	slog.InfoContext(ctx, "job processing started", "component", "worker")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if record["request_id"] != "req-1" || record["job_id"] != "job-1" {
+		t.Errorf("expected request and job ids in record, got %v", record)
+	}
+	if record["msg"] != "job processing started" || record["component"] != "worker" {
+		t.Errorf("unexpected record: %v", record)
+	}
+}
@@ -0,0 +1,259 @@
+package metrics
+
+import (
+	"FinQuotesService/internal/httpx"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "finquotes"
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	WorkersBusy = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "workers_busy",
+		Help:      "Workers currently processing a job.",
+	})
+
+	WorkersIdle = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "workers_idle",
+		Help:      "Workers waiting for a job.",
+	})
+
+	JobDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Time spent by a worker on a single quote job.",
+		Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 20, 30, 45, 60, 120},
+	})
+
+	JobsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_total",
+		Help:      "Processed quote jobs by final status.",
+	}, []string{"status"})
+
+	ProviderDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_request_duration_seconds",
+		Help:      "Latency of upstream rate provider calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider"})
+
+	ProviderErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_errors_total",
+		Help:      "Failed upstream rate provider calls.",
+	}, []string{"provider"})
+
+	AuthFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_failures_total",
+		Help:      "Rejected requests by reason: missing_key, invalid_key, revoked_key or insufficient_scope.",
+	}, []string{"reason"})
+
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by the per-client rate limiter.",
+	}, []string{"route"})
+
+	WSConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ws_connections",
+		Help:      "Open WebSocket connections.",
+	})
+
+	WSDisconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ws_disconnects_total",
+		Help:      "Closed WebSocket connections by reason: client, slow_consumer, timeout or shutdown.",
+	}, []string{"reason"})
+
+	QuotesDerived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quotes_derived_total",
+		Help:      "Quotes computed from the latest quote of the inverse pair instead of fetched upstream.",
+	}, []string{"pair"})
+
+	InverseDeviation = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "quote_inverse_deviation",
+		Help:      "How far the product of the latest quotes of a pair and of its inverse is from 1.",
+	}, []string{"pair", "inverse"})
+
+	InverseInconsistent = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "quote_inverse_inconsistent",
+		Help:      "1 when the inverse deviation of a pair is above the tolerance, 0 otherwise.",
+	}, []string{"pair", "inverse"})
+
+	QuotesSuspect = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quotes_suspect_total",
+		Help:      "Quotes held for review because they moved too far from the previous quote.",
+	}, []string{"pair"})
+
+	QuoteAge = newQuoteAgeCollector()
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, HTTPDuration,
+		WorkersBusy, WorkersIdle, JobDuration, JobsTotal,
+		ProviderDuration, ProviderErrors,
+		AuthFailures, RateLimited,
+		WSConnections, WSDisconnects,
+		QuotesDerived, InverseDeviation, InverseInconsistent,
+		QuotesSuspect,
+		QuoteAge,
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// RegisterQueue exposes the current length and capacity of the job queue.
+func RegisterQueue(length, capacity func() int) {
+	Registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "job_queue_depth",
+			Help:      "Jobs waiting in the queue.",
+		}, func() float64 { return float64(length()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "job_queue_capacity",
+			Help:      "Capacity of the job queue.",
+		}, func() float64 { return float64(capacity()) }),
+	)
+}
+
+// RegisterDeferredJobs exposes the number of jobs waiting to be requeued.
+func RegisterDeferredJobs(count func() int) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "jobs_deferred",
+		Help:      "Jobs postponed because of provider limits, waiting to be requeued.",
+	}, func() float64 { return float64(count()) }))
+}
+
+// RegisterProviderQuota exposes the calls counted today against the daily
+// quota of a provider, and the quota itself.
+func RegisterProviderQuota(provider string, quota int, used func() (int, error)) {
+	labels := prometheus.Labels{"provider": provider}
+	Registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "provider_quota_used",
+			Help:        "Upstream calls made today (UTC).",
+			ConstLabels: labels,
+		}, func() float64 {
+			n, err := used()
+			if err != nil {
+				return -1
+			}
+			return float64(n)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "provider_quota_limit",
+			Help:        "Daily upstream call quota, 0 when unlimited.",
+			ConstLabels: labels,
+		}, func() float64 { return float64(quota) }),
+	)
+}
+
+// Middleware records request count and latency. The route label is the path of
+// the ServeMux pattern that matched, so path parameters don't blow up
+// cardinality; the method has a label of its own.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := httpx.NewStatusWriter(w)
+		next.ServeHTTP(sw, r)
+		route := r.Pattern
+		if _, path, ok := strings.Cut(route, " "); ok {
+			route = path
+		}
+		if route == "" {
+			route = "unmatched"
+		}
+		labels := prometheus.Labels{"route": route, "method": r.Method, "status": strconv.Itoa(sw.Status)}
+		HTTPRequests.With(labels).Inc()
+		HTTPDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+// quoteAgeCollector reports how old the latest done quote of every pair is
+// at scrape time.
+type quoteAgeCollector struct {
+	mu      sync.RWMutex
+	updated map[string]time.Time
+	desc    *prometheus.Desc
+	now     func() time.Time
+}
+
+func newQuoteAgeCollector() *quoteAgeCollector {
+	return &quoteAgeCollector{
+		updated: make(map[string]time.Time),
+		desc:    prometheus.NewDesc(namespace+"_quote_age_seconds", "Age of the latest done quote per currency pair.", []string{"pair"}, nil),
+		now:     time.Now,
+	}
+}
+
+// Observe records that pair got a done quote at t. Older timestamps are ignored.
+func (c *quoteAgeCollector) Observe(pair string, t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if prev, ok := c.updated[pair]; !ok || t.After(prev) {
+		c.updated[pair] = t
+	}
+}
+
+// Updated returns the time of the latest done quote observed for pair.
+func (c *quoteAgeCollector) Updated(pair string) (time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	t, ok := c.updated[pair]
+	return t, ok
+}
+
+func (c *quoteAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *quoteAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := c.now()
+	for pair, t := range c.updated {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, now.Sub(t).Seconds(), pair)
+	}
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddleware_RecordsRoutePattern(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/quotes/last/{base}/{quote}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := Middleware(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/quotes/last/USD/EUR", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	got := testutil.ToFloat64(HTTPRequests.WithLabelValues("/v1/quotes/last/{base}/{quote}", http.MethodGet, "404"))
+	if got != 1 {
+		t.Errorf("expected 1 request recorded, got %v", got)
+	}
+}
+
+func TestQuoteAgeCollector(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := newQuoteAgeCollector()
+	c.now = func() time.Time { return now }
+
+	c.Observe("USD/EUR", now.Add(-90*time.Second))
+	c.Observe("USD/EUR", now.Add(-time.Hour))
+
+	expected := `
+# HELP finquotes_quote_age_seconds Age of the latest done quote per currency pair.
+# TYPE finquotes_quote_age_seconds gauge
+finquotes_quote_age_seconds{pair="USD/EUR"} 90
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+type failingProvider struct{}
+
+func (failingProvider) Name() string { return "failing" }
+
+func (failingProvider) FetchRate(ctx context.Context, base, target string) (float64, error) {
+	return 0, errors.New("upstream down")
+}
+
+func (failingProvider) FetchHistoricalRate(ctx context.Context, base, target string, day time.Time) (float64, error) {
+	return 0, errors.New("upstream down")
+}
+
+func TestInstrumentProvider_CountsErrors(t *testing.T) {
+	p := InstrumentProvider(failingProvider{})
+	if _, err := p.FetchRate(context.Background(), "USD", "EUR"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if got := testutil.ToFloat64(ProviderErrors.WithLabelValues("failing")); got != 1 {
+		t.Errorf("expected 1 provider error, got %v", got)
+	}
+	if got := testutil.CollectAndCount(ProviderDuration); got != 1 {
+		t.Errorf("expected provider latency series, got %d", got)
+	}
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"FinQuotesService/internal/provider"
+	"context"
+	"time"
+)
+
+type instrumentedProvider struct {
+	provider.Provider
+}
+
+// InstrumentProvider records latency and errors of every provider call under
+// the provider's name.
+func InstrumentProvider(p provider.Provider) provider.Provider {
+	return &instrumentedProvider{Provider: p}
+}
+
+func (p *instrumentedProvider) FetchRate(ctx context.Context, base, target string) (float64, error) {
+	return p.observe(func() (float64, error) { return p.Provider.FetchRate(ctx, base, target) })
+}
+
+func (p *instrumentedProvider) FetchHistoricalRate(ctx context.Context, base, target string, day time.Time) (float64, error) {
+	return p.observe(func() (float64, error) { return p.Provider.FetchHistoricalRate(ctx, base, target, day) })
+}
+
+func (p *instrumentedProvider) observe(fetch func() (float64, error)) (float64, error) {
+	start := time.Now()
+	rate, err := fetch()
+	ProviderDuration.WithLabelValues(p.Name()).Observe(time.Since(start).Seconds())
+	if err != nil {
+		ProviderErrors.WithLabelValues(p.Name()).Inc()
+	}
+	return rate, err
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Scope string
+
+const (
+	ScopeQuotesRead   Scope = "quotes:read"
+	ScopeQuotesUpdate Scope = "quotes:update"
+	// ScopeAdmin grants every other scope as well.
+	ScopeAdmin Scope = "admin"
+)
+
+var knownScopes = map[Scope]bool{
+	ScopeQuotesRead:   true,
+	ScopeQuotesUpdate: true,
+	ScopeAdmin:        true,
+}
+
+// APIKey is a client credential. Only the SHA-256 hash of the secret is
+// stored; the plain key is shown once when it is created.
+type APIKey struct {
+	ID        string     `db:"id"`
+	Name      string     `db:"name"`
+	Hash      string     `db:"key_hash"`
+	Scopes    []Scope    `db:"scopes"`
+	CreatedAt time.Time  `db:"created_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+}
+
+func (s Scope) Valid() bool {
+	return knownScopes[s]
+}
+
+func (k APIKey) HasScope(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// ParseScopes parses a comma-separated scope list, as stored in the
+// database and accepted on the command line.
+func ParseScopes(s string) ([]Scope, error) {
+	var scopes []Scope
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		scope := Scope(part)
+		if !scope.Valid() {
+			return nil, fmt.Errorf("unknown scope %q", part)
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	return scopes, nil
+}
+
+func FormatScopes(scopes []Scope) string {
+	parts := make([]string, len(scopes))
+	for i, s := range scopes {
+		parts[i] = string(s)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+type BackfillStatus string
+
+const (
+	BackfillPending BackfillStatus = "pending"
+	BackfillRunning BackfillStatus = "running"
+	BackfillDone    BackfillStatus = "done"
+	BackfillFailed  BackfillStatus = "failed"
+)
+
+// Backfill fetches the historical rates of a currency pair, one UTC day at a
+// time from From to To inclusive. Days are stored as midnight UTC.
+type Backfill struct {
+	ID       string    `db:"id"`
+	Currency string    `db:"currency"`
+	From     time.Time `db:"from_day"`
+	To       time.Time `db:"to_day"`
+	// Next is the first day not fetched yet, the day after To once done.
+	Next   time.Time      `db:"next_day"`
+	Status BackfillStatus `db:"status"`
+	// Inserted counts the stored quotes, Duplicates the days that already
+	// had a backfilled or imported quote.
+	Inserted   int `db:"inserted"`
+	Duplicates int `db:"duplicates"`
+	// Error is why the backfill failed, empty otherwise.
+	Error     string    `db:"error"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+	// Owner is the instance holding the lease to run the backfill, empty
+	// when none does.
+	Owner string `db:"owner"`
+}
+
+// Active reports whether the backfill still has days to fetch.
+func (b Backfill) Active() bool {
+	return b.Status == BackfillPending || b.Status == BackfillRunning
+}
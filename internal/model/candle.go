@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type Candle struct {
+	Bucket time.Time `db:"bucket"`
+	Open   float64   `db:"open"`
+	High   float64   `db:"high"`
+	Low    float64   `db:"low"`
+	Close  float64   `db:"close"`
+	Count  int64     `db:"count"`
+}
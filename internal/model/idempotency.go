@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// IdempotencyRecord is the outcome of a request sent with an Idempotency-Key,
+// kept so that a retry gets the same response. Keys are scoped to the client
+// (an API key ID, empty for anonymous requests) that sent them.
+type IdempotencyRecord struct {
+	ClientID string `db:"client_id"`
+	Key      string `db:"key"`
+	// RequestHash identifies the request body, so that a key reused for a
+	// different request can be rejected.
+	RequestHash string    `db:"request_hash"`
+	RequestID   string    `db:"request_id"`
+	StatusCode  int       `db:"status_code"`
+	CreatedAt   time.Time `db:"created_at"`
+	// Fresh is set when RequestID is an existing done quote that satisfied
+	// max_age, so no update was started.
+	Fresh bool `db:"fresh"`
+}
@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type Quote struct {
 	ID        string     `db:"id"`
@@ -8,6 +11,8 @@ type Quote struct {
 	Price     *float64   `db:"price"`
 	UpdatedAt *time.Time `db:"updated_at"`
 	Status    Status     `db:"status"`
+	// RequestedBy is the ID of the API key that triggered the update.
+	RequestedBy *string `db:"requested_by"`
 }
 
 type Status string
@@ -16,4 +21,24 @@ const (
 	StatusPending Status = "pending"
 	StatusDone    Status = "done"
 	StatusError   Status = "error"
+	// StatusSuspect holds a quote that moved too far from the previous one
+	// until an admin approves it, making it done, or rejects it.
+	StatusSuspect  Status = "suspect"
+	StatusRejected Status = "rejected"
 )
+
+// SourceImport marks quotes loaded from a file rather than fetched upstream.
+const SourceImport = "import"
+
+// SourceBackfill marks quotes fetched for a past day by a backfill.
+const SourceBackfill = "backfill"
+
+// InversePair returns the pair quoted the other way round, "EUR/USD" for
+// "USD/EUR".
+func InversePair(pair string) (string, bool) {
+	base, target, ok := strings.Cut(pair, "/")
+	if !ok || base == "" || target == "" {
+		return "", false
+	}
+	return target + "/" + base, true
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("provider circuit is open")
+
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// CircuitBreaker stops calling a provider after threshold consecutive
+// failures. Once cooldown passes a single trial call is let through: success
+// closes the circuit again, failure re-opens it.
+type CircuitBreaker struct {
+	Provider
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	trial    bool
+	now      func() time.Time
+}
+
+func NewCircuitBreaker(p Provider, threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		Provider:  p,
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     CircuitClosed,
+		now:       time.Now,
+	}
+}
+
+func (b *CircuitBreaker) FetchRate(ctx context.Context, base, target string) (float64, error) {
+	return b.call(func() (float64, error) { return b.Provider.FetchRate(ctx, base, target) })
+}
+
+func (b *CircuitBreaker) FetchHistoricalRate(ctx context.Context, base, target string, day time.Time) (float64, error) {
+	return b.call(func() (float64, error) { return b.Provider.FetchHistoricalRate(ctx, base, target, day) })
+}
+
+func (b *CircuitBreaker) call(fetch func() (float64, error)) (float64, error) {
+	if !b.allow() {
+		return 0, ErrCircuitOpen
+	}
+	rate, err := fetch()
+	b.record(err)
+	return rate, err
+}
+
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = CircuitHalfOpen
+		b.trial = true
+		return true
+	case CircuitHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	// A rate limited response means the upstream is up, just busy; backing
+	// off is left to Throttle.
+	var limited *RateLimitedError
+	if err == nil || errors.As(err, &limited) {
+		b.state = CircuitClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.state = CircuitOpen
+		b.openedAt = b.now()
+	}
+}
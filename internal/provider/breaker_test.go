@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type stubProvider struct {
+	calls int
+	err   error
+}
+
+func (s *stubProvider) Name() string { return "stub" }
+
+func (s *stubProvider) FetchRate(ctx context.Context, base, target string) (float64, error) {
+	s.calls++
+	return 1, s.err
+}
+
+func (s *stubProvider) FetchHistoricalRate(ctx context.Context, base, target string, day time.Time) (float64, error) {
+	return s.FetchRate(ctx, base, target)
+}
+
+func TestCircuitBreaker_OpensAndRecovers(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	stub := &stubProvider{err: errors.New("upstream down")}
+	b := NewCircuitBreaker(stub, 3, time.Minute)
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		b.FetchRate(ctx, "USD", "EUR")
+	}
+	if b.State() != CircuitOpen {
+		t.Fatalf("expected open circuit, got %s", b.State())
+	}
+	if _, err := b.FetchRate(ctx, "USD", "EUR"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if stub.calls != 3 {
+		t.Errorf("open circuit must not call provider, got %d calls", stub.calls)
+	}
+
+	now = now.Add(time.Minute)
+	if b.State() != CircuitHalfOpen {
+		t.Fatalf("expected half-open circuit, got %s", b.State())
+	}
+	b.FetchRate(ctx, "USD", "EUR")
+	if b.State() != CircuitOpen {
+		t.Fatalf("failed trial should re-open circuit, got %s", b.State())
+	}
+
+	now = now.Add(time.Minute)
+	stub.err = nil
+	if _, err := b.FetchRate(ctx, "USD", "EUR"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.State() != CircuitClosed {
+		t.Fatalf("successful trial should close circuit, got %s", b.State())
+	}
+}
@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"FinQuotesService/internal/logging"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Provider fetches the rate of base expressed in target currency, either the
+// current one or the one published for a past UTC day.
+type Provider interface {
+	Name() string
+	FetchRate(ctx context.Context, base, target string) (float64, error)
+	FetchHistoricalRate(ctx context.Context, base, target string, day time.Time) (float64, error)
+}
+
+// RateLimitedError is returned when the upstream rejects a request because of
+// its own limits. RetryAfter is zero when the response didn't say.
+type RateLimitedError struct {
+	Status     int
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("upstream rate limited (%d), retry after %s", e.Status, e.RetryAfter)
+	}
+	return fmt.Sprintf("upstream rate limited (%d)", e.Status)
+}
+
+// parseRetryAfter reads a Retry-After value given either in seconds or as an
+// HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(0, t.Sub(now)), true
+	}
+	return 0, false
+}
+
+type ratesResponse struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+type Vatcomply struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewVatcomply(baseURL string, timeout time.Duration) *Vatcomply {
+	return &Vatcomply{
+		BaseURL: baseURL,
+		Client: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+func (v *Vatcomply) Name() string {
+	return "vatcomply"
+}
+
+func (v *Vatcomply) FetchRate(ctx context.Context, base, target string) (float64, error) {
+	return v.fetch(ctx, fmt.Sprintf("%s/rates?base=%s", v.BaseURL, base), base, target)
+}
+
+// FetchHistoricalRate returns the reference rate of day. For days without a
+// publication, such as weekends, vatcomply answers with the last rate
+// published before.
+func (v *Vatcomply) FetchHistoricalRate(ctx context.Context, base, target string, day time.Time) (float64, error) {
+	return v.fetch(ctx, fmt.Sprintf("%s/rates?base=%s&date=%s", v.BaseURL, base, day.UTC().Format(time.DateOnly)), base, target)
+}
+
+func (v *Vatcomply) fetch(ctx context.Context, url, base, target string) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Set(logging.RequestIDHeader, id)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	start := time.Now()
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		slog.WarnContext(ctx, "provider request failed", "component", "provider", "provider", v.Name(), "url", url, "error", err)
+		return 0, err
+	}
+	defer resp.Body.Close()
+	slog.InfoContext(ctx, "provider request", "component", "provider", "provider", v.Name(), "url", url, "status", resp.StatusCode, "duration_ms", time.Since(start).Milliseconds())
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") != "" {
+		retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		return 0, &RateLimitedError{Status: resp.StatusCode, RetryAfter: retryAfter}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("fetcher: http error: %v", resp.Status)
+	}
+
+	var r ratesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return 0, err
+	}
+
+	rate, ok := r.Rates[target]
+	if !ok {
+		return 0, fmt.Errorf("no rate found for %s/%s", base, target)
+	}
+
+	return rate, nil
+}
@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"FinQuotesService/internal/logging"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVatcomply_FetchRate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rates" || r.URL.Query().Get("base") != "USD" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		w.Write([]byte(`{"base":"USD","rates":{"EUR":0.92,"MXN":17.1}}`))
+	}))
+	defer ts.Close()
+
+	v := NewVatcomply(ts.URL, time.Second)
+	rate, err := v.FetchRate(context.Background(), "USD", "EUR")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rate != 0.92 {
+		t.Errorf("expected 0.92, got %v", rate)
+	}
+
+	if _, err := v.FetchRate(context.Background(), "USD", "GBP"); err == nil {
+		t.Error("expected error for missing rate")
+	}
+}
+
+func TestVatcomply_FetchHistoricalRate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("base") != "USD" || r.URL.Query().Get("date") != "2024-03-01" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		w.Write([]byte(`{"base":"USD","date":"2024-03-01","rates":{"EUR":0.925}}`))
+	}))
+	defer ts.Close()
+
+	v := NewVatcomply(ts.URL, time.Second)
+	rate, err := v.FetchHistoricalRate(context.Background(), "USD", "EUR", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rate != 0.925 {
+		t.Errorf("expected 0.925, got %v", rate)
+	}
+}
+
+func TestVatcomply_HTTPError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	v := NewVatcomply(ts.URL, time.Second)
+	if _, err := v.FetchRate(context.Background(), "USD", "EUR"); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestVatcomply_ForwardsRequestID(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(logging.RequestIDHeader)
+		w.Write([]byte(`{"base":"USD","rates":{"EUR":0.92}}`))
+	}))
+	defer ts.Close()
+
+	v := NewVatcomply(ts.URL, time.Second)
+	ctx := logging.WithRequestID(context.Background(), "req-7")
+	if _, err := v.FetchRate(ctx, "USD", "EUR"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "req-7" {
+		t.Errorf("expected request id req-7 upstream, got %q", got)
+	}
+}
+
+func TestVatcomply_RateLimited(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "12")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	v := NewVatcomply(ts.URL, time.Second)
+	_, err := v.FetchRate(context.Background(), "USD", "EUR")
+	var limited *RateLimitedError
+	if !errors.As(err, &limited) {
+		t.Fatalf("expected RateLimitedError, got %v", err)
+	}
+	if limited.RetryAfter != 12*time.Second {
+		t.Errorf("expected Retry-After 12s, got %v", limited.RetryAfter)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{now.Add(time.Minute).Format(http.TimeFormat), time.Minute, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
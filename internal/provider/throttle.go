@@ -0,0 +1,172 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// defaultRetryAfter is used when the upstream rate limits us without saying
+// for how long.
+const defaultRetryAfter = time.Minute
+
+// DeferredError asks the caller to retry after Until instead of failing the
+// request: the provider is out of quota or asked us to back off.
+type DeferredError struct {
+	Until  time.Time
+	Reason string
+}
+
+func (e *DeferredError) Error() string {
+	return fmt.Sprintf("provider call deferred until %s: %s", e.Until.Format(time.RFC3339), e.Reason)
+}
+
+// QuotaStore counts upstream calls per provider and UTC day, so the daily
+// quota survives restarts and is shared by all instances using the store.
+type QuotaStore interface {
+	// UseQuota counts one call unless limit calls were already made that day,
+	// and reports whether the call may proceed.
+	UseQuota(provider, day string, limit int) (bool, error)
+	GetQuotaUsed(provider, day string) (int, error)
+}
+
+type ThrottleOptions struct {
+	// RequestsPerSecond paces calls, zero disables pacing. Burst calls may be
+	// made back to back after an idle period.
+	RequestsPerSecond float64
+	Burst             int
+	// DailyQuota caps the calls per UTC day, zero means unlimited.
+	DailyQuota int
+	Quota      QuotaStore
+}
+
+// Throttle keeps calls to a provider within its published limits. Callers
+// wait for their turn under the request rate; when the daily quota is used up
+// or the upstream answered with 429, calls fail fast with *DeferredError.
+type Throttle struct {
+	Provider
+	opts ThrottleOptions
+
+	mu           sync.Mutex
+	next         time.Time
+	blockedUntil time.Time
+	now          func() time.Time
+}
+
+func NewThrottle(p Provider, opts ThrottleOptions) *Throttle {
+	return &Throttle{
+		Provider: p,
+		opts:     opts,
+		now:      time.Now,
+	}
+}
+
+func (t *Throttle) FetchRate(ctx context.Context, base, target string) (float64, error) {
+	return t.call(ctx, func() (float64, error) { return t.Provider.FetchRate(ctx, base, target) })
+}
+
+// FetchHistoricalRate shares the pacing and quota of FetchRate.
+func (t *Throttle) FetchHistoricalRate(ctx context.Context, base, target string, day time.Time) (float64, error) {
+	return t.call(ctx, func() (float64, error) { return t.Provider.FetchHistoricalRate(ctx, base, target, day) })
+}
+
+func (t *Throttle) call(ctx context.Context, fetch func() (float64, error)) (float64, error) {
+	if err := t.blocked(); err != nil {
+		return 0, err
+	}
+	if err := t.wait(ctx); err != nil {
+		return 0, err
+	}
+	if err := t.useQuota(ctx); err != nil {
+		return 0, err
+	}
+	rate, err := fetch()
+	var limited *RateLimitedError
+	if errors.As(err, &limited) {
+		retryAfter := limited.RetryAfter
+		if retryAfter <= 0 {
+			retryAfter = defaultRetryAfter
+		}
+		until := t.block(retryAfter)
+		slog.WarnContext(ctx, "provider rate limited us", "component", "provider", "provider", t.Name(), "retry_after", retryAfter.String())
+		return 0, &DeferredError{Until: until, Reason: limited.Error()}
+	}
+	return rate, err
+}
+
+// QuotaUsed returns the calls counted today, for monitoring.
+func (t *Throttle) QuotaUsed() (int, error) {
+	if t.opts.Quota == nil {
+		return 0, nil
+	}
+	return t.opts.Quota.GetQuotaUsed(t.Name(), t.now().UTC().Format(time.DateOnly))
+}
+
+func (t *Throttle) blocked() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.now().Before(t.blockedUntil) {
+		return &DeferredError{Until: t.blockedUntil, Reason: "upstream asked to back off"}
+	}
+	return nil
+}
+
+func (t *Throttle) block(d time.Duration) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until := t.now().Add(d); until.After(t.blockedUntil) {
+		t.blockedUntil = until
+	}
+	return t.blockedUntil
+}
+
+// wait reserves the next call slot and sleeps until it comes. Slots are
+// spaced 1/RequestsPerSecond apart, with up to Burst slots banked.
+func (t *Throttle) wait(ctx context.Context) error {
+	if t.opts.RequestsPerSecond <= 0 {
+		return nil
+	}
+	interval := time.Duration(float64(time.Second) / t.opts.RequestsPerSecond)
+	t.mu.Lock()
+	now := t.now()
+	if earliest := now.Add(-time.Duration(max(t.opts.Burst-1, 0)) * interval); t.next.Before(earliest) {
+		t.next = earliest
+	}
+	slot := t.next
+	t.next = t.next.Add(interval)
+	t.mu.Unlock()
+
+	delay := slot.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (t *Throttle) useQuota(ctx context.Context) error {
+	if t.opts.DailyQuota <= 0 || t.opts.Quota == nil {
+		return nil
+	}
+	now := t.now().UTC()
+	ok, err := t.opts.Quota.UseQuota(t.Name(), now.Format(time.DateOnly), t.opts.DailyQuota)
+	if err != nil {
+		// Losing count for a while is better than stopping all updates.
+		slog.WarnContext(ctx, "quota store failed", "component", "provider", "provider", t.Name(), "error", err)
+		return nil
+	}
+	if !ok {
+		tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		return &DeferredError{Until: tomorrow, Reason: fmt.Sprintf("daily quota of %d calls is used up", t.opts.DailyQuota)}
+	}
+	return nil
+}
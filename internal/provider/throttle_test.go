@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"FinQuotesService/internal/repository/memory"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestThrottle_DailyQuota(t *testing.T) {
+	now := time.Date(2025, 1, 1, 23, 0, 0, 0, time.UTC)
+	stub := &stubProvider{}
+	quota := memory.NewProviderQuotaRepository()
+	th := NewThrottle(stub, ThrottleOptions{DailyQuota: 2, Quota: quota})
+	th.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := th.FetchRate(ctx, "USD", "EUR"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	_, err := th.FetchRate(ctx, "USD", "EUR")
+	var deferred *DeferredError
+	if !errors.As(err, &deferred) {
+		t.Fatalf("expected DeferredError, got %v", err)
+	}
+	if want := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC); !deferred.Until.Equal(want) {
+		t.Errorf("expected deferral until %v, got %v", want, deferred.Until)
+	}
+	if stub.calls != 2 {
+		t.Errorf("exhausted quota must not call provider, got %d calls", stub.calls)
+	}
+	if used, _ := th.QuotaUsed(); used != 2 {
+		t.Errorf("expected 2 calls used, got %d", used)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := th.FetchRate(ctx, "USD", "EUR"); err != nil {
+		t.Errorf("quota must reset on the next day: %v", err)
+	}
+}
+
+func TestThrottle_RetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	stub := &stubProvider{err: &RateLimitedError{Status: 429, RetryAfter: 30 * time.Second}}
+	th := NewThrottle(stub, ThrottleOptions{})
+	th.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := th.FetchRate(ctx, "USD", "EUR")
+	var deferred *DeferredError
+	if !errors.As(err, &deferred) || !deferred.Until.Equal(now.Add(30*time.Second)) {
+		t.Fatalf("expected deferral for 30s, got %v", err)
+	}
+	stub.err = nil
+	if _, err := th.FetchRate(ctx, "USD", "EUR"); !errors.As(err, &deferred) {
+		t.Fatalf("expected calls to stay blocked, got %v", err)
+	}
+	if stub.calls != 1 {
+		t.Errorf("blocked throttle must not call provider, got %d calls", stub.calls)
+	}
+	now = now.Add(30 * time.Second)
+	if _, err := th.FetchRate(ctx, "USD", "EUR"); err != nil {
+		t.Errorf("unexpected error after Retry-After: %v", err)
+	}
+}
+
+func TestThrottle_Pacing(t *testing.T) {
+	stub := &stubProvider{}
+	th := NewThrottle(stub, ThrottleOptions{RequestsPerSecond: 50, Burst: 2})
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := th.FetchRate(ctx, "USD", "EUR"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// Two calls go out at once, the next two are 20ms apart.
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("expected calls to be paced, took %v", elapsed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	th.FetchRate(ctx, "USD", "EUR")
+	if _, err := th.FetchRate(cancelled, "USD", "EUR"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled while waiting, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_IgnoresRateLimits(t *testing.T) {
+	stub := &stubProvider{err: &RateLimitedError{Status: 429}}
+	b := NewCircuitBreaker(stub, 2, time.Minute)
+	for i := 0; i < 5; i++ {
+		b.FetchRate(context.Background(), "USD", "EUR")
+	}
+	if b.State() != CircuitClosed {
+		t.Errorf("rate limited responses must not open the circuit, got %s", b.State())
+	}
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Limit allows Burst requests at once, refilled at Rate tokens per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Per builds a limit of n requests per period.
+func Per(n int, period time.Duration, burst int) Limit {
+	return Limit{Rate: float64(n) / period.Seconds(), Burst: burst}
+}
+
+// Window is the time an empty bucket takes to fill up again.
+func (l Limit) Window() time.Duration {
+	return seconds(float64(l.Burst) / l.Rate)
+}
+
+// Result describes the bucket after a request was counted.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token, zero when allowed.
+	RetryAfter time.Duration
+}
+
+// Bucket is the token bucket state shared by all stores. A zero Bucket is
+// treated as full.
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// Take refills the bucket up to now and consumes n tokens if that many are
+// available. Denied requests don't consume anything.
+func (b *Bucket) Take(limit Limit, n int, now time.Time) Result {
+	burst := float64(limit.Burst)
+	if b.UpdatedAt.IsZero() {
+		b.Tokens = burst
+	} else if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(burst, b.Tokens+elapsed*limit.Rate)
+	}
+	b.UpdatedAt = now
+
+	res := Result{Allowed: b.Tokens >= float64(n)}
+	if res.Allowed {
+		b.Tokens -= float64(n)
+	} else {
+		res.RetryAfter = seconds((float64(n) - b.Tokens) / limit.Rate)
+	}
+	res.Remaining = int(math.Floor(b.Tokens))
+	res.Reset = seconds((burst - b.Tokens) / limit.Rate)
+	return res
+}
+
+// Full reports whether the bucket would be full at now, so it can be dropped
+// without changing behaviour.
+func (b *Bucket) Full(limit Limit, now time.Time) bool {
+	return b.Tokens+now.Sub(b.UpdatedAt).Seconds()*limit.Rate >= float64(limit.Burst)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
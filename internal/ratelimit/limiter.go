@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"FinQuotesService/internal/auth"
+	"FinQuotesService/internal/metrics"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limiter applies per-route token buckets to every client. Authenticated
+// clients are identified by API key, anonymous ones by IP address.
+type Limiter struct {
+	Store  Store
+	Routes map[string]Limit
+	// TrustForwardedFor takes the client IP from X-Forwarded-For, which is
+	// only safe behind a proxy that overwrites the header.
+	TrustForwardedFor bool
+	now               func() time.Time
+}
+
+func NewLimiter(store Store, routes map[string]Limit) *Limiter {
+	return &Limiter{
+		Store:  store,
+		Routes: routes,
+		now:    time.Now,
+	}
+}
+
+// Wrap limits next with the limit configured for route. Routes without a
+// limit are returned unchanged. Wrap it inside auth.Require so rejected
+// requests don't spend the client's tokens.
+func (l *Limiter) Wrap(route string, next http.HandlerFunc) http.HandlerFunc {
+	limit, ok := l.Routes[route]
+	if !ok {
+		return next
+	}
+	policy := strconv.Itoa(limit.Burst) + ";w=" + strconv.Itoa(ceilSeconds(limit.Window()))
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := l.Store.Take(r.Context(), route+"|"+l.Client(r), limit, 1, l.now())
+		if err != nil {
+			// Failing open keeps the API available when the shared store is down.
+			slog.WarnContext(r.Context(), "rate limit store failed", "component", "ratelimit", "route", route, "error", err)
+			next(w, r)
+			return
+		}
+		h := w.Header()
+		h.Set("RateLimit-Policy", policy)
+		h.Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		if !res.Allowed {
+			metrics.RateLimited.WithLabelValues(route).Inc()
+			h.Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(res.RetryAfter))))
+			tooManyRequests(w)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Allow counts n calls of client, as returned by ClientKey, against the
+// limit of route, for callers that are not HTTP handlers. The calls pass
+// together or not at all: a rejection spends no tokens, so n above the
+// burst of the limit never passes. Routes without a limit and store
+// failures let every call through, as in Wrap.
+func (l *Limiter) Allow(ctx context.Context, route, client string, n int) Result {
+	limit, ok := l.Routes[route]
+	if !ok {
+		return Result{Allowed: true}
+	}
+	res, err := l.Store.Take(ctx, route+"|"+client, limit, n, l.now())
+	if err != nil {
+		slog.WarnContext(ctx, "rate limit store failed", "component", "ratelimit", "route", route, "error", err)
+		return Result{Allowed: true}
+	}
+	if !res.Allowed {
+		metrics.RateLimited.WithLabelValues(route).Inc()
+	}
+	return res
+}
+
+// Client returns the key Wrap counts the requests of r under, for callers
+// that go on limiting a client past the request, e.g. per WebSocket message.
+func (l *Limiter) Client(r *http.Request) string {
+	if l.TrustForwardedFor && auth.KeyID(r.Context()) == "" {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			ip, _, _ := strings.Cut(fwd, ",")
+			return "ip:" + strings.TrimSpace(ip)
+		}
+	}
+	return ClientKey(r.Context(), r.RemoteAddr)
+}
+
+// ClientKey identifies a client by the API key in ctx, or by the host of its
+// remote address addr for anonymous calls.
+func ClientKey(ctx context.Context, addr string) string {
+	if id := auth.KeyID(ctx); id != "" {
+		return "key:" + id
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// tooManyRequests uses the same body shape as the API handlers.
+func tooManyRequests(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	err := json.NewEncoder(w).Encode(map[string]string{"error_message": "Too many requests"})
+	if err != nil {
+		return
+	}
+}
@@ -0,0 +1,188 @@
+package ratelimit
+
+import (
+	"FinQuotesService/internal/auth"
+	"FinQuotesService/internal/model"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBucket_Take(t *testing.T) {
+	limit := Per(60, time.Minute, 2)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var b Bucket
+
+	for i, want := range []bool{true, true, false} {
+		res := b.Take(limit, 1, start)
+		if res.Allowed != want {
+			t.Fatalf("request %d: expected allowed=%v", i, want)
+		}
+	}
+	res := b.Take(limit, 1, start)
+	if res.Remaining != 0 || res.RetryAfter != time.Second || res.Reset != 2*time.Second {
+		t.Errorf("unexpected result: %+v", res)
+	}
+
+	res = b.Take(limit, 1, start.Add(1500*time.Millisecond))
+	if !res.Allowed || res.Remaining != 0 {
+		t.Errorf("expected a refilled token, got %+v", res)
+	}
+	res = b.Take(limit, 1, start.Add(time.Hour))
+	if !res.Allowed || res.Remaining != 1 {
+		t.Errorf("refill must be capped at burst, got %+v", res)
+	}
+	res = b.Take(limit, 2, start.Add(time.Hour))
+	if res.Allowed || res.Remaining != 1 || res.RetryAfter != time.Second {
+		t.Errorf("expected 2 tokens to be denied without spending any, got %+v", res)
+	}
+	res = b.Take(limit, 1, start.Add(time.Hour))
+	if !res.Allowed || res.Remaining != 0 {
+		t.Errorf("expected the remaining token to be taken, got %+v", res)
+	}
+}
+
+func TestMemoryStore_KeysAreIndependent(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Per(1, time.Minute, 1)
+	now := time.Now()
+	ctx := context.Background()
+	if res, _ := s.Take(ctx, "a", limit, 1, now); !res.Allowed {
+		t.Fatal("expected first request of a to be allowed")
+	}
+	if res, _ := s.Take(ctx, "a", limit, 1, now); res.Allowed {
+		t.Fatal("expected second request of a to be denied")
+	}
+	if res, _ := s.Take(ctx, "b", limit, 1, now); !res.Allowed {
+		t.Fatal("expected first request of b to be allowed")
+	}
+	if s.Len() != 2 {
+		t.Errorf("expected 2 buckets, got %d", s.Len())
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit Limit, n int, now time.Time) (Result, error) {
+	return Result{}, errors.New("store down")
+}
+
+func TestLimiter_Wrap(t *testing.T) {
+	l := NewLimiter(NewMemoryStore(), map[string]Limit{"/quotes/update": Per(6, time.Minute, 2)})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	handler := l.Wrap("/quotes/update", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	do := func(remoteAddr string, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/quotes/update", nil)
+		req.RemoteAddr = remoteAddr
+		if key != "" {
+			req = req.WithContext(auth.WithKey(req.Context(), model.APIKey{ID: key}))
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := do("10.0.0.1:1234", ""); w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200, got %d", i, w.Code)
+		}
+	}
+	w := do("10.0.0.1:5678", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", w.Code)
+	}
+	for header, want := range map[string]string{
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "20",
+		"RateLimit-Policy":    "2;w=20",
+		"Retry-After":         "10",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("expected %s %q, got %q", header, want, got)
+		}
+	}
+
+	if w := do("10.0.0.2:1234", ""); w.Code != http.StatusOK {
+		t.Errorf("other IP must have its own bucket, got %d", w.Code)
+	}
+	if w := do("10.0.0.1:1234", "key-1"); w.Code != http.StatusOK {
+		t.Errorf("API key must have its own bucket, got %d", w.Code)
+	}
+
+	now = now.Add(10 * time.Second)
+	if w := do("10.0.0.1:1234", ""); w.Code != http.StatusOK {
+		t.Errorf("expected refill after Retry-After, got %d", w.Code)
+	}
+}
+
+func TestLimiter_UnlimitedRouteAndStoreFailure(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	l := NewLimiter(failingStore{}, map[string]Limit{"/quotes/update": Per(1, time.Minute, 1)})
+
+	w := httptest.NewRecorder()
+	l.Wrap("/quotes/last/", ok)(w, httptest.NewRequest(http.MethodGet, "/quotes/last/USD/EUR", nil))
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("route without a limit must not be touched: %d %v", w.Code, w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	l.Wrap("/quotes/update", ok)(w, httptest.NewRequest(http.MethodPost, "/quotes/update", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected fail open on store error, got %d", w.Code)
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	l := NewLimiter(NewMemoryStore(), map[string]Limit{"/quotes/update": Per(6, time.Minute, 3)})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	ctx := auth.WithKey(context.Background(), model.APIKey{ID: "key-1"})
+	client := ClientKey(ctx, "10.0.0.1:1234")
+	if client != "key:key-1" {
+		t.Fatalf("expected the client to be identified by its key, got %s", client)
+	}
+
+	if res := l.Allow(ctx, "/quotes/update", client, 2); !res.Allowed || res.Remaining != 1 {
+		t.Fatalf("expected 2 calls to pass, got %+v", res)
+	}
+	if res := l.Allow(ctx, "/quotes/update", client, 2); res.Allowed || res.Remaining != 1 || res.RetryAfter != 10*time.Second {
+		t.Fatalf("expected the second call to be limited without spending tokens, got %+v", res)
+	}
+	if res := l.Allow(ctx, "/quotes/update", client, 1); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expected the remaining token to be taken, got %+v", res)
+	}
+	if res := l.Allow(ctx, "/quotes/update", client, 1); res.Allowed {
+		t.Fatalf("expected the empty bucket to limit, got %+v", res)
+	}
+	if res := l.Allow(ctx, "/quotes/update", ClientKey(context.Background(), "10.0.0.1:1234"), 1); !res.Allowed {
+		t.Errorf("anonymous client must have its own bucket, got %+v", res)
+	}
+	if res := l.Allow(ctx, "/quotes/last/{base}/{quote}", client, 100); !res.Allowed {
+		t.Errorf("route without a limit must not be limited, got %+v", res)
+	}
+	if res := NewLimiter(failingStore{}, l.Routes).Allow(ctx, "/quotes/update", client, 1); !res.Allowed {
+		t.Errorf("expected fail open on store error, got %+v", res)
+	}
+}
+
+func TestLimiter_TrustForwardedFor(t *testing.T) {
+	l := NewLimiter(NewMemoryStore(), nil)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	if got := l.Client(req); got != "ip:10.0.0.1" {
+		t.Errorf("forwarded header must be ignored by default, got %s", got)
+	}
+	l.TrustForwardedFor = true
+	if got := l.Client(req); got != "ip:203.0.113.7" {
+		t.Errorf("expected client from X-Forwarded-For, got %s", got)
+	}
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps buckets by key. MemoryStore is per instance; a shared store
+// such as postgres.RateLimitStore makes the limit global across replicas.
+// Take consumes n tokens of the bucket at once or none at all.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, n int, now time.Time) (Result, error)
+}
+
+// cleanupEvery is the number of Take calls between sweeps of full buckets.
+const cleanupEvery = 1000
+
+type memoryEntry struct {
+	bucket Bucket
+	limit  Limit
+}
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryEntry
+	calls   int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryEntry)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, n int, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.calls%cleanupEvery == 0 {
+		for k, e := range s.buckets {
+			if e.bucket.Full(e.limit, now) {
+				delete(s.buckets, k)
+			}
+		}
+	}
+	e, ok := s.buckets[key]
+	if !ok {
+		e = &memoryEntry{}
+		s.buckets[key] = e
+	}
+	e.limit = limit
+	return e.bucket.Take(limit, n, now), nil
+}
+
+// Len returns the number of tracked buckets.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"FinQuotesService/internal/model"
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var errDuplicateKey = errors.New("api key already exists")
+
+// APIKeyRepository keeps API keys in process memory, so keys only live as
+// long as the process.
+type APIKeyRepository struct {
+	mu   sync.RWMutex
+	keys map[string]model.APIKey
+}
+
+func NewAPIKeyRepository() *APIKeyRepository {
+	return &APIKeyRepository{keys: make(map[string]model.APIKey)}
+}
+
+func (r *APIKeyRepository) CreateAPIKey(name, hash string, scopes []model.Scope) (model.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range r.keys {
+		if k.Hash == hash {
+			return model.APIKey{}, errDuplicateKey
+		}
+	}
+	k := model.APIKey{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Hash:      hash,
+		Scopes:    append([]model.Scope(nil), scopes...),
+		CreatedAt: time.Now().UTC(),
+	}
+	r.keys[k.ID] = k
+	return copyAPIKey(k), nil
+}
+
+func (r *APIKeyRepository) GetAPIKeyByHash(hash string) (model.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, k := range r.keys {
+		if k.Hash == hash {
+			return copyAPIKey(k), nil
+		}
+	}
+	return model.APIKey{}, sql.ErrNoRows
+}
+
+func (r *APIKeyRepository) ListAPIKeys() ([]model.APIKey, error) {
+	r.mu.RLock()
+	keys := make([]model.APIKey, 0, len(r.keys))
+	for _, k := range r.keys {
+		keys = append(keys, copyAPIKey(k))
+	}
+	r.mu.RUnlock()
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+func (r *APIKeyRepository) RevokeAPIKey(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, ok := r.keys[id]
+	if !ok || k.Revoked() {
+		return sql.ErrNoRows
+	}
+	now := time.Now().UTC()
+	k.RevokedAt = &now
+	r.keys[id] = k
+	return nil
+}
+
+func copyAPIKey(k model.APIKey) model.APIKey {
+	k.Scopes = append([]model.Scope(nil), k.Scopes...)
+	if k.RevokedAt != nil {
+		revokedAt := *k.RevokedAt
+		k.RevokedAt = &revokedAt
+	}
+	return k
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"FinQuotesService/internal/model"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BackfillRepository struct {
+	mu        sync.Mutex
+	backfills map[string]model.Backfill
+	// leases holds when the lease of each owned backfill expires.
+	leases map[string]time.Time
+}
+
+func NewBackfillRepository() *BackfillRepository {
+	return &BackfillRepository{backfills: make(map[string]model.Backfill), leases: make(map[string]time.Time)}
+}
+
+func (r *BackfillRepository) CreateBackfill(b model.Backfill) (model.Backfill, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b.ID = uuid.New().String()
+	r.backfills[b.ID] = b
+	return b, nil
+}
+
+func (r *BackfillRepository) GetBackfill(id string) (model.Backfill, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.backfills[id]
+	if !ok {
+		return model.Backfill{}, sql.ErrNoRows
+	}
+	return b, nil
+}
+
+func (r *BackfillRepository) ListBackfills() ([]model.Backfill, error) {
+	r.mu.Lock()
+	backfills := make([]model.Backfill, 0, len(r.backfills))
+	for _, b := range r.backfills {
+		backfills = append(backfills, b)
+	}
+	r.mu.Unlock()
+	sort.Slice(backfills, func(i, j int) bool {
+		if !backfills[i].CreatedAt.Equal(backfills[j].CreatedAt) {
+			return backfills[i].CreatedAt.Before(backfills[j].CreatedAt)
+		}
+		return backfills[i].ID < backfills[j].ID
+	})
+	return backfills, nil
+}
+
+func (r *BackfillRepository) ClaimBackfill(id, owner string, now, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.backfills[id]
+	if !ok || !stored.Active() || stored.Owner != "" && stored.Owner != owner && !r.leases[id].Before(now) {
+		return sql.ErrNoRows
+	}
+	stored.Owner = owner
+	r.backfills[id] = stored
+	r.leases[id] = until
+	return nil
+}
+
+func (r *BackfillRepository) UpdateBackfill(b model.Backfill) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.backfills[b.ID]
+	if !ok || stored.Owner != b.Owner {
+		return sql.ErrNoRows
+	}
+	if !b.Active() {
+		stored.Owner = ""
+		delete(r.leases, b.ID)
+	}
+	stored.Next = b.Next
+	stored.Status = b.Status
+	stored.Inserted = b.Inserted
+	stored.Duplicates = b.Duplicates
+	stored.Error = b.Error
+	stored.UpdatedAt = b.UpdatedAt
+	r.backfills[b.ID] = stored
+	return nil
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"FinQuotesService/internal/model"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+type idempotencyKey struct {
+	clientID string
+	key      string
+}
+
+type IdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[idempotencyKey]model.IdempotencyRecord
+}
+
+func NewIdempotencyRepository() *IdempotencyRepository {
+	return &IdempotencyRepository{records: make(map[idempotencyKey]model.IdempotencyRecord)}
+}
+
+func (r *IdempotencyRepository) SaveIdempotencyRecord(rec model.IdempotencyRecord, notBefore time.Time) (model.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k := idempotencyKey{rec.ClientID, rec.Key}
+	if existing, ok := r.records[k]; ok && !existing.CreatedAt.Before(notBefore) {
+		return existing, nil
+	}
+	rec.CreatedAt = rec.CreatedAt.UTC()
+	r.records[k] = rec
+	return rec, nil
+}
+
+func (r *IdempotencyRepository) GetIdempotencyRecord(clientID, key string, notBefore time.Time) (model.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec, ok := r.records[idempotencyKey{clientID, key}]
+	if !ok || rec.CreatedAt.Before(notBefore) {
+		return model.IdempotencyRecord{}, sql.ErrNoRows
+	}
+	return rec, nil
+}
+
+func (r *IdempotencyRepository) DeleteIdempotencyRecords(before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for k, rec := range r.records {
+		if rec.CreatedAt.Before(before) {
+			delete(r.records, k)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,283 @@
+package memory
+
+import (
+	"FinQuotesService/internal/model"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QuoteRepository keeps quotes in process memory. It mirrors the Postgres
+// behaviour, including at most one pending quote per currency, and is meant
+// for tests and local demos without a database.
+type QuoteRepository struct {
+	mu      sync.RWMutex
+	quotes  map[string]model.Quote
+	pending map[string]string
+	// historical holds the quotes stored with a source, by currency and
+	// update time.
+	historical map[historicalKey]string
+	// claims holds the claims on pending quotes by ID. A claim without an
+	// owner marks a quote seen unclaimed.
+	claims map[string]claim
+}
+
+type claim struct {
+	owner string
+	until time.Time
+}
+
+type historicalKey struct {
+	currency  string
+	updatedAt int64
+}
+
+func NewQuoteRepository() *QuoteRepository {
+	return &QuoteRepository{
+		quotes:     make(map[string]model.Quote),
+		pending:    make(map[string]string),
+		historical: make(map[historicalKey]string),
+		claims:     make(map[string]claim),
+	}
+}
+
+func (r *QuoteRepository) InsertPendingQuote(currency, requestedBy string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.pending[currency]; exists {
+		return "", sql.ErrNoRows
+	}
+	id := uuid.New().String()
+	q := model.Quote{ID: id, Currency: currency, Status: model.StatusPending}
+	if requestedBy != "" {
+		q.RequestedBy = &requestedBy
+	}
+	r.quotes[id] = q
+	r.pending[currency] = id
+	return id, nil
+}
+
+func (r *QuoteRepository) UpdateQuote(id string, price float64, status model.Status) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	q, ok := r.quotes[id]
+	if !ok {
+		return nil
+	}
+	now := time.Now().UTC()
+	q.Price = &price
+	q.UpdatedAt = &now
+	if q.Status == model.StatusPending && status != model.StatusPending {
+		delete(r.pending, q.Currency)
+		delete(r.claims, id)
+	}
+	q.Status = status
+	r.quotes[id] = q
+	return nil
+}
+
+func (r *QuoteRepository) GetQuoteById(id string) (model.Quote, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	q, ok := r.quotes[id]
+	if !ok {
+		return model.Quote{}, sql.ErrNoRows
+	}
+	return copyQuote(q), nil
+}
+
+func (r *QuoteRepository) GetLastQuote(currency string, status model.Status) (model.Quote, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var last *model.Quote
+	for _, q := range r.quotes {
+		if q.Currency != currency || q.Status != status {
+			continue
+		}
+		if last == nil || newer(q, *last) {
+			q := q
+			last = &q
+		}
+	}
+	if last == nil {
+		return model.Quote{}, sql.ErrNoRows
+	}
+	return copyQuote(*last), nil
+}
+
+func (r *QuoteRepository) GetCandles(currency string, interval time.Duration, from, to time.Time) ([]model.Candle, error) {
+	r.mu.RLock()
+	var quotes []model.Quote
+	for _, q := range r.quotes {
+		if q.Currency != currency || q.Status != model.StatusDone || q.Price == nil || q.UpdatedAt == nil {
+			continue
+		}
+		if q.UpdatedAt.Before(from) || !q.UpdatedAt.Before(to) {
+			continue
+		}
+		quotes = append(quotes, q)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(quotes, func(i, j int) bool { return quotes[i].UpdatedAt.Before(*quotes[j].UpdatedAt) })
+	seconds := int64(interval / time.Second)
+	candles := make([]model.Candle, 0)
+	for _, q := range quotes {
+		unix := q.UpdatedAt.Unix()
+		bucketStart := unix - unix%seconds
+		if unix < 0 && unix%seconds != 0 {
+			bucketStart -= seconds
+		}
+		bucket := time.Unix(bucketStart, 0).UTC()
+		price := *q.Price
+		if n := len(candles); n > 0 && candles[n-1].Bucket.Equal(bucket) {
+			c := &candles[n-1]
+			c.High = max(c.High, price)
+			c.Low = min(c.Low, price)
+			c.Close = price
+			c.Count++
+			continue
+		}
+		candles = append(candles, model.Candle{Bucket: bucket, Open: price, High: price, Low: price, Close: price, Count: 1})
+	}
+	return candles, nil
+}
+
+func (r *QuoteRepository) ClaimQuote(id, owner string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	q, ok := r.quotes[id]
+	if !ok || q.Status != model.StatusPending || r.claims[id].owner != "" {
+		return sql.ErrNoRows
+	}
+	r.claims[id] = claim{owner: owner, until: until}
+	return nil
+}
+
+func (r *QuoteRepository) ClaimPendingQuotes(owner string, now, until time.Time) ([]model.Quote, error) {
+	r.mu.Lock()
+	quotes := make([]model.Quote, 0)
+	for _, id := range r.pending {
+		c, ok := r.claims[id]
+		switch {
+		case !ok:
+			r.claims[id] = claim{until: now}
+		case c.owner == owner:
+			r.claims[id] = claim{owner: owner, until: until}
+		case c.until.Before(now):
+			r.claims[id] = claim{owner: owner, until: until}
+			quotes = append(quotes, copyQuote(r.quotes[id]))
+		}
+	}
+	r.mu.Unlock()
+	sort.Slice(quotes, func(i, j int) bool { return quotes[i].ID < quotes[j].ID })
+	return quotes, nil
+}
+
+func (r *QuoteRepository) ListSuspectQuotes() ([]model.Quote, error) {
+	r.mu.RLock()
+	quotes := make([]model.Quote, 0)
+	for _, q := range r.quotes {
+		if q.Status == model.StatusSuspect {
+			quotes = append(quotes, copyQuote(q))
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(quotes, func(i, j int) bool {
+		if !quotes[i].UpdatedAt.Equal(*quotes[j].UpdatedAt) {
+			return quotes[i].UpdatedAt.Before(*quotes[j].UpdatedAt)
+		}
+		return quotes[i].ID < quotes[j].ID
+	})
+	return quotes, nil
+}
+
+func (r *QuoteRepository) SetQuoteStatus(id string, from, to model.Status) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	q, ok := r.quotes[id]
+	if !ok || q.Status != from {
+		return sql.ErrNoRows
+	}
+	if from == model.StatusPending {
+		delete(r.pending, q.Currency)
+		delete(r.claims, id)
+	}
+	q.Status = to
+	r.quotes[id] = q
+	return nil
+}
+
+// newer reports whether a sorts before b in "ORDER BY updated_at DESC",
+// where Postgres places NULLs first.
+func newer(a, b model.Quote) bool {
+	if a.UpdatedAt == nil {
+		return b.UpdatedAt != nil
+	}
+	if b.UpdatedAt == nil {
+		return false
+	}
+	return a.UpdatedAt.After(*b.UpdatedAt)
+}
+
+func copyQuote(q model.Quote) model.Quote {
+	if q.Price != nil {
+		price := *q.Price
+		q.Price = &price
+	}
+	if q.UpdatedAt != nil {
+		updatedAt := *q.UpdatedAt
+		q.UpdatedAt = &updatedAt
+	}
+	if q.RequestedBy != nil {
+		requestedBy := *q.RequestedBy
+		q.RequestedBy = &requestedBy
+	}
+	return q
+}
+
+func (r *QuoteRepository) ExportQuotes(currency string, from, to time.Time, fn func(model.Quote) error) error {
+	r.mu.RLock()
+	var quotes []model.Quote
+	for _, q := range r.quotes {
+		if q.Currency != currency || q.Status != model.StatusDone || q.Price == nil || q.UpdatedAt == nil {
+			continue
+		}
+		if q.UpdatedAt.Before(from) || !q.UpdatedAt.Before(to) {
+			continue
+		}
+		quotes = append(quotes, copyQuote(q))
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(quotes, func(i, j int) bool {
+		if !quotes[i].UpdatedAt.Equal(*quotes[j].UpdatedAt) {
+			return quotes[i].UpdatedAt.Before(*quotes[j].UpdatedAt)
+		}
+		return quotes[i].ID < quotes[j].ID
+	})
+	for _, q := range quotes {
+		if err := fn(q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *QuoteRepository) InsertHistoricalQuote(currency string, price float64, updatedAt time.Time, source string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// The database backends keep microseconds.
+	updatedAt = updatedAt.UTC().Truncate(time.Microsecond)
+	key := historicalKey{currency: currency, updatedAt: updatedAt.UnixNano()}
+	if _, exists := r.historical[key]; exists {
+		return "", sql.ErrNoRows
+	}
+	id := uuid.New().String()
+	r.quotes[id] = model.Quote{ID: id, Currency: currency, Price: &price, UpdatedAt: &updatedAt, Status: model.StatusDone}
+	r.historical[key] = id
+	return id, nil
+}
@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"FinQuotesService/internal/repository"
+	"FinQuotesService/internal/repository/repositorytest"
+	"testing"
+)
+
+func TestQuoteRepository_Conformance(t *testing.T) {
+	repositorytest.RunQuoteRepositoryTests(t, func(t *testing.T) repository.QuoteRepository {
+		return NewQuoteRepository()
+	})
+}
+
+func TestAPIKeyRepository_Conformance(t *testing.T) {
+	repositorytest.RunAPIKeyRepositoryTests(t, func(t *testing.T) (repository.APIKeyRepository, repository.QuoteRepository) {
+		return NewAPIKeyRepository(), NewQuoteRepository()
+	})
+}
+
+func TestProviderQuotaRepository_Conformance(t *testing.T) {
+	repositorytest.RunProviderQuotaRepositoryTests(t, func(t *testing.T) repository.ProviderQuotaRepository {
+		return NewProviderQuotaRepository()
+	})
+}
+
+func TestIdempotencyRepository_Conformance(t *testing.T) {
+	repositorytest.RunIdempotencyRepositoryTests(t, func(t *testing.T) repository.IdempotencyRepository {
+		return NewIdempotencyRepository()
+	})
+}
+
+func TestBackfillRepository_Conformance(t *testing.T) {
+	repositorytest.RunBackfillRepositoryTests(t, func(t *testing.T) repository.BackfillRepository {
+		return NewBackfillRepository()
+	})
+}
@@ -0,0 +1,34 @@
+package memory
+
+import "sync"
+
+type quotaKey struct {
+	provider string
+	day      string
+}
+
+type ProviderQuotaRepository struct {
+	mu   sync.Mutex
+	used map[quotaKey]int
+}
+
+func NewProviderQuotaRepository() *ProviderQuotaRepository {
+	return &ProviderQuotaRepository{used: make(map[quotaKey]int)}
+}
+
+func (r *ProviderQuotaRepository) UseQuota(provider, day string, limit int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := quotaKey{provider, day}
+	if r.used[key] >= limit {
+		return false, nil
+	}
+	r.used[key]++
+	return true, nil
+}
+
+func (r *ProviderQuotaRepository) GetQuotaUsed(provider, day string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.used[quotaKey{provider, day}], nil
+}
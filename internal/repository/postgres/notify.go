@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const quoteUpdatesChannel = "quote_updates"
+
+type quoteUpdate struct {
+	Currency string `json:"currency"`
+	Origin   string `json:"origin"`
+}
+
+// QuoteUpdateNotifier broadcasts completed quotes to other replicas through
+// LISTEN/NOTIFY so they can drop stale cache entries. Notifications sent by
+// the same instance are ignored by its own listener.
+type QuoteUpdateNotifier struct {
+	db     *sql.DB
+	origin string
+}
+
+func NewQuoteUpdateNotifier(db *sql.DB) *QuoteUpdateNotifier {
+	return &QuoteUpdateNotifier{db: db, origin: uuid.New().String()}
+}
+
+func (n *QuoteUpdateNotifier) PublishQuoteUpdate(currency string) error {
+	payload, err := json.Marshal(quoteUpdate{Currency: currency, Origin: n.origin})
+	if err != nil {
+		return err
+	}
+	_, err = n.db.Exec(`SELECT pg_notify($1, $2)`, quoteUpdatesChannel, string(payload))
+	return err
+}
+
+// Listen calls onUpdate for every pair updated by another instance until ctx
+// is done. An empty currency means notifications may have been missed while
+// reconnecting and everything should be invalidated.
+func (n *QuoteUpdateNotifier) Listen(ctx context.Context, dsn string, onUpdate func(currency string)) error {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Warn("listener event", "component", "notifier", "event", ev, "error", err)
+		}
+	})
+	defer listener.Close()
+	if err := listener.Listen(quoteUpdatesChannel); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-listener.Notify:
+			if notification == nil {
+				onUpdate("")
+				continue
+			}
+			var upd quoteUpdate
+			if err := json.Unmarshal([]byte(notification.Extra), &upd); err != nil {
+				slog.Warn("bad notification payload", "component", "notifier", "payload", notification.Extra, "error", err)
+				continue
+			}
+			if upd.Origin != n.origin {
+				onUpdate(upd.Currency)
+			}
+		case <-time.After(90 * time.Second):
+			go func() {
+				if err := listener.Ping(); err != nil {
+					slog.Warn("listener ping failed", "component", "notifier", "error", err)
+				}
+			}()
+		}
+	}
+}
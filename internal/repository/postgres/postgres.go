@@ -0,0 +1,197 @@
+package postgres
+
+import (
+	"FinQuotesService/internal/model"
+	"database/sql"
+	"time"
+)
+
+type QuoteRepository struct {
+	InsertPendingStmt    *sql.Stmt
+	UpdateQuoteStmt      *sql.Stmt
+	GetQuoteByIdStmt     *sql.Stmt
+	GetLastQuoteStmt     *sql.Stmt
+	GetCandlesStmt       *sql.Stmt
+	ClaimStmt            *sql.Stmt
+	RenewClaimsStmt      *sql.Stmt
+	TakeClaimsStmt       *sql.Stmt
+	MarkUnclaimedStmt    *sql.Stmt
+	ExportStmt           *sql.Stmt
+	InsertHistoricalStmt *sql.Stmt
+	ListSuspectStmt      *sql.Stmt
+	SetStatusStmt        *sql.Stmt
+}
+
+func NewQuoteRepository(db *sql.DB) (*QuoteRepository, error) {
+	var r QuoteRepository
+	statements := []struct {
+		dst   **sql.Stmt
+		query string
+	}{
+		{&r.InsertPendingStmt, `INSERT INTO quotes (currency, status, requested_by) VALUES ($1, 'pending', NULLIF($2, '')) ON CONFLICT (currency) WHERE status = 'pending' DO NOTHING RETURNING id;`},
+		{&r.UpdateQuoteStmt, `UPDATE quotes SET price=$1, updated_at=now(), status=$2 WHERE id=$3`},
+		{&r.GetQuoteByIdStmt, `SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE id =$1`},
+		{&r.GetLastQuoteStmt, `SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=$1 AND status=$2 ORDER BY updated_at DESC LIMIT 1`},
+		{&r.GetCandlesStmt, `SELECT to_timestamp(floor(extract(epoch FROM updated_at) / $2::numeric) * $2::numeric) AT TIME ZONE 'UTC' AS bucket, (array_agg(price ORDER BY updated_at ASC))[1] AS open, max(price) AS high, min(price) AS low, (array_agg(price ORDER BY updated_at DESC))[1] AS close, count(*) AS count FROM quotes WHERE currency=$1 AND status='done' AND price IS NOT NULL AND updated_at >= $3 AND updated_at < $4 GROUP BY bucket ORDER BY bucket`},
+		{&r.ClaimStmt, `UPDATE quotes SET claimed_by=$2, claimed_until=$3 WHERE id=$1 AND status='pending' AND claimed_by IS NULL`},
+		{&r.RenewClaimsStmt, `UPDATE quotes SET claimed_until=$2 WHERE status='pending' AND claimed_by=$1`},
+		{&r.TakeClaimsStmt, `UPDATE quotes SET claimed_by=$1, claimed_until=$3 WHERE status='pending' AND claimed_until < $2 RETURNING id, currency, price, updated_at, status, requested_by`},
+		{&r.MarkUnclaimedStmt, `UPDATE quotes SET claimed_until=$1 WHERE status='pending' AND claimed_until IS NULL`},
+		{&r.ExportStmt, `SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=$1 AND status='done' AND price IS NOT NULL AND updated_at >= $2 AND updated_at < $3 ORDER BY updated_at, id`},
+		{&r.InsertHistoricalStmt, `INSERT INTO quotes (currency, price, updated_at, status, source) VALUES ($1, $2, $3, 'done', $4) ON CONFLICT (currency, updated_at) WHERE source IS NOT NULL DO NOTHING RETURNING id`},
+		{&r.ListSuspectStmt, `SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE status='suspect' ORDER BY updated_at, id`},
+		{&r.SetStatusStmt, `UPDATE quotes SET status=$3 WHERE id=$1 AND status=$2`},
+	}
+	for _, st := range statements {
+		stmt, err := db.Prepare(st.query)
+		if err != nil {
+			return nil, err
+		}
+		*st.dst = stmt
+	}
+	return &r, nil
+}
+
+func (r *QuoteRepository) InsertPendingQuote(currency, requestedBy string) (string, error) {
+	var id string
+	row := r.InsertPendingStmt.QueryRow(currency, requestedBy)
+	err := row.Scan(&id)
+	return id, err
+}
+
+func (r *QuoteRepository) UpdateQuote(id string, price float64, status model.Status) error {
+	_, err := r.UpdateQuoteStmt.Exec(price, status, id)
+	return err
+}
+
+func (r *QuoteRepository) GetQuoteById(id string) (model.Quote, error) {
+	row := r.GetQuoteByIdStmt.QueryRow(id)
+	var q model.Quote
+	err := row.Scan(&q.ID, &q.Currency, &q.Price, &q.UpdatedAt, &q.Status, &q.RequestedBy)
+	return q, err
+}
+
+func (r *QuoteRepository) GetLastQuote(currency string, status model.Status) (model.Quote, error) {
+	row := r.GetLastQuoteStmt.QueryRow(currency, status)
+	var q model.Quote
+	err := row.Scan(&q.ID, &q.Currency, &q.Price, &q.UpdatedAt, &q.Status, &q.RequestedBy)
+	return q, err
+}
+
+func (r *QuoteRepository) GetCandles(currency string, interval time.Duration, from, to time.Time) ([]model.Candle, error) {
+	rows, err := r.GetCandlesStmt.Query(currency, int64(interval/time.Second), from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	candles := make([]model.Candle, 0)
+	for rows.Next() {
+		var c model.Candle
+		if err := rows.Scan(&c.Bucket, &c.Open, &c.High, &c.Low, &c.Close, &c.Count); err != nil {
+			return nil, err
+		}
+		candles = append(candles, c)
+	}
+	return candles, rows.Err()
+}
+
+func (r *QuoteRepository) ClaimQuote(id, owner string, until time.Time) error {
+	res, err := r.ClaimStmt.Exec(id, owner, until.UTC())
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ClaimPendingQuotes takes over expired claims with a single UPDATE, which
+// rechecks the expiry of rows another replica updates concurrently, so a
+// quote is never claimed by two replicas at once.
+func (r *QuoteRepository) ClaimPendingQuotes(owner string, now, until time.Time) ([]model.Quote, error) {
+	if _, err := r.RenewClaimsStmt.Exec(owner, until.UTC()); err != nil {
+		return nil, err
+	}
+	rows, err := r.TakeClaimsStmt.Query(owner, now.UTC(), until.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	quotes := make([]model.Quote, 0)
+	for rows.Next() {
+		var q model.Quote
+		if err := rows.Scan(&q.ID, &q.Currency, &q.Price, &q.UpdatedAt, &q.Status, &q.RequestedBy); err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if _, err := r.MarkUnclaimedStmt.Exec(now.UTC()); err != nil {
+		return nil, err
+	}
+	return quotes, nil
+}
+
+func (r *QuoteRepository) ExportQuotes(currency string, from, to time.Time, fn func(model.Quote) error) error {
+	rows, err := r.ExportStmt.Query(currency, from.UTC(), to.UTC())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var q model.Quote
+		if err := rows.Scan(&q.ID, &q.Currency, &q.Price, &q.UpdatedAt, &q.Status, &q.RequestedBy); err != nil {
+			return err
+		}
+		if err := fn(q); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *QuoteRepository) InsertHistoricalQuote(currency string, price float64, updatedAt time.Time, source string) (string, error) {
+	var id string
+	err := r.InsertHistoricalStmt.QueryRow(currency, price, updatedAt.UTC(), source).Scan(&id)
+	return id, err
+}
+
+func (r *QuoteRepository) ListSuspectQuotes() ([]model.Quote, error) {
+	rows, err := r.ListSuspectStmt.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	quotes := make([]model.Quote, 0)
+	for rows.Next() {
+		var q model.Quote
+		if err := rows.Scan(&q.ID, &q.Currency, &q.Price, &q.UpdatedAt, &q.Status, &q.RequestedBy); err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, q)
+	}
+	return quotes, rows.Err()
+}
+
+func (r *QuoteRepository) SetQuoteStatus(id string, from, to model.Status) error {
+	res, err := r.SetStatusStmt.Exec(id, from, to)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,619 @@
+package postgres
+
+import (
+	"FinQuotesService/internal/db"
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/ratelimit"
+	"FinQuotesService/internal/repository"
+	"FinQuotesService/internal/repository/repositorytest"
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"os"
+	"testing"
+	"time"
+)
+
+func initMocks(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return db, mock
+}
+
+func newRepository(t *testing.T, db *sql.DB) *QuoteRepository {
+	repo, err := NewQuoteRepository(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return repo
+}
+
+func TestQuoteRepository_InsertPendingQuote(t *testing.T) {
+	db, mock := initMocks(t)
+	defer db.Close()
+
+	testUuid := uuid.New().String()
+	testCurrency := "USD/EUR"
+	testKeyId := uuid.New().String()
+
+	rows := sqlmock.NewRows([]string{"id"}).AddRow(testUuid)
+
+	expectedPrepare := mock.ExpectPrepare(`INSERT INTO quotes \(currency, status, requested_by\) VALUES \(\$1, 'pending', NULLIF\(\$2, ''\)\) ON CONFLICT \(currency\) WHERE status = 'pending' DO NOTHING RETURNING id;`)
+	mock.ExpectPrepare(`UPDATE quotes SET price=\$1, updated_at=now\(\), status=\$2 WHERE id=\$3`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE id =\$1`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status=\$2 ORDER BY updated_at DESC LIMIT 1`)
+	mock.ExpectPrepare(`SELECT .+ AS bucket, .+ FROM quotes WHERE currency=\$1 AND status='done' .+ GROUP BY bucket ORDER BY bucket`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$2, claimed_until=\$3 WHERE id=\$1 AND status='pending' AND claimed_by IS NULL`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$2 WHERE status='pending' AND claimed_by=\$1`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$1, claimed_until=\$3 WHERE status='pending' AND claimed_until < \$2 RETURNING .+`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$1 WHERE status='pending' AND claimed_until IS NULL`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status='done' .+ ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`INSERT INTO quotes \(currency, price, updated_at, status, source\) VALUES .+ ON CONFLICT \(currency, updated_at\) WHERE source IS NOT NULL DO NOTHING RETURNING id`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE status='suspect' ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`UPDATE quotes SET status=\$3 WHERE id=\$1 AND status=\$2`)
+
+	expectedPrepare.ExpectQuery().
+		WithArgs(testCurrency, testKeyId).
+		WillReturnRows(rows)
+
+	repo := newRepository(t, db)
+	quoteId, err := repo.InsertPendingQuote(testCurrency, testKeyId)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if quoteId != testUuid {
+		t.Errorf("expected ID %s, got %s", testUuid, quoteId)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestQuoteRepository_UpdateQuote(t *testing.T) {
+	db, mock := initMocks(t)
+	defer db.Close()
+
+	mock.ExpectPrepare(`INSERT INTO quotes \(currency, status, requested_by\) VALUES \(\$1, 'pending', NULLIF\(\$2, ''\)\) ON CONFLICT \(currency\) WHERE status = 'pending' DO NOTHING RETURNING id;`)
+	expectedPrepare := mock.ExpectPrepare(`UPDATE quotes SET price=\$1, updated_at=now\(\), status=\$2 WHERE id=\$3`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE id =\$1`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status=\$2 ORDER BY updated_at DESC LIMIT 1`)
+	mock.ExpectPrepare(`SELECT .+ AS bucket, .+ FROM quotes WHERE currency=\$1 AND status='done' .+ GROUP BY bucket ORDER BY bucket`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$2, claimed_until=\$3 WHERE id=\$1 AND status='pending' AND claimed_by IS NULL`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$2 WHERE status='pending' AND claimed_by=\$1`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$1, claimed_until=\$3 WHERE status='pending' AND claimed_until < \$2 RETURNING .+`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$1 WHERE status='pending' AND claimed_until IS NULL`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status='done' .+ ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`INSERT INTO quotes \(currency, price, updated_at, status, source\) VALUES .+ ON CONFLICT \(currency, updated_at\) WHERE source IS NOT NULL DO NOTHING RETURNING id`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE status='suspect' ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`UPDATE quotes SET status=\$3 WHERE id=\$1 AND status=\$2`)
+
+	expectedPrepare.ExpectExec().
+		WithArgs(1.23, model.StatusDone, "uuid-1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	repo := newRepository(t, db)
+	err := repo.UpdateQuote("uuid-1", 1.23, model.StatusDone)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetQuoteById_Success(t *testing.T) {
+	db, mock := initMocks(t)
+	defer db.Close()
+
+	testID := "test-uuid"
+	testCurrency := "USD/EUR"
+	testPrice := 1.23
+	testTime := time.Now().Truncate(time.Second)
+	testStatus := model.StatusDone
+
+	rows := sqlmock.NewRows([]string{"id", "currency", "price", "updated_at", "status", "requested_by"}).
+		AddRow(testID, testCurrency, testPrice, testTime, testStatus, nil)
+
+	mock.ExpectPrepare(`INSERT INTO quotes \(currency, status, requested_by\) VALUES \(\$1, 'pending', NULLIF\(\$2, ''\)\) ON CONFLICT \(currency\) WHERE status = 'pending' DO NOTHING RETURNING id;`)
+	mock.ExpectPrepare(`UPDATE quotes SET price=\$1, updated_at=now\(\), status=\$2 WHERE id=\$3`)
+	expectedPrepare := mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE id =\$1`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status=\$2 ORDER BY updated_at DESC LIMIT 1`)
+	mock.ExpectPrepare(`SELECT .+ AS bucket, .+ FROM quotes WHERE currency=\$1 AND status='done' .+ GROUP BY bucket ORDER BY bucket`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$2, claimed_until=\$3 WHERE id=\$1 AND status='pending' AND claimed_by IS NULL`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$2 WHERE status='pending' AND claimed_by=\$1`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$1, claimed_until=\$3 WHERE status='pending' AND claimed_until < \$2 RETURNING .+`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$1 WHERE status='pending' AND claimed_until IS NULL`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status='done' .+ ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`INSERT INTO quotes \(currency, price, updated_at, status, source\) VALUES .+ ON CONFLICT \(currency, updated_at\) WHERE source IS NOT NULL DO NOTHING RETURNING id`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE status='suspect' ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`UPDATE quotes SET status=\$3 WHERE id=\$1 AND status=\$2`)
+
+	expectedPrepare.ExpectQuery().
+		WithArgs(testID).
+		WillReturnRows(rows)
+
+	repo := newRepository(t, db)
+	quote, err := repo.GetQuoteById(testID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if quote.ID != testID {
+		t.Errorf("expected ID %s, got %s", testID, quote.ID)
+	}
+	if quote.Currency != testCurrency {
+		t.Errorf("expected Currency %s, got %s", testCurrency, quote.Currency)
+	}
+	if *quote.Price != testPrice {
+		t.Errorf("expected Price %v, got %v", testPrice, quote.Price)
+	}
+	if !quote.UpdatedAt.Equal(testTime) {
+		t.Errorf("expected UpdatedAt %v, got %v", testTime, quote.UpdatedAt)
+	}
+	if quote.Status != testStatus {
+		t.Errorf("expected Status %s, got %s", testStatus, quote.Status)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetQuoteById_NotFound(t *testing.T) {
+	db, mock := initMocks(t)
+	defer db.Close()
+
+	notExistID := "not-exist-uuid"
+
+	mock.ExpectPrepare(`INSERT INTO quotes \(currency, status, requested_by\) VALUES \(\$1, 'pending', NULLIF\(\$2, ''\)\) ON CONFLICT \(currency\) WHERE status = 'pending' DO NOTHING RETURNING id;`)
+	mock.ExpectPrepare(`UPDATE quotes SET price=\$1, updated_at=now\(\), status=\$2 WHERE id=\$3`)
+	expectedPrepare := mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE id =\$1`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status=\$2 ORDER BY updated_at DESC LIMIT 1`)
+	mock.ExpectPrepare(`SELECT .+ AS bucket, .+ FROM quotes WHERE currency=\$1 AND status='done' .+ GROUP BY bucket ORDER BY bucket`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$2, claimed_until=\$3 WHERE id=\$1 AND status='pending' AND claimed_by IS NULL`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$2 WHERE status='pending' AND claimed_by=\$1`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$1, claimed_until=\$3 WHERE status='pending' AND claimed_until < \$2 RETURNING .+`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$1 WHERE status='pending' AND claimed_until IS NULL`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status='done' .+ ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`INSERT INTO quotes \(currency, price, updated_at, status, source\) VALUES .+ ON CONFLICT \(currency, updated_at\) WHERE source IS NOT NULL DO NOTHING RETURNING id`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE status='suspect' ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`UPDATE quotes SET status=\$3 WHERE id=\$1 AND status=\$2`)
+
+	expectedPrepare.ExpectQuery().
+		WithArgs(notExistID).
+		WillReturnError(sql.ErrNoRows)
+
+	repo := newRepository(t, db)
+	_, err := repo.GetQuoteById(notExistID)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetLastQuote_Success(t *testing.T) {
+	db, mock := initMocks(t)
+	defer db.Close()
+
+	testID := "test-uuid"
+	testCurrency := "USD/EUR"
+	testPrice := 1.23
+	testTime := time.Now().Truncate(time.Second)
+	testStatus := model.StatusDone
+
+	rows := sqlmock.NewRows([]string{"id", "currency", "price", "updated_at", "status", "requested_by"}).
+		AddRow(testID, testCurrency, testPrice, testTime, testStatus, nil)
+
+	mock.ExpectPrepare(`INSERT INTO quotes \(currency, status, requested_by\) VALUES \(\$1, 'pending', NULLIF\(\$2, ''\)\) ON CONFLICT \(currency\) WHERE status = 'pending' DO NOTHING RETURNING id;`)
+	mock.ExpectPrepare(`UPDATE quotes SET price=\$1, updated_at=now\(\), status=\$2 WHERE id=\$3`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE id =\$1`)
+	expectedPrepare := mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status=\$2 ORDER BY updated_at DESC LIMIT 1`)
+	mock.ExpectPrepare(`SELECT .+ AS bucket, .+ FROM quotes WHERE currency=\$1 AND status='done' .+ GROUP BY bucket ORDER BY bucket`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$2, claimed_until=\$3 WHERE id=\$1 AND status='pending' AND claimed_by IS NULL`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$2 WHERE status='pending' AND claimed_by=\$1`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$1, claimed_until=\$3 WHERE status='pending' AND claimed_until < \$2 RETURNING .+`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$1 WHERE status='pending' AND claimed_until IS NULL`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status='done' .+ ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`INSERT INTO quotes \(currency, price, updated_at, status, source\) VALUES .+ ON CONFLICT \(currency, updated_at\) WHERE source IS NOT NULL DO NOTHING RETURNING id`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE status='suspect' ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`UPDATE quotes SET status=\$3 WHERE id=\$1 AND status=\$2`)
+
+	expectedPrepare.ExpectQuery().
+		WithArgs(testCurrency, testStatus).
+		WillReturnRows(rows)
+
+	repo := newRepository(t, db)
+	quote, err := repo.GetLastQuote(testCurrency, testStatus)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if quote.ID != testID {
+		t.Errorf("expected ID %s, got %s", testID, quote.ID)
+	}
+	if quote.Currency != testCurrency {
+		t.Errorf("expected Currency %s, got %s", testCurrency, quote.Currency)
+	}
+	if *quote.Price != testPrice {
+		t.Errorf("expected Price %v, got %v", testPrice, quote.Price)
+	}
+	if !quote.UpdatedAt.Equal(testTime) {
+		t.Errorf("expected UpdatedAt %v, got %v", testTime, quote.UpdatedAt)
+	}
+	if quote.Status != testStatus {
+		t.Errorf("expected Status %s, got %s", testStatus, quote.Status)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetLastQuote_NotFound(t *testing.T) {
+	db, mock := initMocks(t)
+	defer db.Close()
+
+	testCurrency := "USD/EUR"
+	testStatus := model.StatusDone
+
+	mock.ExpectPrepare(`INSERT INTO quotes \(currency, status, requested_by\) VALUES \(\$1, 'pending', NULLIF\(\$2, ''\)\) ON CONFLICT \(currency\) WHERE status = 'pending' DO NOTHING RETURNING id;`)
+	mock.ExpectPrepare(`UPDATE quotes SET price=\$1, updated_at=now\(\), status=\$2 WHERE id=\$3`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE id =\$1`)
+	expectedPrepare := mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status=\$2 ORDER BY updated_at DESC LIMIT 1`)
+	mock.ExpectPrepare(`SELECT .+ AS bucket, .+ FROM quotes WHERE currency=\$1 AND status='done' .+ GROUP BY bucket ORDER BY bucket`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$2, claimed_until=\$3 WHERE id=\$1 AND status='pending' AND claimed_by IS NULL`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$2 WHERE status='pending' AND claimed_by=\$1`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$1, claimed_until=\$3 WHERE status='pending' AND claimed_until < \$2 RETURNING .+`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$1 WHERE status='pending' AND claimed_until IS NULL`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status='done' .+ ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`INSERT INTO quotes \(currency, price, updated_at, status, source\) VALUES .+ ON CONFLICT \(currency, updated_at\) WHERE source IS NOT NULL DO NOTHING RETURNING id`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE status='suspect' ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`UPDATE quotes SET status=\$3 WHERE id=\$1 AND status=\$2`)
+
+	expectedPrepare.ExpectQuery().
+		WithArgs(testCurrency, testStatus).
+		WillReturnError(sql.ErrNoRows)
+
+	repo := newRepository(t, db)
+	_, err := repo.GetLastQuote(testCurrency, testStatus)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetCandles_Success(t *testing.T) {
+	db, mock := initMocks(t)
+	defer db.Close()
+
+	testCurrency := "USD/EUR"
+	testTo := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	testFrom := testTo.Add(-2 * time.Hour)
+
+	rows := sqlmock.NewRows([]string{"bucket", "open", "high", "low", "close", "count"}).
+		AddRow(testFrom, 1.1, 1.3, 1.0, 1.2, 4).
+		AddRow(testFrom.Add(time.Hour), 1.2, 1.25, 1.15, 1.15, 2)
+
+	mock.ExpectPrepare(`INSERT INTO quotes \(currency, status, requested_by\) VALUES \(\$1, 'pending', NULLIF\(\$2, ''\)\) ON CONFLICT \(currency\) WHERE status = 'pending' DO NOTHING RETURNING id;`)
+	mock.ExpectPrepare(`UPDATE quotes SET price=\$1, updated_at=now\(\), status=\$2 WHERE id=\$3`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE id =\$1`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status=\$2 ORDER BY updated_at DESC LIMIT 1`)
+	expectedPrepare := mock.ExpectPrepare(`SELECT .+ AS bucket, .+ FROM quotes WHERE currency=\$1 AND status='done' .+ GROUP BY bucket ORDER BY bucket`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$2, claimed_until=\$3 WHERE id=\$1 AND status='pending' AND claimed_by IS NULL`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$2 WHERE status='pending' AND claimed_by=\$1`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$1, claimed_until=\$3 WHERE status='pending' AND claimed_until < \$2 RETURNING .+`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$1 WHERE status='pending' AND claimed_until IS NULL`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status='done' .+ ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`INSERT INTO quotes \(currency, price, updated_at, status, source\) VALUES .+ ON CONFLICT \(currency, updated_at\) WHERE source IS NOT NULL DO NOTHING RETURNING id`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE status='suspect' ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`UPDATE quotes SET status=\$3 WHERE id=\$1 AND status=\$2`)
+
+	expectedPrepare.ExpectQuery().
+		WithArgs(testCurrency, int64(3600), testFrom, testTo).
+		WillReturnRows(rows)
+
+	repo := newRepository(t, db)
+	candles, err := repo.GetCandles(testCurrency, time.Hour, testFrom, testTo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(candles) != 2 {
+		t.Fatalf("expected 2 candles, got %d", len(candles))
+	}
+	if !candles[0].Bucket.Equal(testFrom) {
+		t.Errorf("expected Bucket %v, got %v", testFrom, candles[0].Bucket)
+	}
+	if candles[0].Open != 1.1 || candles[0].High != 1.3 || candles[0].Low != 1.0 || candles[0].Close != 1.2 {
+		t.Errorf("unexpected OHLC values: %+v", candles[0])
+	}
+	if candles[1].Count != 2 {
+		t.Errorf("expected Count 2, got %d", candles[1].Count)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestExportQuotes_Success(t *testing.T) {
+	db, mock := initMocks(t)
+	defer db.Close()
+
+	testCurrency := "USD/EUR"
+	testTo := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	testFrom := testTo.Add(-24 * time.Hour)
+	testId := uuid.New().String()
+
+	rows := sqlmock.NewRows([]string{"id", "currency", "price", "updated_at", "status", "requested_by"}).
+		AddRow(testId, testCurrency, 0.91, testFrom.Add(time.Hour), model.StatusDone, nil).
+		AddRow(uuid.New().String(), testCurrency, 0.92, testFrom.Add(2*time.Hour), model.StatusDone, nil)
+
+	mock.ExpectPrepare(`INSERT INTO quotes \(currency, status, requested_by\) VALUES \(\$1, 'pending', NULLIF\(\$2, ''\)\) ON CONFLICT \(currency\) WHERE status = 'pending' DO NOTHING RETURNING id;`)
+	mock.ExpectPrepare(`UPDATE quotes SET price=\$1, updated_at=now\(\), status=\$2 WHERE id=\$3`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE id =\$1`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status=\$2 ORDER BY updated_at DESC LIMIT 1`)
+	mock.ExpectPrepare(`SELECT .+ AS bucket, .+ FROM quotes WHERE currency=\$1 AND status='done' .+ GROUP BY bucket ORDER BY bucket`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$2, claimed_until=\$3 WHERE id=\$1 AND status='pending' AND claimed_by IS NULL`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$2 WHERE status='pending' AND claimed_by=\$1`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$1, claimed_until=\$3 WHERE status='pending' AND claimed_until < \$2 RETURNING .+`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$1 WHERE status='pending' AND claimed_until IS NULL`)
+	expectedPrepare := mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status='done' .+ ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`INSERT INTO quotes \(currency, price, updated_at, status, source\) VALUES .+ ON CONFLICT \(currency, updated_at\) WHERE source IS NOT NULL DO NOTHING RETURNING id`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE status='suspect' ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`UPDATE quotes SET status=\$3 WHERE id=\$1 AND status=\$2`)
+
+	expectedPrepare.ExpectQuery().
+		WithArgs(testCurrency, testFrom, testTo).
+		WillReturnRows(rows)
+
+	repo := newRepository(t, db)
+	var quotes []model.Quote
+	err := repo.ExportQuotes(testCurrency, testFrom, testTo, func(q model.Quote) error {
+		quotes = append(quotes, q)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(quotes) != 2 {
+		t.Fatalf("expected 2 quotes, got %d", len(quotes))
+	}
+	if quotes[0].ID != testId || *quotes[0].Price != 0.91 {
+		t.Errorf("unexpected first quote: %+v", quotes[0])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestInsertHistoricalQuote_Duplicate(t *testing.T) {
+	db, mock := initMocks(t)
+	defer db.Close()
+
+	testCurrency := "USD/EUR"
+	testAt := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectPrepare(`INSERT INTO quotes \(currency, status, requested_by\) VALUES \(\$1, 'pending', NULLIF\(\$2, ''\)\) ON CONFLICT \(currency\) WHERE status = 'pending' DO NOTHING RETURNING id;`)
+	mock.ExpectPrepare(`UPDATE quotes SET price=\$1, updated_at=now\(\), status=\$2 WHERE id=\$3`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE id =\$1`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status=\$2 ORDER BY updated_at DESC LIMIT 1`)
+	mock.ExpectPrepare(`SELECT .+ AS bucket, .+ FROM quotes WHERE currency=\$1 AND status='done' .+ GROUP BY bucket ORDER BY bucket`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$2, claimed_until=\$3 WHERE id=\$1 AND status='pending' AND claimed_by IS NULL`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$2 WHERE status='pending' AND claimed_by=\$1`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_by=\$1, claimed_until=\$3 WHERE status='pending' AND claimed_until < \$2 RETURNING .+`)
+	mock.ExpectPrepare(`UPDATE quotes SET claimed_until=\$1 WHERE status='pending' AND claimed_until IS NULL`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=\$1 AND status='done' .+ ORDER BY updated_at, id`)
+	expectedPrepare := mock.ExpectPrepare(`INSERT INTO quotes \(currency, price, updated_at, status, source\) VALUES .+ ON CONFLICT \(currency, updated_at\) WHERE source IS NOT NULL DO NOTHING RETURNING id`)
+	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE status='suspect' ORDER BY updated_at, id`)
+	mock.ExpectPrepare(`UPDATE quotes SET status=\$3 WHERE id=\$1 AND status=\$2`)
+
+	expectedPrepare.ExpectQuery().
+		WithArgs(testCurrency, 0.89, testAt, model.SourceImport).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	repo := newRepository(t, db)
+	_, err := repo.InsertHistoricalQuote(testCurrency, 0.89, testAt, model.SourceImport)
+	if err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestQuoteRepository_Conformance runs the shared backend suite against a
+// real database when TEST_DB_DSN points to one. The quotes table is truncated
+// before every case.
+func TestQuoteRepository_Conformance(t *testing.T) {
+	conn := openTestDB(t)
+	repositorytest.RunQuoteRepositoryTests(t, func(t *testing.T) repository.QuoteRepository {
+		if _, err := conn.Exec(`TRUNCATE quotes`); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return newRepository(t, conn)
+	})
+}
+
+func TestAPIKeyRepository_Conformance(t *testing.T) {
+	conn := openTestDB(t)
+	repositorytest.RunAPIKeyRepositoryTests(t, func(t *testing.T) (repository.APIKeyRepository, repository.QuoteRepository) {
+		if _, err := conn.Exec(`TRUNCATE quotes, api_keys`); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		keys, err := NewAPIKeyRepository(conn)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return keys, newRepository(t, conn)
+	})
+}
+
+func TestProviderQuotaRepository_Conformance(t *testing.T) {
+	conn := openTestDB(t)
+	repositorytest.RunProviderQuotaRepositoryTests(t, func(t *testing.T) repository.ProviderQuotaRepository {
+		if _, err := conn.Exec(`TRUNCATE provider_quota`); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		repo, err := NewProviderQuotaRepository(conn)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return repo
+	})
+}
+
+func TestIdempotencyRepository_Conformance(t *testing.T) {
+	conn := openTestDB(t)
+	repositorytest.RunIdempotencyRepositoryTests(t, func(t *testing.T) repository.IdempotencyRepository {
+		if _, err := conn.Exec(`TRUNCATE idempotency_keys`); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		repo, err := NewIdempotencyRepository(conn)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return repo
+	})
+}
+
+func TestBackfillRepository_Conformance(t *testing.T) {
+	conn := openTestDB(t)
+	repositorytest.RunBackfillRepositoryTests(t, func(t *testing.T) repository.BackfillRepository {
+		if _, err := conn.Exec(`TRUNCATE backfills`); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		repo, err := NewBackfillRepository(conn)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return repo
+	})
+}
+
+// openTestDB connects to TEST_DB_DSN and applies migrations, skipping the
+// test when no database is configured.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN is not set")
+	}
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	migrator, err := db.NewMigrator(conn, db.DriverPostgres)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return conn
+}
+
+func TestQuoteUpdateNotifier_Publish(t *testing.T) {
+	db, mock := initMocks(t)
+	defer db.Close()
+
+	notifier := NewQuoteUpdateNotifier(db)
+	payload := `{"currency":"USD/EUR","origin":"` + notifier.origin + `"}`
+	mock.ExpectExec(`SELECT pg_notify\(\$1, \$2\)`).
+		WithArgs(quoteUpdatesChannel, payload).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := notifier.PublishQuoteUpdate("USD/EUR"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRateLimitStore_Take(t *testing.T) {
+	db, mock := initMocks(t)
+	defer db.Close()
+
+	now := time.Date(2025, 1, 1, 0, 0, 10, 0, time.UTC)
+	limit := ratelimit.Per(60, time.Minute, 5)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO rate_limit_buckets \(key, tokens, updated_at\) VALUES \(\$1, \$2, \$3\) ON CONFLICT \(key\) DO NOTHING`).
+		WithArgs("route|ip:10.0.0.1", 5.0, now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT tokens, updated_at FROM rate_limit_buckets WHERE key=\$1 FOR UPDATE`).
+		WithArgs("route|ip:10.0.0.1").
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "updated_at"}).AddRow(0.5, now.Add(-time.Second)))
+	mock.ExpectExec(`UPDATE rate_limit_buckets SET tokens=\$1, updated_at=\$2 WHERE key=\$3`).
+		WithArgs(0.5, now, "route|ip:10.0.0.1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	store := NewRateLimitStore(db, time.Minute)
+	res, err := store.Take(context.Background(), "route|ip:10.0.0.1", limit, 1, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.Allowed || res.Remaining != 0 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRateLimitStore_TakeDenied(t *testing.T) {
+	db, mock := initMocks(t)
+	defer db.Close()
+
+	now := time.Date(2025, 1, 1, 0, 0, 10, 0, time.UTC)
+	limit := ratelimit.Per(60, time.Minute, 5)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO rate_limit_buckets`).
+		WithArgs("route|ip:10.0.0.1", 5.0, now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT tokens, updated_at FROM rate_limit_buckets WHERE key=\$1 FOR UPDATE`).
+		WithArgs("route|ip:10.0.0.1").
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "updated_at"}).AddRow(0.5, now.Add(-time.Second)))
+	// The refill is saved, but none of the 1.5 tokens are taken for 2 calls.
+	mock.ExpectExec(`UPDATE rate_limit_buckets SET tokens=\$1, updated_at=\$2 WHERE key=\$3`).
+		WithArgs(1.5, now, "route|ip:10.0.0.1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	store := NewRateLimitStore(db, time.Minute)
+	res, err := store.Take(context.Background(), "route|ip:10.0.0.1", limit, 2, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Allowed || res.Remaining != 1 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
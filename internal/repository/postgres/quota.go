@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+)
+
+type ProviderQuotaRepository struct {
+	UseStmt  *sql.Stmt
+	UsedStmt *sql.Stmt
+}
+
+func NewProviderQuotaRepository(db *sql.DB) (*ProviderQuotaRepository, error) {
+	var r ProviderQuotaRepository
+	statements := []struct {
+		dst   **sql.Stmt
+		query string
+	}{
+		{&r.UseStmt, `INSERT INTO provider_quota AS q (provider, day, used) VALUES ($1, $2, 1) ON CONFLICT (provider, day) DO UPDATE SET used = q.used + 1 WHERE q.used < $3 RETURNING used`},
+		{&r.UsedStmt, `SELECT used FROM provider_quota WHERE provider=$1 AND day=$2`},
+	}
+	for _, st := range statements {
+		stmt, err := db.Prepare(st.query)
+		if err != nil {
+			return nil, err
+		}
+		*st.dst = stmt
+	}
+	return &r, nil
+}
+
+// UseQuota relies on the conditional upsert returning no row once the limit
+// is reached, so concurrent instances never go over it.
+func (r *ProviderQuotaRepository) UseQuota(provider, day string, limit int) (bool, error) {
+	var used int
+	err := r.UseStmt.QueryRow(provider, day, limit).Scan(&used)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *ProviderQuotaRepository) GetQuotaUsed(provider, day string) (int, error) {
+	var used int
+	err := r.UsedStmt.QueryRow(provider, day).Scan(&used)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return used, err
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"FinQuotesService/internal/ratelimit"
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// RateLimitStore shares token buckets between instances. Each Take locks the
+// bucket row for the duration of a short transaction, so concurrent requests
+// of one client are counted exactly once.
+type RateLimitStore struct {
+	db *sql.DB
+	// Buckets idle for longer than this are deleted by Cleanup.
+	idle time.Duration
+}
+
+func NewRateLimitStore(db *sql.DB, idle time.Duration) *RateLimitStore {
+	return &RateLimitStore{db: db, idle: idle}
+}
+
+func (s *RateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit, n int, now time.Time) (ratelimit.Result, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	defer tx.Rollback()
+
+	now = now.UTC()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES ($1, $2, $3) ON CONFLICT (key) DO NOTHING`, key, float64(limit.Burst), now); err != nil {
+		return ratelimit.Result{}, err
+	}
+	var b ratelimit.Bucket
+	row := tx.QueryRowContext(ctx, `SELECT tokens, updated_at FROM rate_limit_buckets WHERE key=$1 FOR UPDATE`, key)
+	if err := row.Scan(&b.Tokens, &b.UpdatedAt); err != nil {
+		return ratelimit.Result{}, err
+	}
+	if b.UpdatedAt.After(now) {
+		// Another instance with a clock ahead of ours touched the bucket last.
+		now = b.UpdatedAt
+	}
+	res := b.Take(limit, n, now)
+	if _, err := tx.ExecContext(ctx, `UPDATE rate_limit_buckets SET tokens=$1, updated_at=$2 WHERE key=$3`, b.Tokens, b.UpdatedAt, key); err != nil {
+		return ratelimit.Result{}, err
+	}
+	return res, tx.Commit()
+}
+
+// Cleanup periodically deletes idle buckets until ctx is done.
+func (s *RateLimitStore) Cleanup(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			res, err := s.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE updated_at < $1`, time.Now().UTC().Add(-s.idle))
+			if err != nil {
+				slog.Warn("rate limit cleanup failed", "component", "ratelimit", "error", err)
+				continue
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				slog.Debug("rate limit buckets deleted", "component", "ratelimit", "count", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"FinQuotesService/internal/model"
+	"time"
+)
+
+// QuoteRepository is the storage contract shared by all quote backends.
+// Lookups that find nothing return sql.ErrNoRows, and InsertPendingQuote
+// returns sql.ErrNoRows when the currency already has a pending quote, so
+// callers behave the same regardless of the backend in use.
+type QuoteRepository interface {
+	// InsertPendingQuote records requestedBy (an API key ID, empty when
+	// unknown) as the client that triggered the update.
+	InsertPendingQuote(currency, requestedBy string) (string, error)
+	UpdateQuote(id string, price float64, status model.Status) error
+	GetQuoteById(id string) (model.Quote, error)
+	GetLastQuote(currency string, status model.Status) (model.Quote, error)
+	GetCandles(currency string, interval time.Duration, from, to time.Time) ([]model.Candle, error)
+	// ClaimQuote makes owner responsible for queueing the pending quote id
+	// until the claim expires. It returns sql.ErrNoRows unless the quote is
+	// pending and was never claimed.
+	ClaimQuote(id, owner string, until time.Time) error
+	// ClaimPendingQuotes extends the claims of owner to until and claims the
+	// pending quotes whose claim expired before now, which it returns. A
+	// quote that was never claimed is only marked as seen at now and claimed
+	// by a later call, leaving its inserter time to claim it first.
+	ClaimPendingQuotes(owner string, now, until time.Time) ([]model.Quote, error)
+	// ExportQuotes calls fn for every done quote of currency updated in
+	// [from, to), oldest first, reading rows as they come rather than loading
+	// the range up front. An error from fn stops the export and is returned.
+	ExportQuotes(currency string, from, to time.Time, fn func(model.Quote) error) error
+	// InsertHistoricalQuote stores a done quote stamped with updatedAt and
+	// marked with source. It returns sql.ErrNoRows when a marked quote of
+	// currency already exists at updatedAt.
+	InsertHistoricalQuote(currency string, price float64, updatedAt time.Time, source string) (string, error)
+	// ListSuspectQuotes returns the quotes held for review, oldest first.
+	ListSuspectQuotes() ([]model.Quote, error)
+	// SetQuoteStatus moves a quote from status from to status to, keeping its
+	// price and update time. It returns sql.ErrNoRows unless the quote exists
+	// with status from.
+	SetQuoteStatus(id string, from, to model.Status) error
+}
+
+// APIKeyRepository stores API keys by the hash of their secret. Unknown keys
+// are reported with sql.ErrNoRows, revoked keys are still returned so callers
+// can tell them apart.
+type APIKeyRepository interface {
+	CreateAPIKey(name, hash string, scopes []model.Scope) (model.APIKey, error)
+	GetAPIKeyByHash(hash string) (model.APIKey, error)
+	ListAPIKeys() ([]model.APIKey, error)
+	// RevokeAPIKey returns sql.ErrNoRows when there is no active key with id.
+	RevokeAPIKey(id string) error
+}
+
+// ProviderQuotaRepository counts upstream calls per provider and UTC day
+// ("2006-01-02").
+type ProviderQuotaRepository interface {
+	// UseQuota increments the counter unless it already reached limit and
+	// reports whether it did.
+	UseQuota(provider, day string, limit int) (bool, error)
+	// GetQuotaUsed returns 0 for days without calls.
+	GetQuotaUsed(provider, day string) (int, error)
+}
+
+// IdempotencyRepository stores request outcomes by client and key. Records
+// created before notBefore are expired and treated as absent.
+type IdempotencyRepository interface {
+	// SaveIdempotencyRecord stores rec unless an unexpired record exists for
+	// the same client and key, and returns the record that is kept.
+	SaveIdempotencyRecord(rec model.IdempotencyRecord, notBefore time.Time) (model.IdempotencyRecord, error)
+	// GetIdempotencyRecord returns sql.ErrNoRows for unknown or expired keys.
+	GetIdempotencyRecord(clientID, key string, notBefore time.Time) (model.IdempotencyRecord, error)
+	// DeleteIdempotencyRecords deletes records created before t and returns
+	// how many were deleted.
+	DeleteIdempotencyRecords(before time.Time) (int64, error)
+}
+
+// BackfillRepository stores backfills and their progress. Unknown IDs are
+// reported with sql.ErrNoRows.
+type BackfillRepository interface {
+	// CreateBackfill stores b under a new ID and returns it.
+	CreateBackfill(b model.Backfill) (model.Backfill, error)
+	GetBackfill(id string) (model.Backfill, error)
+	// ListBackfills returns every backfill, oldest first.
+	ListBackfills() ([]model.Backfill, error)
+	// ClaimBackfill leases the active backfill id to owner until until,
+	// unless another owner holds a lease that has not expired at now, in
+	// which case it returns sql.ErrNoRows. Claiming again renews the lease.
+	ClaimBackfill(id, owner string, now, until time.Time) error
+	// UpdateBackfill saves the progress of b: next day, status, counters,
+	// error and update time. It returns sql.ErrNoRows unless the lease is
+	// held by b.Owner, or by nobody when b.Owner is empty. Saving a backfill
+	// that is no longer active releases its lease.
+	UpdateBackfill(b model.Backfill) error
+}
@@ -0,0 +1,687 @@
+// Package repositorytest holds the behaviour every repository.QuoteRepository
+// backend must share. Backend packages run it from their own tests.
+package repositorytest
+
+import (
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func RunQuoteRepositoryTests(t *testing.T, newRepo func(t *testing.T) repository.QuoteRepository) {
+	t.Run("InsertPendingQuote", func(t *testing.T) { testInsertPendingQuote(t, newRepo(t)) })
+	t.Run("SinglePendingPerCurrency", func(t *testing.T) { testSinglePendingPerCurrency(t, newRepo(t)) })
+	t.Run("UpdateQuote", func(t *testing.T) { testUpdateQuote(t, newRepo(t)) })
+	t.Run("GetQuoteByIdNotFound", func(t *testing.T) { testGetQuoteByIdNotFound(t, newRepo(t)) })
+	t.Run("GetLastQuote", func(t *testing.T) { testGetLastQuote(t, newRepo(t)) })
+	t.Run("GetCandles", func(t *testing.T) { testGetCandles(t, newRepo(t)) })
+	t.Run("ClaimPendingQuotes", func(t *testing.T) { testClaimPendingQuotes(t, newRepo(t)) })
+	t.Run("ExportQuotes", func(t *testing.T) { testExportQuotes(t, newRepo(t)) })
+	t.Run("InsertHistoricalQuote", func(t *testing.T) { testInsertHistoricalQuote(t, newRepo(t)) })
+	t.Run("SuspectQuotes", func(t *testing.T) { testSuspectQuotes(t, newRepo(t)) })
+}
+
+func testInsertPendingQuote(t *testing.T, repo repository.QuoteRepository) {
+	id, err := repo.InsertPendingQuote("USD/EUR", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id == "" {
+		t.Fatal("expected non-empty id")
+	}
+	q, err := repo.GetQuoteById(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.ID != id || q.Currency != "USD/EUR" || q.Status != model.StatusPending {
+		t.Errorf("unexpected quote: %+v", q)
+	}
+	if q.Price != nil {
+		t.Errorf("expected nil price for pending quote, got %v", *q.Price)
+	}
+	if q.RequestedBy != nil {
+		t.Errorf("expected no requester, got %v", *q.RequestedBy)
+	}
+}
+
+func testSinglePendingPerCurrency(t *testing.T, repo repository.QuoteRepository) {
+	id, err := repo.InsertPendingQuote("USD/EUR", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := repo.InsertPendingQuote("USD/EUR", ""); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows for second pending quote, got %v", err)
+	}
+	if _, err := repo.InsertPendingQuote("EUR/USD", ""); err != nil {
+		t.Fatalf("pending quote for other currency should be allowed: %v", err)
+	}
+	if err := repo.UpdateQuote(id, 0.9, model.StatusDone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := repo.InsertPendingQuote("USD/EUR", ""); err != nil {
+		t.Fatalf("pending quote should be allowed after completion: %v", err)
+	}
+}
+
+func testUpdateQuote(t *testing.T, repo repository.QuoteRepository) {
+	id, err := repo.InsertPendingQuote("USD/EUR", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	before := time.Now().Add(-time.Minute)
+	if err := repo.UpdateQuote(id, 0.91, model.StatusDone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q, err := repo.GetQuoteById(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.Status != model.StatusDone {
+		t.Errorf("expected status done, got %s", q.Status)
+	}
+	if q.Price == nil || *q.Price != 0.91 {
+		t.Errorf("expected price 0.91, got %v", q.Price)
+	}
+	if q.UpdatedAt == nil || q.UpdatedAt.Before(before) {
+		t.Errorf("expected updated_at to be set, got %v", q.UpdatedAt)
+	}
+}
+
+func testGetQuoteByIdNotFound(t *testing.T, repo repository.QuoteRepository) {
+	if _, err := repo.GetQuoteById("00000000-0000-0000-0000-000000000000"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func testGetLastQuote(t *testing.T, repo repository.QuoteRepository) {
+	if _, err := repo.GetLastQuote("USD/EUR", model.StatusDone); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	firstId := insertDone(t, repo, "USD/EUR", 0.9)
+	secondId := insertDone(t, repo, "USD/EUR", 0.95)
+	insertDone(t, repo, "EUR/USD", 1.05)
+	pendingId, err := repo.InsertPendingQuote("USD/EUR", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	q, err := repo.GetLastQuote("USD/EUR", model.StatusDone)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.ID != secondId || q.ID == firstId {
+		t.Errorf("expected latest done quote %s, got %s", secondId, q.ID)
+	}
+	if q.Price == nil || *q.Price != 0.95 {
+		t.Errorf("expected price 0.95, got %v", q.Price)
+	}
+
+	q, err = repo.GetLastQuote("USD/EUR", model.StatusPending)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.ID != pendingId {
+		t.Errorf("expected pending quote %s, got %s", pendingId, q.ID)
+	}
+}
+
+func testGetCandles(t *testing.T, repo repository.QuoteRepository) {
+	prices := []float64{1.10, 1.30, 1.00, 1.20}
+	for _, p := range prices {
+		insertDone(t, repo, "EUR/USD", p)
+	}
+	insertDone(t, repo, "USD/EUR", 5)
+	failedId, err := repo.InsertPendingQuote("EUR/USD", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.UpdateQuote(failedId, 0, model.StatusError); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	candles, err := repo.GetCandles("EUR/USD", time.Minute, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(candles) == 0 {
+		t.Fatal("expected at least one candle")
+	}
+	var count int64
+	high, low := candles[0].High, candles[0].Low
+	for i, c := range candles {
+		if i > 0 && !c.Bucket.After(candles[i-1].Bucket) {
+			t.Errorf("candles are not ordered by bucket: %v", candles)
+		}
+		if c.Bucket.Unix()%60 != 0 {
+			t.Errorf("bucket %v is not aligned to the interval", c.Bucket)
+		}
+		count += c.Count
+		high = max(high, c.High)
+		low = min(low, c.Low)
+	}
+	if count != int64(len(prices)) {
+		t.Errorf("expected %d quotes in candles, got %d", len(prices), count)
+	}
+	if candles[0].Open != prices[0] {
+		t.Errorf("expected open %v, got %v", prices[0], candles[0].Open)
+	}
+	if last := candles[len(candles)-1]; last.Close != prices[len(prices)-1] {
+		t.Errorf("expected close %v, got %v", prices[len(prices)-1], last.Close)
+	}
+	if high != 1.30 || low != 1.00 {
+		t.Errorf("expected high 1.30 and low 1.00, got %v and %v", high, low)
+	}
+
+	empty, err := repo.GetCandles("EUR/USD", time.Minute, now.Add(time.Hour), now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(empty) != 0 {
+		t.Errorf("expected no candles outside of the range, got %d", len(empty))
+	}
+}
+
+func testClaimPendingQuotes(t *testing.T, repo repository.QuoteRepository) {
+	now := time.Now().UTC().Truncate(time.Second)
+	claim := func(owner string, at time.Duration) []string {
+		t.Helper()
+		quotes, err := repo.ClaimPendingQuotes(owner, now.Add(at), now.Add(at+time.Minute))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids := make([]string, 0, len(quotes))
+		for _, q := range quotes {
+			if q.Status != model.StatusPending {
+				t.Errorf("unexpected status %s", q.Status)
+			}
+			ids = append(ids, q.ID)
+		}
+		return ids
+	}
+	if ids := claim("a", 0); len(ids) != 0 {
+		t.Fatalf("expected no pending quotes, got %v", ids)
+	}
+	done := insertDone(t, repo, "USD/EUR", 0.9)
+	first, err := repo.InsertPendingQuote("USD/EUR", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := repo.InsertPendingQuote("EUR/USD", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.ClaimQuote(first, "a", now.Add(time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.ClaimQuote(first, "b", now.Add(time.Minute)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for a claimed quote, got %v", err)
+	}
+	if err := repo.ClaimQuote(done, "b", now.Add(time.Minute)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for a done quote, got %v", err)
+	}
+
+	if ids := claim("b", 0); len(ids) != 0 {
+		t.Errorf("expected an unclaimed quote to be left to its inserter at first, got %v", ids)
+	}
+	if ids := claim("b", time.Second); len(ids) != 1 || ids[0] != second {
+		t.Errorf("expected quote %s to be claimed once seen unclaimed, got %v", second, ids)
+	}
+	if ids := claim("c", 2*time.Second); len(ids) != 0 {
+		t.Errorf("expected live claims to be kept, got %v", ids)
+	}
+	if ids := claim("a", 30*time.Second); len(ids) != 0 {
+		t.Errorf("expected renewed claims not to be returned, got %v", ids)
+	}
+	if ids := claim("c", 80*time.Second); len(ids) != 1 || ids[0] != second {
+		t.Errorf("expected only the expired claim on %s to be taken over, got %v", second, ids)
+	}
+	if err := repo.UpdateQuote(first, 0.91, model.StatusDone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ids := claim("d", time.Hour); len(ids) != 1 || ids[0] != second {
+		t.Errorf("expected only the pending quote %s to be claimed, got %v", second, ids)
+	}
+}
+
+func testExportQuotes(t *testing.T, repo repository.QuoteRepository) {
+	prices := []float64{1.10, 1.30, 1.00}
+	for _, p := range prices {
+		insertDone(t, repo, "EUR/USD", p)
+	}
+	insertDone(t, repo, "USD/EUR", 5)
+	if _, err := repo.InsertPendingQuote("EUR/USD", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	var exported []model.Quote
+	err := repo.ExportQuotes("EUR/USD", now.Add(-time.Hour), now.Add(time.Hour), func(q model.Quote) error {
+		exported = append(exported, q)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exported) != len(prices) {
+		t.Fatalf("expected %d quotes, got %d", len(prices), len(exported))
+	}
+	for i, q := range exported {
+		if q.Currency != "EUR/USD" || q.Status != model.StatusDone || q.Price == nil || *q.Price != prices[i] || q.UpdatedAt == nil {
+			t.Errorf("unexpected quote %d: %+v", i, q)
+		}
+		if i > 0 && q.UpdatedAt.Before(*exported[i-1].UpdatedAt) {
+			t.Errorf("quotes are not ordered by update time")
+		}
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = repo.ExportQuotes("EUR/USD", now.Add(-time.Hour), now.Add(time.Hour), func(model.Quote) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("expected the callback error to stop the export, got %v after %d calls", err, calls)
+	}
+
+	err = repo.ExportQuotes("EUR/USD", now.Add(time.Hour), now.Add(2*time.Hour), func(model.Quote) error {
+		t.Errorf("expected no quotes outside of the range")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func testInsertHistoricalQuote(t *testing.T, repo repository.QuoteRepository) {
+	live := insertDone(t, repo, "USD/EUR", 0.93)
+	at := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	id, err := repo.InsertHistoricalQuote("USD/EUR", 0.89, at, model.SourceImport)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q, err := repo.GetQuoteById(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.Status != model.StatusDone || q.Price == nil || *q.Price != 0.89 || q.UpdatedAt == nil || !q.UpdatedAt.Equal(at) {
+		t.Errorf("unexpected quote %+v", q)
+	}
+
+	if _, err := repo.InsertHistoricalQuote("USD/EUR", 0.90, at, model.SourceImport); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for a duplicate, got %v", err)
+	}
+	if _, err := repo.InsertHistoricalQuote("EUR/USD", 1.12, at, model.SourceImport); err != nil {
+		t.Errorf("expected another pair at the same time to be stored, got %v", err)
+	}
+
+	last, err := repo.GetLastQuote("USD/EUR", model.StatusDone)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if last.ID != live {
+		t.Errorf("expected the live quote to stay the latest, got %+v", last)
+	}
+	var exported []string
+	err = repo.ExportQuotes("USD/EUR", at, at.Add(time.Hour), func(q model.Quote) error {
+		exported = append(exported, q.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exported) != 1 || exported[0] != id {
+		t.Errorf("expected the historical quote in its range, got %v", exported)
+	}
+}
+
+func testSuspectQuotes(t *testing.T, repo repository.QuoteRepository) {
+	done := insertDone(t, repo, "EUR/USD", 1.10)
+	id, err := repo.InsertPendingQuote("EUR/USD", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.UpdateQuote(id, 1.50, model.StatusSuspect); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	suspect, err := repo.ListSuspectQuotes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(suspect) != 1 || suspect[0].ID != id || suspect[0].Price == nil || *suspect[0].Price != 1.50 {
+		t.Fatalf("expected suspect quote %s, got %+v", id, suspect)
+	}
+	last, err := repo.GetLastQuote("EUR/USD", model.StatusDone)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if last.ID != done {
+		t.Errorf("expected the suspect quote to be left out of the last done quote, got %+v", last)
+	}
+	// A suspect quote no longer blocks new pending quotes of its pair.
+	if _, err := repo.InsertPendingQuote("EUR/USD", ""); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := repo.SetQuoteStatus(done, model.StatusSuspect, model.StatusDone); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for a quote in another status, got %v", err)
+	}
+	if err := repo.SetQuoteStatus("00000000-0000-0000-0000-000000000000", model.StatusSuspect, model.StatusDone); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for an unknown quote, got %v", err)
+	}
+	before, err := repo.GetQuoteById(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.SetQuoteStatus(id, model.StatusSuspect, model.StatusDone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q, err := repo.GetQuoteById(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.Status != model.StatusDone || *q.Price != 1.50 || !q.UpdatedAt.Equal(*before.UpdatedAt) {
+		t.Errorf("expected the price and update time to be kept, got %+v", q)
+	}
+	if suspect, _ := repo.ListSuspectQuotes(); len(suspect) != 0 {
+		t.Errorf("expected no suspect quotes left, got %+v", suspect)
+	}
+}
+
+func insertDone(t *testing.T, repo repository.QuoteRepository, currency string, price float64) string {
+	t.Helper()
+	id, err := repo.InsertPendingQuote(currency, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.UpdateQuote(id, price, model.StatusDone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return id
+}
+
+// RunAPIKeyRepositoryTests checks an API key backend. newRepos returns both
+// repositories over the same storage, since quotes reference the keys that
+// requested them.
+func RunAPIKeyRepositoryTests(t *testing.T, newRepos func(t *testing.T) (repository.APIKeyRepository, repository.QuoteRepository)) {
+	t.Run("CreateAndGet", func(t *testing.T) {
+		keys, _ := newRepos(t)
+		testCreateAndGetAPIKey(t, keys)
+	})
+	t.Run("Revoke", func(t *testing.T) {
+		keys, _ := newRepos(t)
+		testRevokeAPIKey(t, keys)
+	})
+	t.Run("QuoteRequestedBy", func(t *testing.T) {
+		keys, quotes := newRepos(t)
+		testQuoteRequestedBy(t, keys, quotes)
+	})
+}
+
+func testCreateAndGetAPIKey(t *testing.T, repo repository.APIKeyRepository) {
+	if _, err := repo.GetAPIKeyByHash("missing"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+	created, err := repo.CreateAPIKey("reader", "hash-1", []model.Scope{model.ScopeQuotesRead})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.ID == "" || created.CreatedAt.IsZero() || created.Revoked() {
+		t.Errorf("unexpected key: %+v", created)
+	}
+	if _, err := repo.CreateAPIKey("copy", "hash-1", []model.Scope{model.ScopeAdmin}); err == nil {
+		t.Error("expected error for duplicate hash, got nil")
+	}
+	if _, err := repo.CreateAPIKey("admin", "hash-2", []model.Scope{model.ScopeQuotesUpdate, model.ScopeAdmin}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := repo.GetAPIKeyByHash("hash-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.ID != created.ID || got.Name != "reader" || len(got.Scopes) != 1 || got.Scopes[0] != model.ScopeQuotesRead {
+		t.Errorf("unexpected key: %+v", got)
+	}
+
+	keys, err := repo.ListAPIKeys()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(keys) != 2 || keys[0].Name != "reader" || len(keys[1].Scopes) != 2 {
+		t.Errorf("unexpected keys: %+v", keys)
+	}
+}
+
+func testRevokeAPIKey(t *testing.T, repo repository.APIKeyRepository) {
+	created, err := repo.CreateAPIKey("reader", "hash-1", []model.Scope{model.ScopeQuotesRead})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.RevokeAPIKey(created.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := repo.GetAPIKeyByHash("hash-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.Revoked() {
+		t.Error("expected key to be revoked")
+	}
+	if err := repo.RevokeAPIKey(created.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for revoked key, got %v", err)
+	}
+	if err := repo.RevokeAPIKey("00000000-0000-0000-0000-000000000000"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for unknown key, got %v", err)
+	}
+}
+
+func testQuoteRequestedBy(t *testing.T, keys repository.APIKeyRepository, quotes repository.QuoteRepository) {
+	key, err := keys.CreateAPIKey("updater", "hash-1", []model.Scope{model.ScopeQuotesUpdate})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	id, err := quotes.InsertPendingQuote("USD/EUR", key.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q, err := quotes.GetQuoteById(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.RequestedBy == nil || *q.RequestedBy != key.ID {
+		t.Errorf("expected requested_by %s, got %v", key.ID, q.RequestedBy)
+	}
+}
+
+func RunProviderQuotaRepositoryTests(t *testing.T, newRepo func(t *testing.T) repository.ProviderQuotaRepository) {
+	repo := newRepo(t)
+	used, err := repo.GetQuotaUsed("vatcomply", "2025-01-01")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if used != 0 {
+		t.Fatalf("expected no usage, got %d", used)
+	}
+	for i, want := range []bool{true, true, false, false} {
+		ok, err := repo.UseQuota("vatcomply", "2025-01-01", 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ok != want {
+			t.Fatalf("call %d: expected %v, got %v", i, want, ok)
+		}
+	}
+	if ok, err := repo.UseQuota("vatcomply", "2025-01-02", 2); err != nil || !ok {
+		t.Errorf("next day must have its own quota: %v %v", ok, err)
+	}
+	if ok, err := repo.UseQuota("other", "2025-01-01", 2); err != nil || !ok {
+		t.Errorf("other provider must have its own quota: %v %v", ok, err)
+	}
+	used, err = repo.GetQuotaUsed("vatcomply", "2025-01-01")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if used != 2 {
+		t.Errorf("expected 2 calls counted, got %d", used)
+	}
+}
+
+func RunIdempotencyRepositoryTests(t *testing.T, newRepo func(t *testing.T) repository.IdempotencyRepository) {
+	repo := newRepo(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	window := now.Add(-time.Hour)
+
+	if _, err := repo.GetIdempotencyRecord("key-1", "retry-1", window); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+	first := model.IdempotencyRecord{ClientID: "key-1", Key: "retry-1", RequestHash: "h1", RequestID: "q1", StatusCode: 200, CreatedAt: now, Fresh: true}
+	kept, err := repo.SaveIdempotencyRecord(first, window)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kept.RequestID != "q1" || kept.RequestHash != "h1" || kept.StatusCode != 200 || !kept.CreatedAt.Equal(now) || !kept.Fresh {
+		t.Errorf("unexpected record %+v", kept)
+	}
+
+	second := first
+	second.RequestHash, second.RequestID = "h2", "q2"
+	kept, err = repo.SaveIdempotencyRecord(second, window)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kept.RequestID != "q1" {
+		t.Errorf("expected the first record to be kept, got %+v", kept)
+	}
+	other := second
+	other.ClientID = "key-2"
+	if kept, err := repo.SaveIdempotencyRecord(other, window); err != nil || kept.RequestID != "q2" {
+		t.Errorf("keys must be scoped per client: %+v %v", kept, err)
+	}
+
+	got, err := repo.GetIdempotencyRecord("key-1", "retry-1", window)
+	if err != nil || got.RequestID != "q1" {
+		t.Errorf("unexpected record %+v, %v", got, err)
+	}
+	later := now.Add(time.Second)
+	if _, err := repo.GetIdempotencyRecord("key-1", "retry-1", later); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected an expired record to be absent, got %v", err)
+	}
+	second.CreatedAt = later
+	if kept, err := repo.SaveIdempotencyRecord(second, later); err != nil || kept.RequestID != "q2" {
+		t.Errorf("expected an expired record to be replaced: %+v %v", kept, err)
+	}
+
+	n, err := repo.DeleteIdempotencyRecords(later)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 record deleted, got %d", n)
+	}
+	if _, err := repo.GetIdempotencyRecord("key-2", "retry-1", window); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected the old record to be deleted, got %v", err)
+	}
+}
+
+// RunBackfillRepositoryTests checks a backfill backend.
+func RunBackfillRepositoryTests(t *testing.T, newRepo func(t *testing.T) repository.BackfillRepository) {
+	repo := newRepo(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, err := repo.GetBackfill("unknown"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+	first, err := repo.CreateBackfill(model.Backfill{Currency: "USD/EUR", From: from, To: from.AddDate(0, 0, 9), Next: from, Status: model.BackfillPending, CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.ID == "" {
+		t.Fatal("expected an id")
+	}
+	second, err := repo.CreateBackfill(model.Backfill{Currency: "EUR/USD", From: from, To: from, Next: from, Status: model.BackfillPending, CreatedAt: now.Add(time.Second), UpdatedAt: now.Add(time.Second)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	first.Next = from.AddDate(0, 0, 3)
+	first.Status = model.BackfillFailed
+	first.Inserted, first.Duplicates = 2, 1
+	first.Error = "upstream down"
+	first.UpdatedAt = now.Add(time.Minute)
+	if err := repo.UpdateBackfill(first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := repo.GetBackfill(first.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Currency != "USD/EUR" || !got.From.Equal(from) || !got.To.Equal(from.AddDate(0, 0, 9)) || !got.Next.Equal(first.Next) ||
+		got.Status != model.BackfillFailed || got.Inserted != 2 || got.Duplicates != 1 || got.Error != "upstream down" ||
+		!got.CreatedAt.Equal(now) || !got.UpdatedAt.Equal(first.UpdatedAt) {
+		t.Errorf("unexpected backfill %+v", got)
+	}
+
+	list, err := repo.ListBackfills()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != first.ID || list[1].ID != second.ID {
+		t.Errorf("expected both backfills oldest first, got %+v", list)
+	}
+
+	if err := repo.UpdateBackfill(model.Backfill{ID: "unknown"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	claim := func(id, owner string, at time.Duration) error {
+		return repo.ClaimBackfill(id, owner, now.Add(at), now.Add(at+time.Minute))
+	}
+	if err := claim(first.ID, "a", 0); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected a failed backfill not to be claimed, got %v", err)
+	}
+	if err := claim("unknown", "a", 0); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+	if err := claim(second.ID, "a", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := claim(second.ID, "b", 30*time.Second); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected a leased backfill not to be claimed, got %v", err)
+	}
+	if err := claim(second.ID, "a", 30*time.Second); err != nil {
+		t.Errorf("expected the owner to renew its lease, got %v", err)
+	}
+	if err := claim(second.ID, "b", 2*time.Minute); err != nil {
+		t.Fatalf("expected an expired lease to be taken over, got %v", err)
+	}
+	second.Status = model.BackfillRunning
+	second.Owner = "a"
+	if err := repo.UpdateBackfill(second); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected a former owner not to save progress, got %v", err)
+	}
+	second.Owner = ""
+	if err := repo.UpdateBackfill(second); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected a leased backfill not to be saved without its owner, got %v", err)
+	}
+	second.Owner = "b"
+	if err := repo.UpdateBackfill(second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, err := repo.GetBackfill(second.ID); err != nil || got.Owner != "b" || got.Status != model.BackfillRunning {
+		t.Errorf("expected the backfill to run for b, got %+v, %v", got, err)
+	}
+	second.Status = model.BackfillDone
+	if err := repo.UpdateBackfill(second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, err := repo.GetBackfill(second.ID); err != nil || got.Owner != "" {
+		t.Errorf("expected a finished backfill to be released, got %+v, %v", got, err)
+	}
+}
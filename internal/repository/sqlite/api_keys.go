@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"FinQuotesService/internal/model"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type APIKeyRepository struct {
+	CreateStmt    *sql.Stmt
+	GetByHashStmt *sql.Stmt
+	ListStmt      *sql.Stmt
+	RevokeStmt    *sql.Stmt
+}
+
+func NewAPIKeyRepository(db *sql.DB) (*APIKeyRepository, error) {
+	var r APIKeyRepository
+	statements := []struct {
+		dst   **sql.Stmt
+		query string
+	}{
+		{&r.CreateStmt, `INSERT INTO api_keys (id, name, key_hash, scopes, created_at) VALUES (?1, ?2, ?3, ?4, ?5) RETURNING id, name, key_hash, scopes, created_at, revoked_at`},
+		{&r.GetByHashStmt, `SELECT id, name, key_hash, scopes, created_at, revoked_at FROM api_keys WHERE key_hash=?1`},
+		{&r.ListStmt, `SELECT id, name, key_hash, scopes, created_at, revoked_at FROM api_keys ORDER BY created_at, id`},
+		{&r.RevokeStmt, `UPDATE api_keys SET revoked_at=?1 WHERE id=?2 AND revoked_at IS NULL`},
+	}
+	for _, st := range statements {
+		stmt, err := db.Prepare(st.query)
+		if err != nil {
+			return nil, err
+		}
+		*st.dst = stmt
+	}
+	return &r, nil
+}
+
+func (r *APIKeyRepository) CreateAPIKey(name, hash string, scopes []model.Scope) (model.APIKey, error) {
+	return scanAPIKey(r.CreateStmt.QueryRow(uuid.New().String(), name, hash, model.FormatScopes(scopes), formatTime(time.Now())))
+}
+
+func (r *APIKeyRepository) GetAPIKeyByHash(hash string) (model.APIKey, error) {
+	return scanAPIKey(r.GetByHashStmt.QueryRow(hash))
+}
+
+func (r *APIKeyRepository) ListAPIKeys() ([]model.APIKey, error) {
+	rows, err := r.ListStmt.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := make([]model.APIKey, 0)
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *APIKeyRepository) RevokeAPIKey(id string) error {
+	res, err := r.RevokeStmt.Exec(formatTime(time.Now()), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func scanAPIKey(row interface{ Scan(...any) error }) (model.APIKey, error) {
+	var k model.APIKey
+	var scopes string
+	if err := row.Scan(&k.ID, &k.Name, &k.Hash, &scopes, &k.CreatedAt, &k.RevokedAt); err != nil {
+		return model.APIKey{}, err
+	}
+	var err error
+	k.Scopes, err = model.ParseScopes(scopes)
+	return k, err
+}
@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"FinQuotesService/internal/model"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BackfillRepository struct {
+	CreateStmt *sql.Stmt
+	GetStmt    *sql.Stmt
+	ListStmt   *sql.Stmt
+	UpdateStmt *sql.Stmt
+	ClaimStmt  *sql.Stmt
+}
+
+func NewBackfillRepository(db *sql.DB) (*BackfillRepository, error) {
+	var r BackfillRepository
+	statements := []struct {
+		dst   **sql.Stmt
+		query string
+	}{
+		{&r.CreateStmt, `INSERT INTO backfills (id, currency, from_day, to_day, next_day, status, inserted, duplicates, error, created_at, updated_at) VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11)`},
+		{&r.GetStmt, `SELECT id, currency, from_day, to_day, next_day, status, inserted, duplicates, error, created_at, updated_at, COALESCE(owner, '') FROM backfills WHERE id=?1`},
+		{&r.ListStmt, `SELECT id, currency, from_day, to_day, next_day, status, inserted, duplicates, error, created_at, updated_at, COALESCE(owner, '') FROM backfills ORDER BY created_at, id`},
+		{&r.UpdateStmt, `UPDATE backfills SET next_day=?1, status=?2, inserted=?3, duplicates=?4, error=?5, updated_at=?6, owner=NULLIF(?9, '') WHERE id=?7 AND COALESCE(owner, '')=?8`},
+		{&r.ClaimStmt, `UPDATE backfills SET owner=?2, lease_until=?4 WHERE id=?1 AND status IN ('pending', 'running') AND (owner IS NULL OR owner=?2 OR lease_until < ?3)`},
+	}
+	for _, st := range statements {
+		stmt, err := db.Prepare(st.query)
+		if err != nil {
+			return nil, err
+		}
+		*st.dst = stmt
+	}
+	return &r, nil
+}
+
+func (r *BackfillRepository) CreateBackfill(b model.Backfill) (model.Backfill, error) {
+	b.ID = uuid.New().String()
+	_, err := r.CreateStmt.Exec(b.ID, b.Currency, formatTime(b.From), formatTime(b.To), formatTime(b.Next), b.Status, b.Inserted, b.Duplicates, b.Error, formatTime(b.CreatedAt), formatTime(b.UpdatedAt))
+	return b, err
+}
+
+func (r *BackfillRepository) GetBackfill(id string) (model.Backfill, error) {
+	return scanBackfill(r.GetStmt.QueryRow(id))
+}
+
+func (r *BackfillRepository) ListBackfills() ([]model.Backfill, error) {
+	rows, err := r.ListStmt.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	backfills := make([]model.Backfill, 0)
+	for rows.Next() {
+		b, err := scanBackfill(rows)
+		if err != nil {
+			return nil, err
+		}
+		backfills = append(backfills, b)
+	}
+	return backfills, rows.Err()
+}
+
+func (r *BackfillRepository) ClaimBackfill(id, owner string, now, until time.Time) error {
+	res, err := r.ClaimStmt.Exec(id, owner, formatTime(now), formatTime(until))
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *BackfillRepository) UpdateBackfill(b model.Backfill) error {
+	owner := b.Owner
+	if !b.Active() {
+		owner = ""
+	}
+	res, err := r.UpdateStmt.Exec(formatTime(b.Next), b.Status, b.Inserted, b.Duplicates, b.Error, formatTime(b.UpdatedAt), b.ID, b.Owner, owner)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func scanBackfill(row interface{ Scan(...any) error }) (model.Backfill, error) {
+	var b model.Backfill
+	err := row.Scan(&b.ID, &b.Currency, &b.From, &b.To, &b.Next, &b.Status, &b.Inserted, &b.Duplicates, &b.Error, &b.CreatedAt, &b.UpdatedAt, &b.Owner)
+	return b, err
+}
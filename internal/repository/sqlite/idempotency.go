@@ -0,0 +1,56 @@
+package sqlite
+
+import (
+	"FinQuotesService/internal/model"
+	"database/sql"
+	"time"
+)
+
+type IdempotencyRepository struct {
+	SaveStmt   *sql.Stmt
+	GetStmt    *sql.Stmt
+	DeleteStmt *sql.Stmt
+}
+
+func NewIdempotencyRepository(db *sql.DB) (*IdempotencyRepository, error) {
+	var r IdempotencyRepository
+	statements := []struct {
+		dst   **sql.Stmt
+		query string
+	}{
+		{&r.SaveStmt, `INSERT INTO idempotency_keys AS k (client_id, key, request_hash, request_id, status_code, created_at, fresh) VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7) ON CONFLICT (client_id, key) DO UPDATE SET request_hash=EXCLUDED.request_hash, request_id=EXCLUDED.request_id, status_code=EXCLUDED.status_code, created_at=EXCLUDED.created_at, fresh=EXCLUDED.fresh WHERE k.created_at < ?8`},
+		{&r.GetStmt, `SELECT client_id, key, request_hash, request_id, status_code, created_at, fresh FROM idempotency_keys WHERE client_id=?1 AND key=?2 AND created_at >= ?3`},
+		{&r.DeleteStmt, `DELETE FROM idempotency_keys WHERE created_at < ?1`},
+	}
+	for _, st := range statements {
+		stmt, err := db.Prepare(st.query)
+		if err != nil {
+			return nil, err
+		}
+		*st.dst = stmt
+	}
+	return &r, nil
+}
+
+// SaveIdempotencyRecord only overwrites expired records, so of two requests
+// racing with the same key the first one to be saved wins.
+func (r *IdempotencyRepository) SaveIdempotencyRecord(rec model.IdempotencyRecord, notBefore time.Time) (model.IdempotencyRecord, error) {
+	if _, err := r.SaveStmt.Exec(rec.ClientID, rec.Key, rec.RequestHash, rec.RequestID, rec.StatusCode, formatTime(rec.CreatedAt), rec.Fresh, formatTime(notBefore)); err != nil {
+		return model.IdempotencyRecord{}, err
+	}
+	return r.GetIdempotencyRecord(rec.ClientID, rec.Key, notBefore)
+}
+
+func (r *IdempotencyRepository) GetIdempotencyRecord(clientID, key string, notBefore time.Time) (model.IdempotencyRecord, error) {
+	var rec model.IdempotencyRecord
+	err := r.GetStmt.QueryRow(clientID, key, formatTime(notBefore)).Scan(&rec.ClientID, &rec.Key, &rec.RequestHash, &rec.RequestID, &rec.StatusCode, &rec.CreatedAt, &rec.Fresh)
+	return rec, err
+}
+
+func (r *IdempotencyRepository) DeleteIdempotencyRecords(before time.Time) (int64, error) {
+	res, err := r.DeleteStmt.Exec(formatTime(before))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,219 @@
+package sqlite
+
+import (
+	"FinQuotesService/internal/model"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// timeLayout is fixed-width UTC so that stored timestamps sort correctly as
+// text and stay readable by SQLite date functions. The driver parses it back
+// into time.Time for TIMESTAMP columns.
+const timeLayout = "2006-01-02 15:04:05.000000"
+
+type QuoteRepository struct {
+	InsertPendingStmt    *sql.Stmt
+	UpdateQuoteStmt      *sql.Stmt
+	GetQuoteByIdStmt     *sql.Stmt
+	GetLastQuoteStmt     *sql.Stmt
+	GetCandlesStmt       *sql.Stmt
+	ClaimStmt            *sql.Stmt
+	RenewClaimsStmt      *sql.Stmt
+	TakeClaimsStmt       *sql.Stmt
+	MarkUnclaimedStmt    *sql.Stmt
+	ExportStmt           *sql.Stmt
+	InsertHistoricalStmt *sql.Stmt
+	ListSuspectStmt      *sql.Stmt
+	SetStatusStmt        *sql.Stmt
+}
+
+func NewQuoteRepository(db *sql.DB) (*QuoteRepository, error) {
+	var r QuoteRepository
+	statements := []struct {
+		dst   **sql.Stmt
+		query string
+	}{
+		{&r.InsertPendingStmt, `INSERT INTO quotes (id, currency, status, requested_by) VALUES (?1, ?2, 'pending', NULLIF(?3, '')) ON CONFLICT (currency) WHERE status = 'pending' DO NOTHING RETURNING id`},
+		{&r.UpdateQuoteStmt, `UPDATE quotes SET price=?1, updated_at=?2, status=?3 WHERE id=?4`},
+		{&r.GetQuoteByIdStmt, `SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE id=?1`},
+		{&r.GetLastQuoteStmt, `SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=?1 AND status=?2 ORDER BY updated_at DESC LIMIT 1`},
+		{&r.GetCandlesStmt, `SELECT DISTINCT bucket,
+			first_value(price) OVER w AS open,
+			max(price) OVER w AS high,
+			min(price) OVER w AS low,
+			last_value(price) OVER w AS close,
+			count(*) OVER w AS count
+		FROM (
+			SELECT (CAST(strftime('%s', updated_at) AS INTEGER) / ?2) * ?2 AS bucket, price, updated_at
+			FROM quotes
+			WHERE currency=?1 AND status='done' AND price IS NOT NULL AND updated_at >= ?3 AND updated_at < ?4
+		)
+		WINDOW w AS (PARTITION BY bucket ORDER BY updated_at ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING)
+		ORDER BY bucket`},
+		{&r.ClaimStmt, `UPDATE quotes SET claimed_by=?2, claimed_until=?3 WHERE id=?1 AND status='pending' AND claimed_by IS NULL`},
+		{&r.RenewClaimsStmt, `UPDATE quotes SET claimed_until=?2 WHERE status='pending' AND claimed_by=?1`},
+		{&r.TakeClaimsStmt, `UPDATE quotes SET claimed_by=?1, claimed_until=?3 WHERE status='pending' AND claimed_until < ?2 RETURNING id, currency, price, updated_at, status, requested_by`},
+		{&r.MarkUnclaimedStmt, `UPDATE quotes SET claimed_until=?1 WHERE status='pending' AND claimed_until IS NULL`},
+		{&r.ExportStmt, `SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE currency=?1 AND status='done' AND price IS NOT NULL AND updated_at >= ?2 AND updated_at < ?3 ORDER BY updated_at, id`},
+		{&r.InsertHistoricalStmt, `INSERT INTO quotes (id, currency, price, updated_at, status, source) VALUES (?1, ?2, ?3, ?4, 'done', ?5) ON CONFLICT (currency, updated_at) WHERE source IS NOT NULL DO NOTHING RETURNING id`},
+		{&r.ListSuspectStmt, `SELECT id, currency, price, updated_at, status, requested_by FROM quotes WHERE status='suspect' ORDER BY updated_at, id`},
+		{&r.SetStatusStmt, `UPDATE quotes SET status=?3 WHERE id=?1 AND status=?2`},
+	}
+	for _, st := range statements {
+		stmt, err := db.Prepare(st.query)
+		if err != nil {
+			return nil, err
+		}
+		*st.dst = stmt
+	}
+	return &r, nil
+}
+
+func (r *QuoteRepository) InsertPendingQuote(currency, requestedBy string) (string, error) {
+	var id string
+	row := r.InsertPendingStmt.QueryRow(uuid.New().String(), currency, requestedBy)
+	err := row.Scan(&id)
+	return id, err
+}
+
+func (r *QuoteRepository) UpdateQuote(id string, price float64, status model.Status) error {
+	_, err := r.UpdateQuoteStmt.Exec(price, formatTime(time.Now()), status, id)
+	return err
+}
+
+func (r *QuoteRepository) GetQuoteById(id string) (model.Quote, error) {
+	return scanQuote(r.GetQuoteByIdStmt.QueryRow(id))
+}
+
+func (r *QuoteRepository) GetLastQuote(currency string, status model.Status) (model.Quote, error) {
+	return scanQuote(r.GetLastQuoteStmt.QueryRow(currency, status))
+}
+
+func (r *QuoteRepository) GetCandles(currency string, interval time.Duration, from, to time.Time) ([]model.Candle, error) {
+	rows, err := r.GetCandlesStmt.Query(currency, int64(interval/time.Second), formatTime(from), formatTime(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	candles := make([]model.Candle, 0)
+	for rows.Next() {
+		var c model.Candle
+		var bucket int64
+		if err := rows.Scan(&bucket, &c.Open, &c.High, &c.Low, &c.Close, &c.Count); err != nil {
+			return nil, err
+		}
+		c.Bucket = time.Unix(bucket, 0).UTC()
+		candles = append(candles, c)
+	}
+	return candles, rows.Err()
+}
+
+func (r *QuoteRepository) ClaimQuote(id, owner string, until time.Time) error {
+	res, err := r.ClaimStmt.Exec(id, owner, formatTime(until))
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *QuoteRepository) ClaimPendingQuotes(owner string, now, until time.Time) ([]model.Quote, error) {
+	if _, err := r.RenewClaimsStmt.Exec(owner, formatTime(until)); err != nil {
+		return nil, err
+	}
+	rows, err := r.TakeClaimsStmt.Query(owner, formatTime(now), formatTime(until))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	quotes := make([]model.Quote, 0)
+	for rows.Next() {
+		q, err := scanQuote(rows)
+		if err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if _, err := r.MarkUnclaimedStmt.Exec(formatTime(now)); err != nil {
+		return nil, err
+	}
+	return quotes, nil
+}
+
+func (r *QuoteRepository) ExportQuotes(currency string, from, to time.Time, fn func(model.Quote) error) error {
+	rows, err := r.ExportStmt.Query(currency, formatTime(from), formatTime(to))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		q, err := scanQuote(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(q); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func scanQuote(row interface{ Scan(...any) error }) (model.Quote, error) {
+	var q model.Quote
+	err := row.Scan(&q.ID, &q.Currency, &q.Price, &q.UpdatedAt, &q.Status, &q.RequestedBy)
+	return q, err
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func (r *QuoteRepository) InsertHistoricalQuote(currency string, price float64, updatedAt time.Time, source string) (string, error) {
+	var id string
+	err := r.InsertHistoricalStmt.QueryRow(uuid.New().String(), currency, price, formatTime(updatedAt), source).Scan(&id)
+	return id, err
+}
+
+func (r *QuoteRepository) ListSuspectQuotes() ([]model.Quote, error) {
+	rows, err := r.ListSuspectStmt.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	quotes := make([]model.Quote, 0)
+	for rows.Next() {
+		q, err := scanQuote(rows)
+		if err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, q)
+	}
+	return quotes, rows.Err()
+}
+
+func (r *QuoteRepository) SetQuoteStatus(id string, from, to model.Status) error {
+	res, err := r.SetStatusStmt.Exec(id, from, to)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package sqlite
+
+import (
+	"FinQuotesService/internal/db"
+	"FinQuotesService/internal/repository"
+	"FinQuotesService/internal/repository/repositorytest"
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	_, source := db.ParseDSN("sqlite://file::memory:")
+	conn, err := sql.Open(db.DriverSQLite, source)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+	migrator, err := db.NewMigrator(conn, db.DriverSQLite)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return conn
+}
+
+func TestQuoteRepository_Conformance(t *testing.T) {
+	repositorytest.RunQuoteRepositoryTests(t, func(t *testing.T) repository.QuoteRepository {
+		repo, err := NewQuoteRepository(newTestDB(t))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return repo
+	})
+}
+
+func TestAPIKeyRepository_Conformance(t *testing.T) {
+	repositorytest.RunAPIKeyRepositoryTests(t, func(t *testing.T) (repository.APIKeyRepository, repository.QuoteRepository) {
+		conn := newTestDB(t)
+		keys, err := NewAPIKeyRepository(conn)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		quotes, err := NewQuoteRepository(conn)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return keys, quotes
+	})
+}
+
+func TestProviderQuotaRepository_Conformance(t *testing.T) {
+	repositorytest.RunProviderQuotaRepositoryTests(t, func(t *testing.T) repository.ProviderQuotaRepository {
+		repo, err := NewProviderQuotaRepository(newTestDB(t))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return repo
+	})
+}
+
+func TestIdempotencyRepository_Conformance(t *testing.T) {
+	repositorytest.RunIdempotencyRepositoryTests(t, func(t *testing.T) repository.IdempotencyRepository {
+		repo, err := NewIdempotencyRepository(newTestDB(t))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return repo
+	})
+}
+
+func TestBackfillRepository_Conformance(t *testing.T) {
+	repositorytest.RunBackfillRepositoryTests(t, func(t *testing.T) repository.BackfillRepository {
+		repo, err := NewBackfillRepository(newTestDB(t))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return repo
+	})
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"FinQuotesService/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// minSigmaSamples is the number of changes needed before the spread of
+// recent history is trusted.
+const minSigmaSamples = 10
+
+// AnomalyRule bounds how far a new rate may move from the previous done
+// quote of its pair. A zero field disables its check.
+type AnomalyRule struct {
+	// MaxChange is the largest relative move, 0.05 being 5%.
+	MaxChange float64
+	// MaxSigma is the largest move in standard deviations of the moves seen
+	// over the detector's window.
+	MaxSigma float64
+}
+
+// AnomalyDetector flags rates that jump away from the previous done quote of
+// their pair, so they can be held for review instead of being served.
+type AnomalyDetector struct {
+	Srv     QuoteServiceInterface
+	Default AnomalyRule
+	// Pairs overrides Default for some pairs.
+	Pairs map[string]AnomalyRule
+	// Window is how much history MaxSigma is measured over.
+	Window time.Duration
+
+	now func() time.Time
+}
+
+func NewAnomalyDetector(srv QuoteServiceInterface, rule AnomalyRule, window time.Duration) *AnomalyDetector {
+	return &AnomalyDetector{Srv: srv, Default: rule, Window: window, now: time.Now}
+}
+
+// Rule returns the rule applied to currency.
+func (d *AnomalyDetector) Rule(currency string) AnomalyRule {
+	if rule, ok := d.Pairs[currency]; ok {
+		return rule
+	}
+	return d.Default
+}
+
+// Check returns why price is suspect for currency, or an empty reason when
+// it is not. A pair without a done quote yet has nothing to compare against.
+func (d *AnomalyDetector) Check(ctx context.Context, currency string, price float64) (string, error) {
+	rule := d.Rule(currency)
+	if rule.MaxChange <= 0 && rule.MaxSigma <= 0 {
+		return "", nil
+	}
+	prev, err := d.Srv.GetLastQuote(ctx, currency, model.StatusDone)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if prev.Price == nil || *prev.Price == 0 {
+		return "", nil
+	}
+	change := math.Abs(price / *prev.Price - 1)
+	if rule.MaxChange > 0 && change > rule.MaxChange {
+		return fmt.Sprintf("moved %.2f%% from %g, over the %.2f%% limit", change*100, *prev.Price, rule.MaxChange*100), nil
+	}
+	if rule.MaxSigma <= 0 || d.Window <= 0 {
+		return "", nil
+	}
+	sigma, ok, err := d.sigma(ctx, currency)
+	if err != nil || !ok {
+		return "", err
+	}
+	if moves := change / sigma; moves > rule.MaxSigma {
+		return fmt.Sprintf("moved %.1f standard deviations from %g, over the %g limit", moves, *prev.Price, rule.MaxSigma), nil
+	}
+	return "", nil
+}
+
+// sigma returns the standard deviation of the relative changes between
+// consecutive done quotes over the window. It reports false when there are
+// too few of them or they never moved.
+func (d *AnomalyDetector) sigma(ctx context.Context, currency string) (float64, bool, error) {
+	now := d.now()
+	var changes []float64
+	var last float64
+	err := d.Srv.ExportQuotes(ctx, currency, now.Add(-d.Window), now, func(q model.Quote) error {
+		if q.Price == nil {
+			return nil
+		}
+		if last != 0 {
+			changes = append(changes, *q.Price/last-1)
+		}
+		last = *q.Price
+		return nil
+	})
+	if err != nil || len(changes) < minSigmaSamples {
+		return 0, false, err
+	}
+	var mean float64
+	for _, c := range changes {
+		mean += c
+	}
+	mean /= float64(len(changes))
+	var variance float64
+	for _, c := range changes {
+		variance += (c - mean) * (c - mean)
+	}
+	sigma := math.Sqrt(variance / float64(len(changes)))
+	return sigma, sigma > 0, nil
+}
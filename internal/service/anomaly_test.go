@@ -0,0 +1,84 @@
+package service
+
+import (
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository/memory"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAnomalyDetector_MaxChange(t *testing.T) {
+	ctx := context.Background()
+	srv := NewQuoteService(memory.NewQuoteRepository())
+	d := NewAnomalyDetector(srv, AnomalyRule{MaxChange: 0.05}, time.Hour)
+
+	if reason, err := d.Check(ctx, "EUR/USD", 100); err != nil || reason != "" {
+		t.Fatalf("expected the first quote of a pair to pass, got %q, %v", reason, err)
+	}
+	if _, err := srv.InsertHistoricalQuote(ctx, "EUR/USD", 1.10, time.Now().Add(-time.Minute), model.SourceImport); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		price   float64
+		suspect bool
+	}{{1.12, false}, {1.05, false}, {1.16, true}, {1.04, true}}
+	for _, tt := range tests {
+		reason, err := d.Check(ctx, "EUR/USD", tt.price)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if (reason != "") != tt.suspect {
+			t.Errorf("price %g: expected suspect %v, got %q", tt.price, tt.suspect, reason)
+		}
+	}
+
+	d.Pairs = map[string]AnomalyRule{"EUR/USD": {MaxChange: 0.10}}
+	if reason, _ := d.Check(ctx, "EUR/USD", 1.16); reason != "" {
+		t.Errorf("expected the pair rule to override the default, got %q", reason)
+	}
+	d.Pairs["EUR/USD"] = AnomalyRule{}
+	if reason, _ := d.Check(ctx, "EUR/USD", 10); reason != "" {
+		t.Errorf("expected an empty rule to disable the checks, got %q", reason)
+	}
+}
+
+func TestAnomalyDetector_MaxSigma(t *testing.T) {
+	ctx := context.Background()
+	srv := NewQuoteService(memory.NewQuoteRepository())
+	now := time.Now()
+	d := NewAnomalyDetector(srv, AnomalyRule{MaxSigma: 4}, time.Hour)
+	d.now = func() time.Time { return now }
+
+	// Alternate between 1.000 and 1.001, a spread of about 0.1%.
+	start := now.Add(-30 * time.Minute)
+	for i := 0; i < 6; i++ {
+		price := 1.000
+		if i%2 == 1 {
+			price = 1.001
+		}
+		if _, err := srv.InsertHistoricalQuote(ctx, "EUR/USD", price, start.Add(time.Duration(i)*time.Minute), model.SourceImport); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// Too little history to measure the spread.
+	if reason, _ := d.Check(ctx, "EUR/USD", 1.05); reason != "" {
+		t.Errorf("expected no sigma check below %d samples, got %q", minSigmaSamples, reason)
+	}
+
+	for i := 6; i < 12; i++ {
+		price := 1.000
+		if i%2 == 1 {
+			price = 1.001
+		}
+		if _, err := srv.InsertHistoricalQuote(ctx, "EUR/USD", price, start.Add(time.Duration(i)*time.Minute), model.SourceImport); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if reason, err := d.Check(ctx, "EUR/USD", 1.0015); err != nil || reason != "" {
+		t.Errorf("expected a move in line with history to pass, got %q, %v", reason, err)
+	}
+	if reason, _ := d.Check(ctx, "EUR/USD", 1.01); reason == "" {
+		t.Error("expected a 1% move to be suspect")
+	}
+}
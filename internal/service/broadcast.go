@@ -0,0 +1,54 @@
+package service
+
+import (
+	"FinQuotesService/internal/model"
+	"sync"
+)
+
+// Broadcaster fans finished quotes out to in-process subscribers, such as
+// streaming API clients. A subscriber that does not keep up misses updates
+// instead of blocking the workers.
+type Broadcaster struct {
+	mu   sync.Mutex
+	subs map[chan model.Quote]struct{}
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{subs: make(map[chan model.Quote]struct{})}
+}
+
+// Subscribe returns a channel receiving every published quote and a function
+// that unsubscribes and closes it.
+func (b *Broadcaster) Subscribe(buffer int) (<-chan model.Quote, func()) {
+	ch := make(chan model.Quote, buffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (b *Broadcaster) Publish(q model.Quote) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- q:
+		default:
+		}
+	}
+}
+
+// Subscribers returns the number of active subscriptions.
+func (b *Broadcaster) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
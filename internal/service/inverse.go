@@ -0,0 +1,92 @@
+package service
+
+import (
+	"FinQuotesService/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// InverseCheck compares the latest done quotes of a pair and of its inverse,
+// whose product should be 1.
+type InverseCheck struct {
+	Pair             string
+	Inverse          string
+	Price            float64
+	InversePrice     float64
+	UpdatedAt        time.Time
+	InverseUpdatedAt time.Time
+	// Deviation is how far the product of both prices is from 1.
+	Deviation  float64
+	Consistent bool
+}
+
+// InverseChecker compares the pairs supported both ways, such as EUR/USD
+// and USD/EUR.
+type InverseChecker struct {
+	Srv QuoteServiceInterface
+	// Pairs holds one pair of every couple, the other one is its inverse.
+	Pairs []string
+	// Tolerance is the largest deviation still considered consistent.
+	Tolerance float64
+}
+
+func NewInverseChecker(srv QuoteServiceInterface, supported map[string]bool, tolerance float64) *InverseChecker {
+	c := &InverseChecker{Srv: srv, Tolerance: tolerance}
+	for pair := range supported {
+		if inverse, ok := model.InversePair(pair); ok && supported[inverse] && pair < inverse {
+			c.Pairs = append(c.Pairs, pair)
+		}
+	}
+	sort.Strings(c.Pairs)
+	return c
+}
+
+// Check compares every couple. Couples where either pair has no done quote
+// yet are left out.
+func (c *InverseChecker) Check(ctx context.Context) ([]InverseCheck, error) {
+	checks := make([]InverseCheck, 0, len(c.Pairs))
+	for _, pair := range c.Pairs {
+		inverse, _ := model.InversePair(pair)
+		q, ok, err := c.lastPrice(ctx, pair)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		iq, ok, err := c.lastPrice(ctx, inverse)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		deviation := math.Abs(*q.Price**iq.Price - 1)
+		checks = append(checks, InverseCheck{
+			Pair:             pair,
+			Inverse:          inverse,
+			Price:            *q.Price,
+			InversePrice:     *iq.Price,
+			UpdatedAt:        *q.UpdatedAt,
+			InverseUpdatedAt: *iq.UpdatedAt,
+			Deviation:        deviation,
+			Consistent:       deviation <= c.Tolerance,
+		})
+	}
+	return checks, nil
+}
+
+func (c *InverseChecker) lastPrice(ctx context.Context, pair string) (model.Quote, bool, error) {
+	q, err := c.Srv.GetLastQuote(ctx, pair, model.StatusDone)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Quote{}, false, nil
+	}
+	if err != nil {
+		return model.Quote{}, false, err
+	}
+	return q, q.Price != nil && q.UpdatedAt != nil, nil
+}
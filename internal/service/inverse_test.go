@@ -0,0 +1,47 @@
+package service
+
+import (
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository/memory"
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestInverseChecker_Check(t *testing.T) {
+	ctx := context.Background()
+	srv := NewQuoteService(memory.NewQuoteRepository())
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, q := range []struct {
+		currency string
+		price    float64
+	}{{"EUR/USD", 1.1}, {"USD/EUR", 0.9}, {"USD/MXN", 17}} {
+		if _, err := srv.InsertHistoricalQuote(ctx, q.currency, q.price, day, model.SourceImport); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	supported := map[string]bool{"EUR/USD": true, "USD/EUR": true, "USD/MXN": true, "MXN/USD": true, "EUR/MXN": true}
+	c := NewInverseChecker(srv, supported, 0.005)
+	if len(c.Pairs) != 2 || c.Pairs[0] != "EUR/USD" || c.Pairs[1] != "MXN/USD" {
+		t.Fatalf("unexpected couples %v", c.Pairs)
+	}
+
+	checks, err := c.Check(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// MXN/USD has no quote yet.
+	if len(checks) != 1 {
+		t.Fatalf("expected 1 check, got %+v", checks)
+	}
+	got := checks[0]
+	if got.Pair != "EUR/USD" || got.Inverse != "USD/EUR" || math.Abs(got.Deviation-0.01) > 1e-9 || got.Consistent || !got.UpdatedAt.Equal(day) {
+		t.Errorf("unexpected check %+v", got)
+	}
+
+	c.Tolerance = 0.02
+	if checks, _ := c.Check(ctx); !checks[0].Consistent {
+		t.Errorf("expected a deviation within tolerance, got %+v", checks[0])
+	}
+}
@@ -1,62 +1,238 @@
 package service
 
 import (
+	"FinQuotesService/internal/cache"
+	"FinQuotesService/internal/metrics"
 	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository"
+	"FinQuotesService/internal/tracing"
+	"context"
 	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type QuoteServiceInterface interface {
-	InsertPendingQuote(currency string) (string, error)
-	UpdateQuote(id string, price float64, status model.Status) error
-	GetQuoteById(id string) (model.Quote, error)
-	GetLastQuote(currency string, status model.Status) (model.Quote, error)
+	InsertPendingQuote(ctx context.Context, currency, requestedBy string) (string, error)
+	UpdateQuote(ctx context.Context, id string, price float64, status model.Status) error
+	GetQuoteById(ctx context.Context, id string) (model.Quote, error)
+	GetLastQuote(ctx context.Context, currency string, status model.Status) (model.Quote, error)
+	GetCandles(ctx context.Context, currency string, interval time.Duration, from, to time.Time) ([]model.Candle, error)
+	ExportQuotes(ctx context.Context, currency string, from, to time.Time, fn func(model.Quote) error) error
+	InsertHistoricalQuote(ctx context.Context, currency string, price float64, updatedAt time.Time, source string) (string, error)
+	ListSuspectQuotes(ctx context.Context) ([]model.Quote, error)
+	ReviewQuote(ctx context.Context, id string, approve bool) error
+}
+
+// UpdatePublisher tells other service instances that a pair got a new done
+// quote, so they can invalidate their caches.
+type UpdatePublisher interface {
+	PublishQuoteUpdate(currency string) error
 }
 
 type QuoteService struct {
-	InsertPendingStmt *sql.Stmt
-	UpdateQuoteStmt   *sql.Stmt
-	GetQuoteByIdStmt  *sql.Stmt
-	GetLastQuoteStmt  *sql.Stmt
+	Repo      repository.QuoteRepository
+	Cache     *cache.QuoteCache
+	Publisher UpdatePublisher
+	// Updates receives every quote that completes on this instance.
+	Updates *Broadcaster
+	// Instance, when set, claims new pending quotes for ClaimTTL on behalf
+	// of this instance, so other replicas do not queue them as well.
+	Instance string
+	ClaimTTL time.Duration
+}
+
+func NewQuoteService(repo repository.QuoteRepository) *QuoteService {
+	return &QuoteService{
+		Repo: repo,
+	}
+}
+
+func (s *QuoteService) InsertPendingQuote(ctx context.Context, currency, requestedBy string) (string, error) {
+	_, span := startSpan(ctx, "QuoteService.InsertPendingQuote", attribute.String("currency", currency))
+	id, err := s.Repo.InsertPendingQuote(currency, requestedBy)
+	endSpan(span, err)
+	if err != nil || s.Instance == "" {
+		return id, err
+	}
+	// The caller queues the quote anyway. Left unclaimed, it may be queued
+	// by another replica as well, which only costs one more provider call.
+	if err := s.Repo.ClaimQuote(id, s.Instance, time.Now().Add(s.ClaimTTL)); err != nil {
+		slog.ErrorContext(ctx, "failed to claim pending quote", "component", "service", "quote_id", id, "error", err)
+	}
+	return id, nil
 }
 
-func NewQuoteService(db *sql.DB) *QuoteService {
-	insertPendingStmt, err := db.Prepare(`INSERT INTO quotes (currency, status) VALUES ($1, 'pending') ON CONFLICT (currency) WHERE status = 'pending' DO NOTHING RETURNING id;`)
-	updateQuoteStmt, err := db.Prepare(`UPDATE quotes SET price=$1, updated_at=now(), status=$2 WHERE id=$3`)
-	getQuoteByIdStmt, err := db.Prepare(`SELECT id, currency, price, updated_at, status FROM quotes WHERE id =$1`)
-	getLastQuoteStmt, err := db.Prepare(`SELECT id, currency, price, updated_at, status FROM quotes WHERE currency=$1 AND status=$2 ORDER BY updated_at DESC LIMIT 1`)
+func (s *QuoteService) UpdateQuote(ctx context.Context, id string, price float64, status model.Status) error {
+	ctx, span := startSpan(ctx, "QuoteService.UpdateQuote", attribute.String("quote.id", id), attribute.String("quote.status", string(status)))
+	err := s.Repo.UpdateQuote(id, price, status)
+	endSpan(span, err)
+	if err != nil || status != model.StatusDone {
+		return err
+	}
+	q, err := s.Repo.GetQuoteById(id)
 	if err != nil {
-		panic(err)
+		slog.ErrorContext(ctx, "failed to reload quote for cache", "component", "service", "quote_id", id, "error", err)
+		return nil
 	}
-	return &QuoteService{
-		InsertPendingStmt: insertPendingStmt,
-		UpdateQuoteStmt:   updateQuoteStmt,
-		GetQuoteByIdStmt:  getQuoteByIdStmt,
-		GetLastQuoteStmt:  getLastQuoteStmt,
+	observeAge(q)
+	if s.Cache != nil {
+		s.Cache.Set(q)
+	}
+	if s.Updates != nil {
+		s.Updates.Publish(q)
+	}
+	if s.Publisher != nil {
+		if err := s.Publisher.PublishQuoteUpdate(q.Currency); err != nil {
+			slog.ErrorContext(ctx, "failed to publish quote update", "component", "service", "currency", q.Currency, "error", err)
+		}
+	}
+	return nil
+}
+
+func (s *QuoteService) GetQuoteById(ctx context.Context, id string) (model.Quote, error) {
+	_, span := startSpan(ctx, "QuoteService.GetQuoteById", attribute.String("quote.id", id))
+	q, err := s.Repo.GetQuoteById(id)
+	endSpan(span, err)
+	return q, err
+}
+
+func (s *QuoteService) GetLastQuote(ctx context.Context, currency string, status model.Status) (model.Quote, error) {
+	_, span := startSpan(ctx, "QuoteService.GetLastQuote", attribute.String("currency", currency), attribute.String("quote.status", string(status)))
+	if status != model.StatusDone || s.Cache == nil {
+		q, err := s.Repo.GetLastQuote(currency, status)
+		endSpan(span, err)
+		if err == nil && status == model.StatusDone {
+			observeAge(q)
+		}
+		return q, err
+	}
+	if q, ok := s.Cache.Get(currency); ok {
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		span.End()
+		return q, nil
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+	q, err := s.Repo.GetLastQuote(currency, status)
+	endSpan(span, err)
+	if err != nil {
+		return q, err
 	}
+	observeAge(q)
+	s.Cache.Set(q)
+	return q, nil
 }
 
-func (s *QuoteService) InsertPendingQuote(currency string) (string, error) {
-	var id string
-	row := s.InsertPendingStmt.QueryRow(currency)
-	err := row.Scan(&id)
-	return id, err
+func (s *QuoteService) GetCandles(ctx context.Context, currency string, interval time.Duration, from, to time.Time) ([]model.Candle, error) {
+	_, span := startSpan(ctx, "QuoteService.GetCandles", attribute.String("currency", currency), attribute.String("interval", interval.String()))
+	candles, err := s.Repo.GetCandles(currency, interval, from, to)
+	endSpan(span, err)
+	return candles, err
 }
 
-func (s *QuoteService) UpdateQuote(id string, price float64, status model.Status) error {
-	_, err := s.UpdateQuoteStmt.Exec(price, status, id)
+// ExportQuotes streams done quotes of currency to fn, oldest first. It stops
+// with ctx's error once ctx is done, so an export does not keep reading rows
+// for a client that went away.
+func (s *QuoteService) ExportQuotes(ctx context.Context, currency string, from, to time.Time, fn func(model.Quote) error) error {
+	_, span := startSpan(ctx, "QuoteService.ExportQuotes", attribute.String("currency", currency))
+	rows := 0
+	err := s.Repo.ExportQuotes(currency, from, to, func(q model.Quote) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rows++
+		return fn(q)
+	})
+	span.SetAttributes(attribute.Int("export.rows", rows))
+	endSpan(span, err)
 	return err
 }
 
-func (s *QuoteService) GetQuoteById(id string) (model.Quote, error) {
-	row := s.GetQuoteByIdStmt.QueryRow(id)
-	var q model.Quote
-	err := row.Scan(&q.ID, &q.Currency, &q.Price, &q.UpdatedAt, &q.Status)
-	return q, err
+// InsertHistoricalQuote stores a done quote from the past. It is not
+// broadcast as a live update, but the cached latest quote of the pair is
+// dropped in case the stored one is newer.
+func (s *QuoteService) InsertHistoricalQuote(ctx context.Context, currency string, price float64, updatedAt time.Time, source string) (string, error) {
+	_, span := startSpan(ctx, "QuoteService.InsertHistoricalQuote", attribute.String("currency", currency), attribute.String("quote.source", source))
+	id, err := s.Repo.InsertHistoricalQuote(currency, price, updatedAt, source)
+	endSpan(span, err)
+	if err != nil {
+		return id, err
+	}
+	metrics.QuoteAge.Observe(currency, updatedAt)
+	if s.Cache != nil {
+		s.Cache.Invalidate(currency)
+	}
+	return id, nil
 }
 
-func (s *QuoteService) GetLastQuote(currency string, status model.Status) (model.Quote, error) {
-	row := s.GetLastQuoteStmt.QueryRow(currency, status)
-	var q model.Quote
-	err := row.Scan(&q.ID, &q.Currency, &q.Price, &q.UpdatedAt, &q.Status)
-	return q, err
+func (s *QuoteService) ListSuspectQuotes(ctx context.Context) ([]model.Quote, error) {
+	_, span := startSpan(ctx, "QuoteService.ListSuspectQuotes")
+	quotes, err := s.Repo.ListSuspectQuotes()
+	endSpan(span, err)
+	return quotes, err
+}
+
+// ReviewQuote settles a suspect quote. An approved quote becomes done and is
+// published like any completed quote, a rejected one is kept for the record
+// but never served. It returns sql.ErrNoRows when id is not a suspect quote.
+func (s *QuoteService) ReviewQuote(ctx context.Context, id string, approve bool) error {
+	status := model.StatusRejected
+	if approve {
+		status = model.StatusDone
+	}
+	ctx, span := startSpan(ctx, "QuoteService.ReviewQuote", attribute.String("quote.id", id), attribute.String("quote.status", string(status)))
+	err := s.Repo.SetQuoteStatus(id, model.StatusSuspect, status)
+	endSpan(span, err)
+	if err != nil || !approve {
+		return err
+	}
+	q, err := s.Repo.GetQuoteById(id)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to reload approved quote", "component", "service", "quote_id", id, "error", err)
+		return nil
+	}
+	observeAge(q)
+	// A newer quote may have completed while this one waited for review, so
+	// the cache is refilled from the store rather than with q.
+	if s.Cache != nil {
+		s.Cache.Invalidate(q.Currency)
+	}
+	if s.Updates != nil {
+		if last, err := s.Repo.GetLastQuote(q.Currency, model.StatusDone); err == nil && last.ID == q.ID {
+			s.Updates.Publish(q)
+		}
+	}
+	if s.Publisher != nil {
+		if err := s.Publisher.PublishQuoteUpdate(q.Currency); err != nil {
+			slog.ErrorContext(ctx, "failed to publish quote update", "component", "service", "currency", q.Currency, "error", err)
+		}
+	}
+	return nil
+}
+
+// observeAge reports q as the latest done quote of its pair to the quote age
+// metric, which keeps the newest time it has seen. Every read from the store
+// goes through it as well, so replicas that did not fetch a quote themselves
+// catch up once their cache is invalidated and refilled.
+func observeAge(q model.Quote) {
+	if q.UpdatedAt != nil {
+		metrics.QuoteAge.Observe(q.Currency, *q.UpdatedAt)
+	}
+}
+
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindInternal), trace.WithAttributes(attrs...))
+}
+
+// endSpan finishes span, not counting a missing row as a failure since it is
+// an expected answer for lookups.
+func endSpan(span trace.Span, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	tracing.End(span, err)
 }
@@ -1,232 +1,293 @@
 package service
 
 import (
+	"FinQuotesService/internal/cache"
+	"FinQuotesService/internal/metrics"
 	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository/memory"
+	"context"
 	"database/sql"
-	"github.com/DATA-DOG/go-sqlmock"
-	"github.com/google/uuid"
+	"errors"
 	"testing"
 	"time"
 )
 
-func initMocks(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
-	db, mock, err := sqlmock.New()
+func TestQuoteService_PendingLifecycle(t *testing.T) {
+	ctx := context.Background()
+	srv := NewQuoteService(memory.NewQuoteRepository())
+
+	id, err := srv.InsertPendingQuote(ctx, "USD/EUR", "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	return db, mock
-}
-
-func TestQuoteService_InsertPendingQuote(t *testing.T) {
-	db, mock := initMocks(t)
-	defer db.Close()
-
-	testUuid := uuid.New().String()
-	testCurrency := "USD/EUR"
-
-	rows := sqlmock.NewRows([]string{"id"}).AddRow(testUuid)
-
-	expectedPrepare := mock.ExpectPrepare(`INSERT INTO quotes \(currency, status\) VALUES \(\$1, 'pending'\) ON CONFLICT \(currency\) WHERE status = 'pending' DO NOTHING RETURNING id;`)
-	mock.ExpectPrepare(`UPDATE quotes SET price=\$1, updated_at=now\(\), status=\$2 WHERE id=\$3`)
-	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status FROM quotes WHERE id =\$1`)
-	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status FROM quotes WHERE currency=\$1 AND status=\$2 ORDER BY updated_at DESC LIMIT 1`)
-
-	expectedPrepare.ExpectQuery().
-		WithArgs(testCurrency).
-		WillReturnRows(rows)
-
-	service := NewQuoteService(db)
-	quoteId, err := service.InsertPendingQuote(testCurrency)
+	pending, err := srv.GetLastQuote(ctx, "USD/EUR", model.StatusPending)
 	if err != nil {
-		t.Errorf("unexpected error: %v", err)
+		t.Fatalf("unexpected error: %v", err)
 	}
-
-	if quoteId != testUuid {
-		t.Errorf("expected ID %s, got %s", testUuid, quoteId)
+	if pending.ID != id {
+		t.Errorf("expected pending quote %s, got %s", id, pending.ID)
 	}
 
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unfulfilled expectations: %s", err)
+	if err := srv.UpdateQuote(ctx, id, 0.92, model.StatusDone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := srv.GetLastQuote(ctx, "USD/EUR", model.StatusPending); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+	last, err := srv.GetLastQuote(ctx, "USD/EUR", model.StatusDone)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if last.ID != id || last.Price == nil || *last.Price != 0.92 {
+		t.Errorf("unexpected last quote: %+v", last)
 	}
 }
 
-func TestQuoteService_UpdateQuote(t *testing.T) {
-	db, mock := initMocks(t)
-	defer db.Close()
+func TestQuoteService_ClaimsPendingQuotes(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewQuoteRepository()
+	srv := NewQuoteService(repo)
+	srv.Instance = "a"
+	srv.ClaimTTL = time.Minute
 
-	mock.ExpectPrepare(`INSERT INTO quotes \(currency, status\) VALUES \(\$1, 'pending'\) ON CONFLICT \(currency\) WHERE status = 'pending' DO NOTHING RETURNING id;`)
-	expectedPrepare := mock.ExpectPrepare(`UPDATE quotes SET price=\$1, updated_at=now\(\), status=\$2 WHERE id=\$3`)
-	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status FROM quotes WHERE id =\$1`)
-	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status FROM quotes WHERE currency=\$1 AND status=\$2 ORDER BY updated_at DESC LIMIT 1`)
+	if _, err := srv.InsertPendingQuote(ctx, "USD/EUR", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now()
+	for _, at := range []time.Time{now, now.Add(time.Second)} {
+		claimed, err := repo.ClaimPendingQuotes("b", at, at.Add(time.Minute))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(claimed) != 0 {
+			t.Fatalf("expected the quote to stay claimed by its inserter, got %+v", claimed)
+		}
+	}
+}
 
-	expectedPrepare.ExpectExec().
-		WithArgs(1.23, model.StatusDone, "uuid-1").
-		WillReturnResult(sqlmock.NewResult(1, 1))
+func TestQuoteService_GetCandles(t *testing.T) {
+	ctx := context.Background()
+	srv := NewQuoteService(memory.NewQuoteRepository())
 
-	service := NewQuoteService(db)
-	err := service.UpdateQuote("uuid-1", 1.23, model.StatusDone)
+	id, err := srv.InsertPendingQuote(ctx, "USD/EUR", "")
 	if err != nil {
-		t.Errorf("unexpected error: %v", err)
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := srv.UpdateQuote(ctx, id, 0.92, model.StatusDone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unfulfilled expectations: %s", err)
+	now := time.Now()
+	candles, err := srv.GetCandles(ctx, "USD/EUR", time.Hour, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(candles) != 1 || candles[0].Count != 1 || candles[0].Close != 0.92 {
+		t.Errorf("unexpected candles: %+v", candles)
 	}
 }
 
-func TestGetQuoteById_Success(t *testing.T) {
-	db, mock := initMocks(t)
-	defer db.Close()
+type countingRepository struct {
+	*memory.QuoteRepository
+	lastQuoteCalls int
+}
 
-	testID := "test-uuid"
-	testCurrency := "USD/EUR"
-	testPrice := 1.23
-	testTime := time.Now().Truncate(time.Second)
-	testStatus := model.StatusDone
+func (r *countingRepository) GetLastQuote(currency string, status model.Status) (model.Quote, error) {
+	r.lastQuoteCalls++
+	return r.QuoteRepository.GetLastQuote(currency, status)
+}
 
-	rows := sqlmock.NewRows([]string{"id", "currency", "price", "updated_at", "status"}).
-		AddRow(testID, testCurrency, testPrice, testTime, testStatus)
+type recordingPublisher struct {
+	published []string
+}
 
-	mock.ExpectPrepare(`INSERT INTO quotes \(currency, status\) VALUES \(\$1, 'pending'\) ON CONFLICT \(currency\) WHERE status = 'pending' DO NOTHING RETURNING id;`)
-	mock.ExpectPrepare(`UPDATE quotes SET price=\$1, updated_at=now\(\), status=\$2 WHERE id=\$3`)
-	expectedPrepare := mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status FROM quotes WHERE id =\$1`)
-	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status FROM quotes WHERE currency=\$1 AND status=\$2 ORDER BY updated_at DESC LIMIT 1`)
+func (p *recordingPublisher) PublishQuoteUpdate(currency string) error {
+	p.published = append(p.published, currency)
+	return nil
+}
 
-	expectedPrepare.ExpectQuery().
-		WithArgs(testID).
-		WillReturnRows(rows)
+func TestQuoteService_LastQuoteCache(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingRepository{QuoteRepository: memory.NewQuoteRepository()}
+	publisher := &recordingPublisher{}
+	srv := NewQuoteService(repo)
+	srv.Cache = cache.NewQuoteCache(time.Minute)
+	srv.Publisher = publisher
 
-	srv := NewQuoteService(db)
-	quote, err := srv.GetQuoteById(testID)
+	id, err := srv.InsertPendingQuote(ctx, "USD/EUR", "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-
-	if quote.ID != testID {
-		t.Errorf("expected ID %s, got %s", testID, quote.ID)
-	}
-	if quote.Currency != testCurrency {
-		t.Errorf("expected Currency %s, got %s", testCurrency, quote.Currency)
+	if err := srv.UpdateQuote(ctx, id, 0.92, model.StatusDone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	if *quote.Price != testPrice {
-		t.Errorf("expected Price %v, got %v", testPrice, quote.Price)
+	if len(publisher.published) != 1 || publisher.published[0] != "USD/EUR" {
+		t.Errorf("expected update published for USD/EUR, got %v", publisher.published)
 	}
-	if !quote.UpdatedAt.Equal(testTime) {
-		t.Errorf("expected UpdatedAt %v, got %v", testTime, quote.UpdatedAt)
+
+	for i := 0; i < 3; i++ {
+		q, err := srv.GetLastQuote(ctx, "USD/EUR", model.StatusDone)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if q.ID != id {
+			t.Errorf("expected quote %s, got %s", id, q.ID)
+		}
 	}
-	if quote.Status != testStatus {
-		t.Errorf("expected Status %s, got %s", testStatus, quote.Status)
+	if repo.lastQuoteCalls != 0 {
+		t.Errorf("expected cached reads, got %d repository calls", repo.lastQuoteCalls)
 	}
 
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("unfulfilled expectations: %v", err)
+	srv.Cache.Invalidate("USD/EUR")
+	if _, err := srv.GetLastQuote(ctx, "USD/EUR", model.StatusDone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := srv.GetLastQuote(ctx, "USD/EUR", model.StatusDone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.lastQuoteCalls != 1 {
+		t.Errorf("expected a single read-through after invalidation, got %d", repo.lastQuoteCalls)
 	}
 }
 
-func TestGetQuoteById_NotFound(t *testing.T) {
-	db, mock := initMocks(t)
-	defer db.Close()
-
-	notExistID := "not-exist-uuid"
+func TestQuoteService_ErrorNotCached(t *testing.T) {
+	ctx := context.Background()
+	srv := NewQuoteService(memory.NewQuoteRepository())
+	srv.Cache = cache.NewQuoteCache(time.Minute)
 
-	mock.ExpectPrepare(`INSERT INTO quotes \(currency, status\) VALUES \(\$1, 'pending'\) ON CONFLICT \(currency\) WHERE status = 'pending' DO NOTHING RETURNING id;`)
-	mock.ExpectPrepare(`UPDATE quotes SET price=\$1, updated_at=now\(\), status=\$2 WHERE id=\$3`)
-	expectedPrepare := mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status FROM quotes WHERE id =\$1`)
-	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status FROM quotes WHERE currency=\$1 AND status=\$2 ORDER BY updated_at DESC LIMIT 1`)
-
-	expectedPrepare.ExpectQuery().
-		WithArgs(notExistID).
-		WillReturnError(sql.ErrNoRows)
-
-	srv := NewQuoteService(db)
-	_, err := srv.GetQuoteById(notExistID)
-	if err == nil {
-		t.Fatal("expected error, got nil")
+	id, err := srv.InsertPendingQuote(ctx, "USD/EUR", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	if err != sql.ErrNoRows {
-		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	if err := srv.UpdateQuote(ctx, id, 0, model.StatusError); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("unfulfilled expectations: %v", err)
+	if _, ok := srv.Cache.Get("USD/EUR"); ok {
+		t.Error("failed quote must not be cached")
 	}
 }
 
-func TestGetLastQuote_Success(t *testing.T) {
-	db, mock := initMocks(t)
-	defer db.Close()
-
-	testID := "test-uuid"
-	testCurrency := "USD/EUR"
-	testPrice := 1.23
-	testTime := time.Now().Truncate(time.Second)
-	testStatus := model.StatusDone
+func TestQuoteService_BroadcastsDoneQuotes(t *testing.T) {
+	ctx := context.Background()
+	srv := NewQuoteService(memory.NewQuoteRepository())
+	srv.Updates = NewBroadcaster()
+	updates, unsubscribe := srv.Updates.Subscribe(2)
 
-	rows := sqlmock.NewRows([]string{"id", "currency", "price", "updated_at", "status"}).
-		AddRow(testID, testCurrency, testPrice, testTime, testStatus)
+	failed, _ := srv.InsertPendingQuote(ctx, "USD/EUR", "")
+	if err := srv.UpdateQuote(ctx, failed, 0, model.StatusError); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	id, _ := srv.InsertPendingQuote(ctx, "USD/EUR", "")
+	if err := srv.UpdateQuote(ctx, id, 0.92, model.StatusDone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 
-	mock.ExpectPrepare(`INSERT INTO quotes \(currency, status\) VALUES \(\$1, 'pending'\) ON CONFLICT \(currency\) WHERE status = 'pending' DO NOTHING RETURNING id;`)
-	mock.ExpectPrepare(`UPDATE quotes SET price=\$1, updated_at=now\(\), status=\$2 WHERE id=\$3`)
-	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status FROM quotes WHERE id =\$1`)
-	expectedPrepare := mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status FROM quotes WHERE currency=\$1 AND status=\$2 ORDER BY updated_at DESC LIMIT 1`)
+	select {
+	case q := <-updates:
+		if q.ID != id || q.Status != model.StatusDone {
+			t.Errorf("unexpected update: %+v", q)
+		}
+	default:
+		t.Fatal("expected a broadcast for the done quote")
+	}
+	if len(updates) != 0 {
+		t.Errorf("failed updates must not be broadcast")
+	}
 
-	expectedPrepare.ExpectQuery().
-		WithArgs(testCurrency, testStatus).
-		WillReturnRows(rows)
+	unsubscribe()
+	unsubscribe()
+	if _, ok := <-updates; ok || srv.Updates.Subscribers() != 0 {
+		t.Error("expected the subscription to be closed")
+	}
+}
 
-	srv := NewQuoteService(db)
-	quote, err := srv.GetLastQuote(testCurrency)
+func TestQuoteService_ReviewQuote(t *testing.T) {
+	ctx := context.Background()
+	publisher := &recordingPublisher{}
+	srv := NewQuoteService(memory.NewQuoteRepository())
+	srv.Cache = cache.NewQuoteCache(time.Minute)
+	srv.Publisher = publisher
+	srv.Updates = NewBroadcaster()
+	updates, unsubscribe := srv.Updates.Subscribe(2)
+	defer unsubscribe()
+
+	done, _ := srv.InsertPendingQuote(ctx, "USD/EUR", "")
+	if err := srv.UpdateQuote(ctx, done, 0.92, model.StatusDone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-updates
+	suspect, _ := srv.InsertPendingQuote(ctx, "USD/EUR", "")
+	if err := srv.UpdateQuote(ctx, suspect, 1.5, model.StatusSuspect); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(updates) != 0 || len(publisher.published) != 1 {
+		t.Fatal("suspect quotes must not be published")
+	}
+	listed, err := srv.ListSuspectQuotes(ctx)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if len(listed) != 1 || listed[0].ID != suspect {
+		t.Fatalf("expected suspect quote %s, got %+v", suspect, listed)
+	}
 
-	if quote.ID != testID {
-		t.Errorf("expected ID %s, got %s", testID, quote.ID)
+	if err := srv.ReviewQuote(ctx, suspect, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	if quote.Currency != testCurrency {
-		t.Errorf("expected Currency %s, got %s", testCurrency, quote.Currency)
+	last, err := srv.GetLastQuote(ctx, "USD/EUR", model.StatusDone)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	if *quote.Price != testPrice {
-		t.Errorf("expected Price %v, got %v", testPrice, quote.Price)
+	if last.ID != suspect {
+		t.Errorf("expected the approved quote to be served, got %+v", last)
 	}
-	if !quote.UpdatedAt.Equal(testTime) {
-		t.Errorf("expected UpdatedAt %v, got %v", testTime, quote.UpdatedAt)
+	if len(updates) != 1 || len(publisher.published) != 2 {
+		t.Errorf("expected the approved quote to be published")
 	}
-	if quote.Status != testStatus {
-		t.Errorf("expected Status %s, got %s", testStatus, quote.Status)
+	if err := srv.ReviewQuote(ctx, suspect, false); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for a reviewed quote, got %v", err)
 	}
 
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("unfulfilled expectations: %v", err)
+	rejected, _ := srv.InsertPendingQuote(ctx, "USD/EUR", "")
+	if err := srv.UpdateQuote(ctx, rejected, 9, model.StatusSuspect); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := srv.ReviewQuote(ctx, rejected, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q, _ := srv.GetQuoteById(ctx, rejected); q.Status != model.StatusRejected {
+		t.Errorf("expected the quote to be rejected, got %+v", q)
+	}
+	if last, _ := srv.GetLastQuote(ctx, "USD/EUR", model.StatusDone); last.ID != suspect {
+		t.Errorf("expected the rejected quote not to be served, got %+v", last)
 	}
 }
 
-func TestGetLastQuote_NotFound(t *testing.T) {
-	db, mock := initMocks(t)
-	defer db.Close()
+func TestQuoteService_ObservesQuoteAge(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewQuoteRepository()
+	srv := NewQuoteService(repo)
+	srv.Cache = cache.NewQuoteCache(time.Minute)
 
-	testCurrency := "USD/EUR"
-	testStatus := model.StatusDone
-
-	mock.ExpectPrepare(`INSERT INTO quotes \(currency, status\) VALUES \(\$1, 'pending'\) ON CONFLICT \(currency\) WHERE status = 'pending' DO NOTHING RETURNING id;`)
-	mock.ExpectPrepare(`UPDATE quotes SET price=\$1, updated_at=now\(\), status=\$2 WHERE id=\$3`)
-	mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status FROM quotes WHERE id =\$1`)
-	expectedPrepare := mock.ExpectPrepare(`SELECT id, currency, price, updated_at, status FROM quotes WHERE currency=\$1 AND status=\$2 ORDER BY updated_at DESC LIMIT 1`)
-
-	expectedPrepare.ExpectQuery().
-		WithArgs(testCurrency, testStatus).
-		WillReturnError(sql.ErrNoRows)
-
-	srv := NewQuoteService(db)
-	_, err := srv.GetLastQuote(testCurrency)
-	if err == nil {
-		t.Fatal("expected error, got nil")
+	// Another replica completes a quote: this one learns about it when its
+	// cache is refilled.
+	id, _ := repo.InsertPendingQuote("GBP/CHF", "")
+	if err := repo.UpdateQuote(id, 1.12, model.StatusDone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	if err != sql.ErrNoRows {
-		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	q, err := srv.GetLastQuote(ctx, "GBP/CHF", model.StatusDone)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, ok := metrics.QuoteAge.Updated("GBP/CHF"); !ok || !got.Equal(*q.UpdatedAt) {
+		t.Errorf("expected the quote age observed at %v, got %v", q.UpdatedAt, got)
 	}
 
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("unfulfilled expectations: %v", err)
+	later := time.Now().Add(time.Hour)
+	if _, err := srv.InsertHistoricalQuote(ctx, "GBP/CHF", 1.13, later, model.SourceImport); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := metrics.QuoteAge.Updated("GBP/CHF"); !got.Equal(later) {
+		t.Errorf("expected imported quotes to be observed, got %v", got)
 	}
 }
@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"FinQuotesService/internal/provider"
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracedProvider struct {
+	provider.Provider
+}
+
+// InstrumentProvider wraps every provider call into a client span.
+func InstrumentProvider(p provider.Provider) provider.Provider {
+	return &tracedProvider{Provider: p}
+}
+
+func (p *tracedProvider) FetchRate(ctx context.Context, base, target string) (float64, error) {
+	ctx, span := Tracer().Start(ctx, "provider.FetchRate",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("provider.name", p.Name()),
+			attribute.String("currency.base", base),
+			attribute.String("currency.target", target),
+		),
+	)
+	rate, err := p.Provider.FetchRate(ctx, base, target)
+	End(span, err)
+	return rate, err
+}
+
+func (p *tracedProvider) FetchHistoricalRate(ctx context.Context, base, target string, day time.Time) (float64, error) {
+	ctx, span := Tracer().Start(ctx, "provider.FetchHistoricalRate",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("provider.name", p.Name()),
+			attribute.String("currency.base", base),
+			attribute.String("currency.target", target),
+			attribute.String("rate.date", day.UTC().Format(time.DateOnly)),
+		),
+	)
+	rate, err := p.Provider.FetchHistoricalRate(ctx, base, target, day)
+	End(span, err)
+	return rate, err
+}
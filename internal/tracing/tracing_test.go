@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return recorder
+}
+
+func TestMiddleware_NamesSpanByPattern(t *testing.T) {
+	recorder := setupRecorder(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/quotes/last/{base}/{quote}", func(w http.ResponseWriter, r *http.Request) {
+		if !trace.SpanContextFromContext(r.Context()).IsValid() {
+			t.Error("expected span in handler context")
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	Middleware(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/quotes/last/USD/EUR", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name() != "GET /v1/quotes/last/{base}/{quote}" {
+		t.Errorf("unexpected span name %q", spans[0].Name())
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("expected error status for 500 response, got %v", spans[0].Status().Code)
+	}
+}
+
+func TestInjectExtract_ContinuesTrace(t *testing.T) {
+	recorder := setupRecorder(t)
+	ctx, parent := Tracer().Start(context.Background(), "handler")
+	carrier := Inject(ctx)
+	parent.End()
+
+	_, child := Tracer().Start(Extract(context.Background(), carrier), "worker")
+	End(child, errors.New("upstream down"))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[1].Parent().SpanID() != spans[0].SpanContext().SpanID() {
+		t.Error("worker span is not a child of the handler span")
+	}
+	if spans[1].SpanContext().TraceID() != spans[0].SpanContext().TraceID() {
+		t.Error("worker span belongs to another trace")
+	}
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("expected error status, got %v", spans[1].Status().Code)
+	}
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	if _, err := Setup(context.Background(), "zipkin"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,262 @@
+package worker
+
+import (
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/provider"
+	"FinQuotesService/internal/repository"
+	"FinQuotesService/internal/service"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultBackfillPoll is how often the repository is checked for
+	// backfills created by other instances.
+	defaultBackfillPoll = time.Minute
+	// defaultBackfillAttempts is how many times a day is tried before its
+	// backfill fails.
+	defaultBackfillAttempts = 5
+	// maxBackfillBackoff caps the wait between attempts of a day.
+	maxBackfillBackoff = time.Minute
+	// defaultBackfillLease is how long a backfill stays with an instance
+	// that stops renewing its lease.
+	defaultBackfillLease = 5 * time.Minute
+)
+
+// Backfiller runs backfills one at a time, oldest first, fetching one day
+// per provider call. A backfill is leased to one instance at a time, so
+// several may run backfillers. Progress is saved after every day, so a
+// backfill left running by a stopped instance resumes where it was once its
+// lease expires.
+type Backfiller struct {
+	Repo     repository.BackfillRepository
+	Srv      *service.QuoteService
+	Provider provider.Provider
+	// Interval spaces the provider calls of a backfill, on top of the
+	// provider's own throttling, to leave room for live updates.
+	Interval time.Duration
+	// PollInterval is how often the repository is checked for new
+	// backfills when Notify is not called.
+	PollInterval time.Duration
+	// MaxAttempts is how many times a day is tried before the backfill
+	// fails. Deferred calls are not counted.
+	MaxAttempts int
+	// Owner identifies this backfiller in leases, Lease is how long they
+	// last without being renewed.
+	Owner string
+	Lease time.Duration
+
+	wake chan struct{}
+	now  func() time.Time
+}
+
+func NewBackfiller(repo repository.BackfillRepository, srv *service.QuoteService, p provider.Provider) *Backfiller {
+	return &Backfiller{
+		Repo:         repo,
+		Srv:          srv,
+		Provider:     p,
+		PollInterval: defaultBackfillPoll,
+		MaxAttempts:  defaultBackfillAttempts,
+		Owner:        uuid.New().String(),
+		Lease:        defaultBackfillLease,
+		wake:         make(chan struct{}, 1),
+		now:          time.Now,
+	}
+}
+
+// Notify tells the backfiller that a backfill was created or resumed.
+func (b *Backfiller) Notify() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run works through the active backfills until ctx is done.
+func (b *Backfiller) Run(ctx context.Context) {
+	for {
+		next, ok, err := b.next()
+		if err != nil {
+			slog.ErrorContext(ctx, "list backfills", "component", "backfill", "error", err)
+		}
+		if ok && b.run(ctx, next) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.wake:
+		case <-time.After(b.PollInterval):
+		}
+	}
+}
+
+// next claims the oldest active backfill not leased to another instance.
+func (b *Backfiller) next() (model.Backfill, bool, error) {
+	backfills, err := b.Repo.ListBackfills()
+	if err != nil {
+		return model.Backfill{}, false, err
+	}
+	for _, bf := range backfills {
+		if !bf.Active() {
+			continue
+		}
+		err := b.claim(bf)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return model.Backfill{}, false, err
+		}
+		bf.Owner = b.Owner
+		return bf, true, nil
+	}
+	return model.Backfill{}, false, nil
+}
+
+// claim takes or renews the lease on bf.
+func (b *Backfiller) claim(bf model.Backfill) error {
+	now := b.now()
+	return b.Repo.ClaimBackfill(bf.ID, b.Owner, now, now.Add(b.Lease))
+}
+
+// run fetches the remaining days of bf and reports whether it finished, done
+// or failed. It gives up early when ctx is done or progress cannot be saved,
+// leaving the backfill active for a later round.
+func (b *Backfiller) run(ctx context.Context, bf model.Backfill) bool {
+	log := slog.With("component", "backfill", "backfill_id", bf.ID, "currency", bf.Currency)
+	if bf.Status == model.BackfillPending {
+		bf.Status = model.BackfillRunning
+		if !b.save(ctx, log, &bf) {
+			return false
+		}
+		log.InfoContext(ctx, "backfill started", "from", bf.From.Format(time.DateOnly), "to", bf.To.Format(time.DateOnly))
+	}
+	attempts := 0
+	for !bf.Next.After(bf.To) {
+		if !b.renew(ctx, log, bf) {
+			return false
+		}
+		bf.Owner = b.Owner
+		err := b.fetchDay(ctx, &bf)
+		if ctx.Err() != nil {
+			return false
+		}
+		var deferred *provider.DeferredError
+		switch {
+		case errors.As(err, &deferred):
+			log.WarnContext(ctx, "backfill deferred", "until", deferred.Until, "reason", deferred.Reason)
+			if !b.wait(ctx, log, bf, time.Until(deferred.Until)) {
+				return false
+			}
+			continue
+		case err != nil:
+			attempts++
+			log.WarnContext(ctx, "backfill day failed", "day", bf.Next.Format(time.DateOnly), "attempt", attempts, "error", err)
+			if attempts >= b.MaxAttempts {
+				bf.Status = model.BackfillFailed
+				bf.Error = fmt.Sprintf("%s: %v", bf.Next.Format(time.DateOnly), err)
+				log.ErrorContext(ctx, "backfill failed", "error", err)
+				return b.save(ctx, log, &bf)
+			}
+			if !b.wait(ctx, log, bf, min(time.Second<<attempts, maxBackfillBackoff)) {
+				return false
+			}
+			continue
+		}
+		attempts = 0
+		bf.Next = bf.Next.AddDate(0, 0, 1)
+		if bf.Next.After(bf.To) {
+			break
+		}
+		if !b.save(ctx, log, &bf) || !b.wait(ctx, log, bf, b.Interval) {
+			return false
+		}
+	}
+	bf.Status = model.BackfillDone
+	log.InfoContext(ctx, "backfill done", "inserted", bf.Inserted, "duplicates", bf.Duplicates)
+	return b.save(ctx, log, &bf)
+}
+
+// fetchDay stores the rate of bf.Next and counts it.
+func (b *Backfiller) fetchDay(ctx context.Context, bf *model.Backfill) error {
+	base, target, ok := strings.Cut(bf.Currency, "/")
+	if !ok {
+		return errors.New("bad currency pair")
+	}
+	rate, err := b.Provider.FetchHistoricalRate(ctx, base, target, bf.Next)
+	if err != nil {
+		return err
+	}
+	_, err = b.Srv.InsertHistoricalQuote(ctx, bf.Currency, rate, bf.Next, model.SourceBackfill)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		bf.Duplicates++
+	case err != nil:
+		return err
+	default:
+		bf.Inserted++
+	}
+	return nil
+}
+
+// renew extends the lease on bf and reports whether it is still held. A
+// backfill whose lease was taken over by another instance is left to it.
+func (b *Backfiller) renew(ctx context.Context, log *slog.Logger, bf model.Backfill) bool {
+	err := b.claim(bf)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.WarnContext(ctx, "backfill lease lost")
+		return false
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "renew backfill lease", "error", err)
+		return false
+	}
+	return true
+}
+
+// wait sleeps for d, renewing the lease on bf when d is long enough for it
+// to expire, and reports whether ctx is still live and the lease held.
+func (b *Backfiller) wait(ctx context.Context, log *slog.Logger, bf model.Backfill, d time.Duration) bool {
+	for d > b.Lease/2 {
+		if !sleep(ctx, b.Lease/2) || !b.renew(ctx, log, bf) {
+			return false
+		}
+		d -= b.Lease / 2
+	}
+	return sleep(ctx, d)
+}
+
+// save persists the progress of bf. The backfill is dropped for this round
+// when that fails, e.g. because another instance took over its lease; it is
+// picked up again on the next poll if it is still free.
+func (b *Backfiller) save(ctx context.Context, log *slog.Logger, bf *model.Backfill) bool {
+	bf.UpdatedAt = b.now().UTC()
+	if err := b.Repo.UpdateBackfill(*bf); err != nil {
+		log.ErrorContext(ctx, "save backfill progress", "error", err)
+		return false
+	}
+	return true
+}
+
+// sleep waits for d and reports whether ctx is still live.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
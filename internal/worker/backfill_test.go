@@ -0,0 +1,173 @@
+package worker
+
+import (
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/provider"
+	"FinQuotesService/internal/repository/memory"
+	"FinQuotesService/internal/service"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type historicalProvider struct {
+	calls    []time.Time
+	deferred bool
+	err      error
+}
+
+func (p *historicalProvider) Name() string { return "stub" }
+
+func (p *historicalProvider) FetchRate(ctx context.Context, base, target string) (float64, error) {
+	return 0, errors.New("not implemented")
+}
+
+func (p *historicalProvider) FetchHistoricalRate(ctx context.Context, base, target string, day time.Time) (float64, error) {
+	p.calls = append(p.calls, day)
+	if p.err != nil {
+		return 0, p.err
+	}
+	if !p.deferred {
+		p.deferred = true
+		return 0, &provider.DeferredError{Until: time.Now().Add(10 * time.Millisecond), Reason: "quota"}
+	}
+	return 1 + float64(day.Day())/100, nil
+}
+
+func newBackfill(t *testing.T, repo *memory.BackfillRepository, from, to time.Time) model.Backfill {
+	t.Helper()
+	bf, err := repo.CreateBackfill(model.Backfill{Currency: "EUR/USD", From: from, To: to, Next: from, Status: model.BackfillPending, CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return bf
+}
+
+func TestBackfiller_Run(t *testing.T) {
+	repo := memory.NewBackfillRepository()
+	srv := service.NewQuoteService(memory.NewQuoteRepository())
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := srv.InsertHistoricalQuote(context.Background(), "EUR/USD", 1.1, from.AddDate(0, 0, 1), model.SourceImport); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bf := newBackfill(t, repo, from, from.AddDate(0, 0, 2))
+	p := &historicalProvider{}
+	b := NewBackfiller(repo, srv, p)
+
+	if !b.run(context.Background(), bf) {
+		t.Fatal("expected the backfill to finish")
+	}
+	got, err := repo.GetBackfill(bf.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Status != model.BackfillDone || got.Inserted != 2 || got.Duplicates != 1 || !got.Next.Equal(from.AddDate(0, 0, 3)) {
+		t.Errorf("unexpected backfill %+v", got)
+	}
+	// The deferred first day is fetched again.
+	if len(p.calls) != 4 || !p.calls[1].Equal(from) {
+		t.Errorf("unexpected provider calls %v", p.calls)
+	}
+	var quotes []model.Quote
+	err = srv.ExportQuotes(context.Background(), "EUR/USD", from, from.AddDate(0, 0, 3), func(q model.Quote) error {
+		quotes = append(quotes, q)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(quotes) != 3 || *quotes[0].Price != 1.01 || !quotes[0].UpdatedAt.Equal(from) || *quotes[2].Price != 1.03 {
+		t.Errorf("unexpected quotes %+v", quotes)
+	}
+}
+
+func TestBackfiller_Fails(t *testing.T) {
+	repo := memory.NewBackfillRepository()
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bf := newBackfill(t, repo, from, from.AddDate(0, 0, 5))
+	b := NewBackfiller(repo, service.NewQuoteService(memory.NewQuoteRepository()), &historicalProvider{err: errors.New("boom")})
+	b.MaxAttempts = 1
+
+	if !b.run(context.Background(), bf) {
+		t.Fatal("expected the backfill to finish")
+	}
+	got, err := repo.GetBackfill(bf.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Status != model.BackfillFailed || got.Error != "2024-01-01: boom" || !got.Next.Equal(from) {
+		t.Errorf("unexpected backfill %+v", got)
+	}
+}
+
+func TestBackfiller_ResumesAfterStop(t *testing.T) {
+	repo := memory.NewBackfillRepository()
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bf := newBackfill(t, repo, from, from.AddDate(0, 0, 9))
+	p := &historicalProvider{deferred: true}
+	b := NewBackfiller(repo, service.NewQuoteService(memory.NewQuoteRepository()), p)
+	b.Interval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.Run(ctx)
+		close(done)
+	}()
+	deadline := time.After(time.Second)
+	for {
+		got, err := repo.GetBackfill(bf.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Next.After(from) {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("the first day was not fetched")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	got, err := repo.GetBackfill(bf.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Status != model.BackfillRunning || !got.Next.Equal(from.AddDate(0, 0, 1)) || got.Inserted != 1 {
+		t.Errorf("expected the backfill to stay running after day 1, got %+v", got)
+	}
+}
+
+func TestBackfiller_Lease(t *testing.T) {
+	repo := memory.NewBackfillRepository()
+	srv := service.NewQuoteService(memory.NewQuoteRepository())
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bf := newBackfill(t, repo, from, from.AddDate(0, 0, 9))
+	first := NewBackfiller(repo, srv, &historicalProvider{deferred: true})
+	second := NewBackfiller(repo, srv, &historicalProvider{deferred: true})
+
+	got, ok, err := first.next()
+	if err != nil || !ok || got.ID != bf.ID || got.Owner != first.Owner {
+		t.Fatalf("expected the first backfiller to claim %s, got %+v %v %v", bf.ID, got, ok, err)
+	}
+	if _, ok, err := second.next(); err != nil || ok {
+		t.Fatalf("expected the leased backfill to be skipped, got %v %v", ok, err)
+	}
+
+	// Once the lease expires the second backfiller takes over, and the
+	// progress of the first is no longer saved.
+	second.now = func() time.Time { return time.Now().Add(first.Lease + time.Minute) }
+	taken, ok, err := second.next()
+	if err != nil || !ok || taken.Owner != second.Owner {
+		t.Fatalf("expected the second backfiller to take over, got %+v %v %v", taken, ok, err)
+	}
+	got.Next = from.AddDate(0, 0, 1)
+	if first.save(context.Background(), slog.Default(), &got) {
+		t.Error("expected the first backfiller to lose its lease")
+	}
+}
@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"sync"
+	"time"
+)
+
+// Deferrer puts jobs back on the queue once their delay has passed. Jobs
+// still waiting when Stop is called are dropped; their quotes stay pending
+// and are queued again on the next start.
+type Deferrer struct {
+	jobs chan<- QuoteJob
+	stop chan struct{}
+
+	mu      sync.Mutex
+	stopped bool
+	waiting int
+	wg      sync.WaitGroup
+}
+
+func NewDeferrer(jobs chan<- QuoteJob) *Deferrer {
+	return &Deferrer{jobs: jobs, stop: make(chan struct{})}
+}
+
+func (d *Deferrer) Defer(job QuoteJob, until time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		return
+	}
+	d.waiting++
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer func() {
+			d.mu.Lock()
+			d.waiting--
+			d.mu.Unlock()
+		}()
+		timer := time.NewTimer(time.Until(until))
+		defer timer.Stop()
+		select {
+		case <-d.stop:
+			return
+		case <-timer.C:
+		}
+		job.EnqueuedAt = time.Now()
+		select {
+		case <-d.stop:
+		case d.jobs <- job:
+		}
+	}()
+}
+
+// Len returns the number of jobs waiting to be requeued.
+func (d *Deferrer) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.waiting
+}
+
+// Stop drops the waiting jobs. It must be called before the job channel is
+// closed.
+func (d *Deferrer) Stop() {
+	d.mu.Lock()
+	if !d.stopped {
+		d.stopped = true
+		close(d.stop)
+	}
+	d.mu.Unlock()
+	d.wg.Wait()
+}
@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeferrer_Requeues(t *testing.T) {
+	jobs := make(chan QuoteJob, 1)
+	d := NewDeferrer(jobs)
+	defer d.Stop()
+
+	d.Defer(QuoteJob{Id: "q-1"}, time.Now().Add(10*time.Millisecond))
+	if d.Len() != 1 {
+		t.Errorf("expected 1 waiting job, got %d", d.Len())
+	}
+	select {
+	case job := <-jobs:
+		if job.Id != "q-1" || job.EnqueuedAt.IsZero() {
+			t.Errorf("unexpected job: %+v", job)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("job was not requeued")
+	}
+}
+
+func TestDeferrer_StopDropsWaitingJobs(t *testing.T) {
+	jobs := make(chan QuoteJob)
+	d := NewDeferrer(jobs)
+	d.Defer(QuoteJob{Id: "later"}, time.Now().Add(time.Hour))
+	d.Defer(QuoteJob{Id: "blocked"}, time.Now())
+
+	done := make(chan struct{})
+	go func() {
+		d.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not return")
+	}
+	close(jobs)
+
+	d.Defer(QuoteJob{Id: "after-stop"}, time.Now())
+	if d.Len() != 0 {
+		t.Errorf("expected no waiting jobs after Stop, got %d", d.Len())
+	}
+}
@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"FinQuotesService/internal/logging"
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/service"
+	"FinQuotesService/internal/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// StartUpdate returns the id of the update of currency that is still
+// pending, or records a new pending quote on behalf of requestedBy and queues
+// it. Every API starts updates through it, so a pair is never fetched twice
+// at the same time. It blocks while the queue is full.
+func StartUpdate(ctx context.Context, srv service.QuoteServiceInterface, jobs chan<- QuoteJob, currency, requestedBy string) (string, error) {
+	quote, err := srv.GetLastQuote(ctx, currency, model.StatusPending)
+	if err == nil {
+		slog.InfoContext(ctx, "existing pending job found", "component", "dispatch", "job_id", quote.ID, "currency", currency)
+		return quote.ID, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	id, err := srv.InsertPendingQuote(ctx, currency, requestedBy)
+	if err != nil {
+		return "", err
+	}
+	jobs <- QuoteJob{
+		Id:           id,
+		Currency:     currency,
+		RequestId:    logging.RequestID(ctx),
+		TraceContext: tracing.Inject(ctx),
+		EnqueuedAt:   time.Now(),
+	}
+	slog.InfoContext(ctx, "job pushed to queue", "component", "dispatch", "job_id", id, "currency", currency, "api_key_id", requestedBy)
+	return id, nil
+}
+
+// FreshQuote returns the latest done quote of currency if it was updated at
+// most maxAge ago, for callers content with a recent rate rather than a new
+// fetch.
+func FreshQuote(ctx context.Context, srv service.QuoteServiceInterface, currency string, maxAge time.Duration) (model.Quote, bool, error) {
+	q, err := srv.GetLastQuote(ctx, currency, model.StatusDone)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Quote{}, false, nil
+	}
+	if err != nil {
+		return model.Quote{}, false, err
+	}
+	return q, q.UpdatedAt != nil && time.Since(*q.UpdatedAt) <= maxAge, nil
+}
@@ -1,79 +1,175 @@
 package worker
 
 import (
+	"FinQuotesService/internal/logging"
+	"FinQuotesService/internal/metrics"
 	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/provider"
 	"FinQuotesService/internal/service"
-	"encoding/json"
+	"FinQuotesService/internal/tracing"
+	"context"
 	"errors"
-	"fmt"
-	"log"
-	"net/http"
+	"log/slog"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type QuoteJob struct {
-	Id       string
-	Currency string
+	Id        string
+	Currency  string
+	RequestId string
+	// TraceContext carries the W3C trace context of the request that queued
+	// the job, EnqueuedAt is used to report the time spent in the queue.
+	TraceContext map[string]string
+	EnqueuedAt   time.Time
 }
 
-type ratesResponse struct {
-	Base  string             `json:"base"`
-	Rates map[string]float64 `json:"rates"`
+var running atomic.Int32
+
+// Running returns the number of workers currently consuming the job queue.
+func Running() int {
+	return int(running.Load())
 }
 
-var client = &http.Client{
-	Timeout: 5 * time.Second,
+// Worker consumes the job queue until it is closed.
+type Worker struct {
+	Jobs     <-chan QuoteJob
+	Srv      *service.QuoteService
+	Provider provider.Provider
+	// ProcessingDelay emulates slow processing before the provider is called.
+	ProcessingDelay time.Duration
+	// Deferrer requeues jobs the provider asked to postpone. Without it such
+	// jobs fail like any other provider error.
+	Deferrer *Deferrer
+	// DeriveInverse lists pairs computed as the inverse of the latest done
+	// quote of their opposite pair, when it is at most DeriveMaxAge old,
+	// rather than fetched. A stale opposite quote falls back to the provider.
+	DeriveInverse map[string]bool
+	DeriveMaxAge  time.Duration
+	// Anomaly, when set, holds rates that jump too far from the previous
+	// done quote as suspect until an admin reviews them.
+	Anomaly *service.AnomalyDetector
 }
 
-func StartWorker(jobs <-chan QuoteJob, srv *service.QuoteService) {
-	for job := range jobs {
-		log.Println("[Worker] Job processing started, job_id = " + job.Id)
-		price, err := fetchExternalQuote(job.Currency)
-		log.Println("[Worker] Job processing finished, job_id = " + job.Id)
-
-		status := model.StatusDone
-		if err != nil {
-			status = model.StatusError
-			log.Printf("[Worker] failed to fetch quote for %s: %v", job.Currency, err)
-		}
-
-		if err := srv.UpdateQuote(job.Id, price, status); err != nil {
-			log.Printf("[Worker] db update error: %v", err)
-		}
+func (w *Worker) Run() {
+	running.Add(1)
+	defer running.Add(-1)
+	metrics.WorkersIdle.Inc()
+	defer metrics.WorkersIdle.Dec()
+	for job := range w.Jobs {
+		metrics.WorkersIdle.Dec()
+		metrics.WorkersBusy.Inc()
+		w.processJob(job)
+		metrics.WorkersBusy.Dec()
+		metrics.WorkersIdle.Inc()
 	}
-	log.Println("[Worker] Job channel closed, worker exiting")
+	slog.Info("job channel closed, worker exiting", "component", "worker")
 }
 
-func fetchExternalQuote(currencyPair string) (float64, error) {
-	// emulation of processing
-	time.Sleep(30 * time.Second)
-	split := strings.Split(currencyPair, "/")
-	if len(split) != 2 {
-		return 0, errors.New("bad currency pair")
+func (w *Worker) processJob(job QuoteJob) {
+	start := time.Now()
+	ctx := tracing.Extract(context.Background(), job.TraceContext)
+	ctx = logging.WithJobID(logging.WithRequestID(ctx, job.RequestId), job.Id)
+	attrs := trace.WithAttributes(attribute.String("job.id", job.Id), attribute.String("currency", job.Currency))
+	if !job.EnqueuedAt.IsZero() {
+		_, wait := tracing.Tracer().Start(ctx, "queue.wait", attrs, trace.WithTimestamp(job.EnqueuedAt))
+		wait.End(trace.WithTimestamp(start))
 	}
-	base, target := split[0], split[1]
-	url := fmt.Sprintf("https://api.vatcomply.com/rates?base=%s", base)
+	ctx, span := tracing.Tracer().Start(ctx, "worker.ProcessJob", attrs, trace.WithSpanKind(trace.SpanKindConsumer))
+	defer span.End()
 
-	resp, err := client.Get(url)
-	if err != nil {
-		return 0, err
+	slog.InfoContext(ctx, "job processing started", "component", "worker", "currency", job.Currency)
+	price, derived := w.deriveInverse(ctx, job.Currency)
+	var err error
+	if !derived {
+		price, err = fetchExternalQuote(ctx, w.Provider, job.Currency, w.ProcessingDelay)
 	}
-	defer resp.Body.Close()
+	span.SetAttributes(attribute.Bool("quote.derived", derived))
+	slog.InfoContext(ctx, "job processing finished", "component", "worker", "currency", job.Currency, "duration_ms", time.Since(start).Milliseconds())
 
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("fetcher: http error: %v", resp.Status)
+	var deferred *provider.DeferredError
+	if w.Deferrer != nil && errors.As(err, &deferred) {
+		w.Deferrer.Defer(job, deferred.Until)
+		span.SetAttributes(attribute.String("quote.status", "deferred"))
+		slog.WarnContext(ctx, "job deferred", "component", "worker", "currency", job.Currency, "until", deferred.Until, "reason", deferred.Reason)
+		metrics.JobsTotal.WithLabelValues("deferred").Inc()
+		metrics.JobDuration.Observe(time.Since(start).Seconds())
+		return
 	}
 
-	var r ratesResponse
-	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
-		return 0, err
+	status := model.StatusDone
+	if err != nil {
+		status = model.StatusError
+		slog.ErrorContext(ctx, "failed to fetch quote", "component", "worker", "currency", job.Currency, "error", err)
+	} else if w.suspect(ctx, job.Currency, price) {
+		status = model.StatusSuspect
+	}
+	span.SetAttributes(attribute.String("quote.status", string(status)))
+
+	if err := w.Srv.UpdateQuote(ctx, job.Id, price, status); err != nil {
+		slog.ErrorContext(ctx, "db update error", "component", "worker", "error", err)
 	}
 
-	rate, ok := r.Rates[target]
+	metrics.JobsTotal.WithLabelValues(string(status)).Inc()
+	metrics.JobDuration.Observe(time.Since(start).Seconds())
+}
+
+// deriveInverse computes currency from a fresh quote of its inverse pair,
+// if it is one of the derived pairs.
+func (w *Worker) deriveInverse(ctx context.Context, currency string) (float64, bool) {
+	if !w.DeriveInverse[currency] {
+		return 0, false
+	}
+	inverse, ok := model.InversePair(currency)
 	if !ok {
-		return 0, fmt.Errorf("no rate found for %s/%s", base, target)
+		return 0, false
+	}
+	q, fresh, err := FreshQuote(ctx, w.Srv, inverse, w.DeriveMaxAge)
+	if err != nil {
+		slog.WarnContext(ctx, "inverse quote lookup failed", "component", "worker", "currency", currency, "inverse", inverse, "error", err)
+		return 0, false
+	}
+	if !fresh || q.Price == nil || *q.Price <= 0 {
+		slog.InfoContext(ctx, "no fresh inverse quote, fetching upstream", "component", "worker", "currency", currency, "inverse", inverse)
+		return 0, false
 	}
+	slog.InfoContext(ctx, "quote derived from inverse", "component", "worker", "currency", currency, "inverse_id", q.ID)
+	metrics.QuotesDerived.WithLabelValues(currency).Inc()
+	return 1 / *q.Price, true
+}
 
-	return rate, nil
+// suspect reports whether price should be held for review. A failed check
+// lets the quote through rather than stalling the pair.
+func (w *Worker) suspect(ctx context.Context, currency string, price float64) bool {
+	if w.Anomaly == nil {
+		return false
+	}
+	reason, err := w.Anomaly.Check(ctx, currency, price)
+	if err != nil {
+		slog.WarnContext(ctx, "anomaly check failed", "component", "worker", "currency", currency, "error", err)
+		return false
+	}
+	if reason == "" {
+		return false
+	}
+	slog.WarnContext(ctx, "quote held for review", "component", "worker", "currency", currency, "price", price, "reason", reason)
+	metrics.QuotesSuspect.WithLabelValues(currency).Inc()
+	return true
+}
+
+func fetchExternalQuote(ctx context.Context, p provider.Provider, currencyPair string, delay time.Duration) (float64, error) {
+	// emulation of processing
+	_, span := tracing.Tracer().Start(ctx, "worker.EmulatedDelay")
+	time.Sleep(delay)
+	span.End()
+	split := strings.Split(currencyPair, "/")
+	if len(split) != 2 {
+		return 0, errors.New("bad currency pair")
+	}
+	base, target := split[0], split[1]
+	return p.FetchRate(ctx, base, target)
 }
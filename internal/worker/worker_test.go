@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/repository/memory"
+	"FinQuotesService/internal/service"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWorker_DeriveInverse(t *testing.T) {
+	ctx := context.Background()
+	srv := service.NewQuoteService(memory.NewQuoteRepository())
+	// The stub provider fails live fetches, so only derived quotes succeed.
+	w := &Worker{Srv: srv, Provider: &historicalProvider{}, DeriveInverse: map[string]bool{"USD/EUR": true}, DeriveMaxAge: time.Minute}
+
+	process := func(currency string) model.Quote {
+		t.Helper()
+		id, err := srv.InsertPendingQuote(ctx, currency, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.processJob(QuoteJob{Id: id, Currency: currency})
+		q, err := srv.GetQuoteById(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return q
+	}
+
+	// Without a quote of EUR/USD the provider is called.
+	if q := process("USD/EUR"); q.Status != model.StatusError {
+		t.Errorf("expected a failed fetch, got %+v", q)
+	}
+
+	if _, err := srv.InsertHistoricalQuote(ctx, "EUR/USD", 1.25, time.Now().Add(-time.Hour), model.SourceImport); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q := process("USD/EUR"); q.Status != model.StatusError {
+		t.Errorf("expected a stale inverse to be ignored, got %+v", q)
+	}
+
+	if _, err := srv.InsertHistoricalQuote(ctx, "EUR/USD", 1.25, time.Now(), model.SourceImport); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q := process("USD/EUR"); q.Status != model.StatusDone || *q.Price != 0.8 {
+		t.Errorf("expected USD/EUR derived as 0.8, got %+v", q)
+	}
+	// EUR/USD is not derived, even though USD/EUR is fresh now.
+	if q := process("EUR/USD"); q.Status != model.StatusError {
+		t.Errorf("expected EUR/USD to be fetched, got %+v", q)
+	}
+}
+
+func TestWorker_HoldsAnomalies(t *testing.T) {
+	ctx := context.Background()
+	srv := service.NewQuoteService(memory.NewQuoteRepository())
+	// The derived USD/EUR rate stands in for a provider answer.
+	w := &Worker{
+		Srv:           srv,
+		Provider:      &historicalProvider{},
+		DeriveInverse: map[string]bool{"USD/EUR": true},
+		DeriveMaxAge:  time.Minute,
+		Anomaly:       service.NewAnomalyDetector(srv, service.AnomalyRule{MaxChange: 0.05}, time.Hour),
+	}
+	if _, err := srv.InsertHistoricalQuote(ctx, "USD/EUR", 0.8, time.Now().Add(-time.Hour), model.SourceImport); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := srv.InsertHistoricalQuote(ctx, "EUR/USD", 2, time.Now(), model.SourceImport); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id, err := srv.InsertPendingQuote(ctx, "USD/EUR", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.processJob(QuoteJob{Id: id, Currency: "USD/EUR"})
+	q, err := srv.GetQuoteById(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.Status != model.StatusSuspect || *q.Price != 0.5 {
+		t.Errorf("expected a suspect quote at 0.5, got %+v", q)
+	}
+	if last, _ := srv.GetLastQuote(ctx, "USD/EUR", model.StatusDone); *last.Price != 0.8 {
+		t.Errorf("expected the previous rate to stay the latest, got %+v", last)
+	}
+}
@@ -0,0 +1,377 @@
+// Package ws serves live quote ticks over WebSocket.
+package ws
+
+import (
+	"FinQuotesService/internal/api"
+	"FinQuotesService/internal/auth"
+	"FinQuotesService/internal/metrics"
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/ratelimit"
+	"FinQuotesService/internal/service"
+	"FinQuotesService/internal/worker"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	maxMessageSize = 4096
+	// sendBuffer is how many messages may wait for a client. Ticks beyond it
+	// are dropped; a client that lets its command replies pile up past it is
+	// disconnected.
+	sendBuffer = 64
+)
+
+// Message types sent by clients.
+const (
+	TypeSubscribe   = "subscribe"
+	TypeUnsubscribe = "unsubscribe"
+	TypeRefresh     = "refresh"
+)
+
+// Message types sent by the server.
+const (
+	TypeSubscribed = "subscribed"
+	TypeRefreshing = "refreshing"
+	TypeTick       = "tick"
+	TypeError      = "error"
+)
+
+// ClientMessage is a command sent by a client.
+type ClientMessage struct {
+	Type  string   `json:"type"`
+	Pairs []string `json:"pairs"`
+}
+
+// ServerMessage is a reply or tick sent to a client. Fields not relevant to
+// the message type are left out.
+type ServerMessage struct {
+	Type         string     `json:"type"`
+	Pairs        []string   `json:"pairs,omitempty"`
+	Pair         string     `json:"pair,omitempty"`
+	Price        *float64   `json:"price,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+	RequestID    string     `json:"request_id,omitempty"`
+	ErrorMessage string     `json:"error_message,omitempty"`
+}
+
+// Handler upgrades requests to WebSocket connections. Ticks come from the
+// quote service broadcaster, which never blocks on a connection, so slow
+// clients cannot hold up the workers completing quotes.
+type Handler struct {
+	SupportedCurrency map[string]bool
+	Srv               service.QuoteServiceInterface
+	JobChan           chan worker.QuoteJob
+	Updates           *service.Broadcaster
+	Authn             *auth.Authenticator
+	// Limiter, when set, counts every pair of a refresh command against the
+	// /quotes/update route, as one POST /v1/quotes/update each.
+	Limiter *ratelimit.Limiter
+	// PingInterval is how often connections are pinged; a client that does
+	// not answer within PongWait is disconnected.
+	PingInterval time.Duration
+	PongWait     time.Duration
+
+	upgrader websocket.Upgrader
+	mu       sync.Mutex
+	conns    map[*websocket.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+func NewHandler(supported map[string]bool, srv service.QuoteServiceInterface, jobs chan worker.QuoteJob, updates *service.Broadcaster, authn *auth.Authenticator) *Handler {
+	return &Handler{
+		SupportedCurrency: supported,
+		Srv:               srv,
+		JobChan:           jobs,
+		Updates:           updates,
+		Authn:             authn,
+		PingInterval:      pongWait * 9 / 10,
+		PongWait:          pongWait,
+		upgrader: websocket.Upgrader{
+			// Clients authenticate with an API key rather than cookies, so a
+			// page on another origin gains nothing it does not already have.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		conns: make(map[*websocket.Conn]struct{}),
+	}
+}
+
+func (h *Handler) Serve(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied with an HTTP error.
+		return
+	}
+	if !h.track(conn) {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(writeWait))
+		conn.Close()
+		return
+	}
+	defer h.untrack(conn)
+
+	// The request context is done once the handshake handler returns, keep
+	// only its values.
+	c := &connection{
+		h:    h,
+		conn: conn,
+		ctx:  context.WithoutCancel(r.Context()),
+		out:  make(chan ServerMessage, sendBuffer),
+		subs: make(map[string]bool),
+	}
+	if h.Limiter != nil {
+		c.client = h.Limiter.Client(r)
+	}
+	metrics.WSConnections.Inc()
+	defer metrics.WSConnections.Dec()
+	reason := c.run()
+	metrics.WSDisconnects.WithLabelValues(reason).Inc()
+	slog.InfoContext(c.ctx, "websocket closed", "component", "ws", "reason", reason, "api_key_id", auth.KeyID(c.ctx))
+}
+
+// Close disconnects every client with a going-away close frame and waits for
+// their connections to wind down. Later handshakes are refused.
+func (h *Handler) Close() {
+	h.mu.Lock()
+	h.closed = true
+	for conn := range h.conns {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(writeWait))
+		conn.Close()
+	}
+	h.mu.Unlock()
+	h.wg.Wait()
+}
+
+func (h *Handler) track(conn *websocket.Conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return false
+	}
+	h.conns[conn] = struct{}{}
+	h.wg.Add(1)
+	return true
+}
+
+func (h *Handler) untrack(conn *websocket.Conn) {
+	h.mu.Lock()
+	delete(h.conns, conn)
+	h.mu.Unlock()
+	h.wg.Done()
+}
+
+type connection struct {
+	h    *Handler
+	conn *websocket.Conn
+	ctx  context.Context
+	// client identifies the connection to the rate limiter.
+	client string
+	// out carries command replies to the writer, which owns all writes.
+	out chan ServerMessage
+
+	mu   sync.Mutex
+	subs map[string]bool
+}
+
+// run serves the connection until it closes and returns the reason.
+func (c *connection) run() string {
+	updates, unsubscribe := c.h.Updates.Subscribe(sendBuffer)
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	writerErr := make(chan error, 1)
+	go func() {
+		writerErr <- c.writeLoop(updates, done)
+		// Unblock the reader when writing failed.
+		c.conn.Close()
+	}()
+
+	reason := c.readLoop()
+	close(done)
+	if err := <-writerErr; err != nil && reason == "client" {
+		reason = "timeout"
+	}
+	c.conn.Close()
+	if c.h.isClosed() {
+		reason = "shutdown"
+	}
+	return reason
+}
+
+func (h *Handler) isClosed() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.closed
+}
+
+func (c *connection) readLoop() string {
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(c.h.PongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.h.PongWait))
+	})
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			var netErr interface{ Timeout() bool }
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return "timeout"
+			}
+			return "client"
+		}
+		var msg ClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			if !c.send(errorMessage("", api.InvalidRequestParams)) {
+				return "slow_consumer"
+			}
+			continue
+		}
+		if !c.handle(msg) {
+			slog.WarnContext(c.ctx, "websocket client is not reading, disconnecting", "component", "ws", "api_key_id", auth.KeyID(c.ctx))
+			return "slow_consumer"
+		}
+	}
+}
+
+// handle runs a client command. It returns false when the replies no longer
+// fit in the send buffer.
+func (c *connection) handle(msg ClientMessage) bool {
+	if msg.Type != TypeSubscribe && msg.Type != TypeUnsubscribe && msg.Type != TypeRefresh || len(msg.Pairs) == 0 {
+		return c.send(errorMessage("", api.InvalidRequestParams))
+	}
+	for _, pair := range msg.Pairs {
+		if !c.h.SupportedCurrency[pair] {
+			return c.send(errorMessage(pair, api.UnsupportedCurrencyPair))
+		}
+	}
+	switch msg.Type {
+	case TypeSubscribe:
+		var added []string
+		c.mu.Lock()
+		for _, pair := range msg.Pairs {
+			if !c.subs[pair] {
+				c.subs[pair] = true
+				added = append(added, pair)
+			}
+		}
+		c.mu.Unlock()
+		if !c.send(ServerMessage{Type: TypeSubscribed, Pairs: c.subscriptions()}) {
+			return false
+		}
+		// Start new subscribers off with the latest known price.
+		for _, pair := range added {
+			q, err := c.h.Srv.GetLastQuote(c.ctx, pair, model.StatusDone)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				slog.ErrorContext(c.ctx, "quote lookup failed", "component", "ws", "currency", pair, "error", err)
+				continue
+			}
+			if !c.send(tickMessage(q)) {
+				return false
+			}
+		}
+		return true
+	case TypeUnsubscribe:
+		c.mu.Lock()
+		for _, pair := range msg.Pairs {
+			delete(c.subs, pair)
+		}
+		c.mu.Unlock()
+		return c.send(ServerMessage{Type: TypeSubscribed, Pairs: c.subscriptions()})
+	default:
+		if !c.h.Authn.Allowed(c.ctx, model.ScopeQuotesUpdate) {
+			return c.send(ServerMessage{Type: TypeError, ErrorMessage: "API key lacks scope " + string(model.ScopeQuotesUpdate)})
+		}
+		for _, pair := range msg.Pairs {
+			if c.h.Limiter != nil && !c.h.Limiter.Allow(c.ctx, "/quotes/update", c.client, 1).Allowed {
+				if !c.send(errorMessage(pair, api.TooManyRequests)) {
+					return false
+				}
+				continue
+			}
+			id, err := worker.StartUpdate(c.ctx, c.h.Srv, c.h.JobChan, pair, auth.KeyID(c.ctx))
+			reply := ServerMessage{Type: TypeRefreshing, Pair: pair, RequestID: id}
+			if err != nil {
+				slog.ErrorContext(c.ctx, "failed to start update", "component", "ws", "currency", pair, "error", err)
+				reply = errorMessage(pair, api.ServerInternalError)
+			}
+			if !c.send(reply) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func (c *connection) send(msg ServerMessage) bool {
+	select {
+	case c.out <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *connection) subscribed(pair string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.subs[pair]
+}
+
+func (c *connection) subscriptions() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pairs := make([]string, 0, len(c.subs))
+	for pair := range c.subs {
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
+
+// writeLoop owns all writes to the connection: replies, ticks for
+// subscribed pairs and pings. It returns when done is closed or a write fails.
+func (c *connection) writeLoop(updates <-chan model.Quote, done <-chan struct{}) error {
+	ping := time.NewTicker(c.h.PingInterval)
+	defer ping.Stop()
+	for {
+		var msg ServerMessage
+		select {
+		case <-done:
+			return nil
+		case msg = <-c.out:
+		case q := <-updates:
+			if !c.subscribed(q.Currency) {
+				continue
+			}
+			msg = tickMessage(q)
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return err
+			}
+			continue
+		}
+		c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := c.conn.WriteJSON(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func tickMessage(q model.Quote) ServerMessage {
+	return ServerMessage{Type: TypeTick, Pair: q.Currency, Price: q.Price, UpdatedAt: q.UpdatedAt}
+}
+
+func errorMessage(pair string, e api.ServiceError) ServerMessage {
+	return ServerMessage{Type: TypeError, Pair: pair, ErrorMessage: string(e)}
+}
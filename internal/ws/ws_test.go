@@ -0,0 +1,244 @@
+package ws
+
+import (
+	"FinQuotesService/internal/auth"
+	"FinQuotesService/internal/model"
+	"FinQuotesService/internal/ratelimit"
+	"FinQuotesService/internal/repository/memory"
+	"FinQuotesService/internal/service"
+	"FinQuotesService/internal/worker"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type testEnv struct {
+	url     string
+	srv     *service.QuoteService
+	jobs    chan worker.QuoteJob
+	handler *Handler
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	keys := memory.NewAPIKeyRepository()
+	keys.CreateAPIKey("reader", auth.HashKey("reader"), []model.Scope{model.ScopeQuotesRead})
+	keys.CreateAPIKey("writer", auth.HashKey("writer"), []model.Scope{model.ScopeQuotesUpdate, model.ScopeQuotesRead})
+	authn := auth.NewAuthenticator(keys)
+
+	srv := service.NewQuoteService(memory.NewQuoteRepository())
+	srv.Updates = service.NewBroadcaster()
+	jobs := make(chan worker.QuoteJob, 10)
+	h := NewHandler(map[string]bool{"USD/EUR": true, "EUR/USD": true}, srv, jobs, srv.Updates, authn)
+
+	ts := httptest.NewServer(authn.Middleware(authn.Require(model.ScopeQuotesRead, h.Serve)))
+	t.Cleanup(func() {
+		h.Close()
+		ts.Close()
+	})
+	return &testEnv{url: "ws" + strings.TrimPrefix(ts.URL, "http"), srv: srv, jobs: jobs, handler: h}
+}
+
+func (env *testEnv) dial(t *testing.T, key string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(env.url+"?api_key="+key, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func send(t *testing.T, conn *websocket.Conn, msg ClientMessage) {
+	t.Helper()
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func receive(t *testing.T, conn *websocket.Conn) ServerMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg ServerMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return msg
+}
+
+func (env *testEnv) complete(t *testing.T, currency string, price float64) {
+	t.Helper()
+	ctx := context.Background()
+	id, err := env.srv.InsertPendingQuote(ctx, currency, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := env.srv.UpdateQuote(ctx, id, price, model.StatusDone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestHandler_SubscribeAndTicks(t *testing.T) {
+	env := newTestEnv(t)
+	env.complete(t, "USD/EUR", 0.91)
+	conn := env.dial(t, "reader")
+
+	send(t, conn, ClientMessage{Type: TypeSubscribe, Pairs: []string{"USD/EUR"}})
+	if msg := receive(t, conn); msg.Type != TypeSubscribed || len(msg.Pairs) != 1 || msg.Pairs[0] != "USD/EUR" {
+		t.Fatalf("unexpected reply %+v", msg)
+	}
+	if msg := receive(t, conn); msg.Type != TypeTick || msg.Price == nil || *msg.Price != 0.91 || msg.UpdatedAt == nil {
+		t.Fatalf("expected the latest quote first, got %+v", msg)
+	}
+
+	env.complete(t, "EUR/USD", 1.09)
+	env.complete(t, "USD/EUR", 0.93)
+	if msg := receive(t, conn); msg.Type != TypeTick || msg.Pair != "USD/EUR" || *msg.Price != 0.93 {
+		t.Fatalf("expected the USD/EUR tick only, got %+v", msg)
+	}
+
+	send(t, conn, ClientMessage{Type: TypeUnsubscribe, Pairs: []string{"USD/EUR"}})
+	if msg := receive(t, conn); msg.Type != TypeSubscribed || len(msg.Pairs) != 0 {
+		t.Fatalf("unexpected reply %+v", msg)
+	}
+	env.complete(t, "USD/EUR", 0.94)
+	send(t, conn, ClientMessage{Type: TypeSubscribe, Pairs: []string{"EUR/USD"}})
+	if msg := receive(t, conn); msg.Type != TypeSubscribed {
+		t.Fatalf("expected no tick after unsubscribing, got %+v", msg)
+	}
+}
+
+func TestHandler_Refresh(t *testing.T) {
+	env := newTestEnv(t)
+
+	reader := env.dial(t, "reader")
+	send(t, reader, ClientMessage{Type: TypeRefresh, Pairs: []string{"USD/EUR"}})
+	if msg := receive(t, reader); msg.Type != TypeError || msg.ErrorMessage != "API key lacks scope quotes:update" {
+		t.Fatalf("expected refresh to need the update scope, got %+v", msg)
+	}
+
+	writer := env.dial(t, "writer")
+	send(t, writer, ClientMessage{Type: TypeRefresh, Pairs: []string{"USD/EUR"}})
+	msg := receive(t, writer)
+	if msg.Type != TypeRefreshing || msg.Pair != "USD/EUR" || msg.RequestID == "" {
+		t.Fatalf("unexpected reply %+v", msg)
+	}
+	job := <-env.jobs
+	if job.Id != msg.RequestID {
+		t.Errorf("expected job %s, got %+v", msg.RequestID, job)
+	}
+	q, err := env.srv.GetQuoteById(context.Background(), msg.RequestID)
+	if err != nil || q.RequestedBy == nil {
+		t.Errorf("expected the quote to record the key, got %+v, %v", q, err)
+	}
+}
+
+func TestHandler_RefreshRateLimit(t *testing.T) {
+	env := newTestEnv(t)
+	env.handler.Limiter = ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[string]ratelimit.Limit{
+		"/quotes/update": ratelimit.Per(1, time.Hour, 1),
+	})
+	conn := env.dial(t, "writer")
+
+	send(t, conn, ClientMessage{Type: TypeRefresh, Pairs: []string{"USD/EUR", "EUR/USD"}})
+	if msg := receive(t, conn); msg.Type != TypeRefreshing || msg.Pair != "USD/EUR" {
+		t.Fatalf("expected the first pair to start, got %+v", msg)
+	}
+	if msg := receive(t, conn); msg.Type != TypeError || msg.Pair != "EUR/USD" || msg.ErrorMessage != "Too many requests" {
+		t.Fatalf("expected the second pair to be limited, got %+v", msg)
+	}
+	if len(env.jobs) != 1 {
+		t.Errorf("expected 1 queued job, got %d", len(env.jobs))
+	}
+}
+
+func TestHandler_InvalidMessages(t *testing.T) {
+	env := newTestEnv(t)
+	conn := env.dial(t, "reader")
+
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"malformed JSON", `{"type":`, "Invalid request parameters"},
+		{"unknown type", `{"type":"watch","pairs":["USD/EUR"]}`, "Invalid request parameters"},
+		{"no pairs", `{"type":"subscribe"}`, "Invalid request parameters"},
+		{"unsupported pair", `{"type":"subscribe","pairs":["GBP/USD"]}`, "Unsupported currency pair"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(tt.message)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if msg := receive(t, conn); msg.Type != TypeError || msg.ErrorMessage != tt.want {
+				t.Errorf("expected error %q, got %+v", tt.want, msg)
+			}
+		})
+	}
+}
+
+func TestHandler_RequiresKey(t *testing.T) {
+	env := newTestEnv(t)
+	_, resp, err := websocket.DefaultDialer.Dial(env.url, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %v, %v", resp, err)
+	}
+}
+
+func TestHandler_Close(t *testing.T) {
+	env := newTestEnv(t)
+	conn := env.dial(t, "reader")
+	send(t, conn, ClientMessage{Type: TypeSubscribe, Pairs: []string{"USD/EUR"}})
+	receive(t, conn)
+
+	env.handler.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Fatalf("expected a going-away close, got %v", err)
+	}
+	if env.srv.Updates.Subscribers() != 0 {
+		t.Errorf("expected the connection to unsubscribe from updates")
+	}
+}
+
+func TestHandler_Heartbeat(t *testing.T) {
+	env := newTestEnv(t)
+	env.handler.PingInterval = 10 * time.Millisecond
+	env.handler.PongWait = 100 * time.Millisecond
+
+	// A client that keeps reading answers pings and stays connected.
+	alive := env.dial(t, "reader")
+	pings := make(chan struct{}, 100)
+	alive.SetPingHandler(func(data string) error {
+		pings <- struct{}{}
+		return alive.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	go func() {
+		for {
+			if _, _, err := alive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// A client that stops reading never sends pongs and is dropped.
+	stalled := env.dial(t, "reader")
+	time.Sleep(300 * time.Millisecond)
+	stalled.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := stalled.ReadMessage(); err != nil {
+			break
+		}
+	}
+
+	if len(pings) < 5 {
+		t.Errorf("expected regular pings, got %d", len(pings))
+	}
+}